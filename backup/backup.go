@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/backup_history"
@@ -24,36 +25,61 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(utils.DBNAME)
+	_ = cmd.Flags().MarkHidden(utils.BACKUP_GROUP)
 
 	cmdFlags = cmd.Flags()
 }
 
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.Bool(utils.ALL_DATABASES, false, "Back up every database that accepts connections, other than template databases, as a backup group")
 	flagSet.String(utils.BACKUP_DIR, "", "The absolute path of the directory to which all backup files will be written")
+	flagSet.String(utils.BACKUP_GROUP, "", "The backup group that this backup belongs to, set when backing up several databases in one run")
 	flagSet.Int(utils.COMPRESSION_LEVEL, 1, "Level of compression to use during data backup. Valid values are between 1 and 9.")
+	flagSet.Bool(utils.CONSISTENT, false, "If synchronized snapshots are not supported, hold EXCLUSIVE locks on all tables in the backup set for the duration of the backup so that data backed up on all connections is consistent")
+	flagSet.String(utils.CONSOLIDATE, "", "Create a new full backup from the files of the incremental backup with the specified timestamp and the backups it depends on, without reading any data from the database")
+	flagSet.Int(utils.COPY_RETRIES, 0, "The number of times to retry backing up a table's data if it fails, removing any partial data files first.  Not supported with --single-data-file or --plugin-config.")
 	flagSet.Bool(utils.DATA_ONLY, false, "Only back up data, do not back up metadata")
 	flagSet.String(utils.DBNAME, "", "The database to be backed up")
 	flagSet.Bool(utils.DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(utils.DIFF_TIMESTAMP, "", "Compare the metadata of the backup with the specified timestamp against the live database, or against --diff-to-timestamp, report the added, removed, and changed objects, and exit without taking a backup")
+	flagSet.String(utils.DIFF_TO_TIMESTAMP, "", "The timestamp of the backup to compare the --diff-timestamp backup against, instead of the live database")
+	flagSet.Bool(utils.DIFFERENTIAL, false, "Only back up data for AO tables that have been modified since the last full backup")
 	flagSet.StringSlice(utils.EXCLUDE_SCHEMA, []string{}, "Back up all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.EXCLUDE_RELATION, []string{}, "Back up all metadata except the specified table(s). --exclude-table can be specified multiple times.")
 	flagSet.String(utils.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be excluded from the backup")
+	flagSet.StringSlice(utils.EXCLUDE_RELATION_PATTERN, []string{}, "Back up all metadata except the tables whose fully-qualified names match the specified pattern(s). --exclude-table-pattern can be specified multiple times.")
+	flagSet.StringSlice(utils.EXCLUDE_SCHEMA_PATTERN, []string{}, "Back up all metadata except objects in the schema(s) whose names match the specified pattern(s). --exclude-schema-pattern can be specified multiple times.")
 	flagSet.String(utils.FROM_TIMESTAMP, "", "A timestamp to use to base the current incremental backup off")
 	flagSet.Bool("help", false, "Help for gpbackup")
 	flagSet.StringSlice(utils.INCLUDE_SCHEMA, []string{}, "Back up only the specified schema(s). --include-schema can be specified multiple times.")
+	flagSet.StringArray(utils.INCLUDE_DATABASE, []string{}, "Back up the specified database(s) as a backup group, each with its own timestamp.  Each value is taken as a whole database name.  --include-database can be specified multiple times.")
 	flagSet.StringSlice(utils.INCLUDE_RELATION, []string{}, "Back up only the specified table(s). --include-table can be specified multiple times.")
 	flagSet.String(utils.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be included in the backup")
+	flagSet.StringSlice(utils.INCLUDE_RELATION_PATTERN, []string{}, "Back up only the tables whose fully-qualified names match the specified pattern(s). --include-table-pattern can be specified multiple times.")
+	flagSet.StringSlice(utils.INCLUDE_SCHEMA_PATTERN, []string{}, "Back up only the schema(s) whose names match the specified pattern(s). --include-schema-pattern can be specified multiple times.")
 	flagSet.Bool(utils.INCREMENTAL, false, "Only back up data for AO tables that have been modified since the last backup")
 	flagSet.Int(utils.JOBS, 1, "The number of parallel connections to use when backing up data")
 	flagSet.Bool(utils.LEAF_PARTITION_DATA, false, "For partition tables, create one data file per leaf partition instead of one data file for the whole table")
+	flagSet.Bool(utils.LIST_ONLY, false, "List the objects and table data that would be backed up, with the estimated size of each table, and exit without taking a backup")
+	flagSet.Int(utils.LOCK_WAIT_TIMEOUT, 0, "The number of seconds to wait to acquire locks on tables before failing the backup.  0 waits indefinitely.")
+	flagSet.String(utils.MASKING_RULES_FILE, "", "A YAML file mapping fully-qualified table names and their column names to masking rules (hash, null, constant, keep-prefix, or date-shift) to apply to the backed up data.  Backups taken with this flag cannot be used as the base of an incremental backup.")
+	flagSet.String(utils.METADATA_FORMAT, utils.METADATA_FORMAT_FILE, "The layout of the backed up metadata, either file for a single metadata file or directory for one file per object under a metadata directory")
 	flagSet.Bool(utils.METADATA_ONLY, false, "Only back up metadata, do not back up data")
 	flagSet.Bool(utils.NO_COMPRESSION, false, "Disable compression of data files")
+	flagSet.String(utils.PATTERN_SYNTAX, utils.PATTERN_SYNTAX_GLOB, "The syntax of the patterns passed to the --*-pattern flags, either glob or regex")
 	flagSet.String(utils.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.Bool(utils.PRESERVE_TABLE_ORDER, false, "Back up table data in catalog order, instead of starting with the largest tables when --jobs is greater than 1")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(utils.QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.String(utils.RESUME, "", "Resume the failed backup with the specified timestamp, reusing the data files of tables that have not changed since they were backed up")
 	flagSet.Bool(utils.SINGLE_DATA_FILE, false, "Back up all data to a single file instead of one per table")
+	flagSet.String(utils.TABLE_PREDICATE_FILE, "", "A YAML file mapping fully-qualified table names to SQL predicates; only the rows matching a table's predicate are backed up.  Backups taken with this flag cannot be used as the base of an incremental backup.")
+	flagSet.Bool(utils.TRACK_HEAP_CHANGES, false, "Record a checksum of the contents of each heap table, so that incremental backups based on this backup can skip heap tables that have not changed")
 	flagSet.Bool(utils.VERBOSE, false, "Print verbose log messages")
+	flagSet.Bool(utils.WITH_CATALOG_MODEL, false, "Write a JSON model of every backed up object, with its definition, metadata, and dependencies, alongside the metadata file")
+	flagSet.Bool(utils.WITH_FINGERPRINTS, false, "Record a per-segment row count and checksum for each table's data, for use with gprestore --verify-data")
 	flagSet.Bool(utils.WITH_STATS, false, "Back up query plan statistics")
+	flagSet.Bool(utils.WITHOUT_GLOBALS, false, "Do not back up roles, role GUCs, role grants, resource queues, resource groups, or tablespaces")
 }
 
 // This function handles setup that can be done before parsing flags.
@@ -70,24 +96,65 @@ func DoFlagValidation(cmd *cobra.Command) {
 	ValidateFlagValues()
 }
 
+// --list-only and --diff-timestamp only read from the database and existing backups
+func WritesBackupFiles() bool {
+	return !MustGetFlagBool(utils.LIST_ONLY) && MustGetFlagString(utils.DIFF_TIMESTAMP) == ""
+}
+
 // This function handles setup that must be done after parsing flags.
 func DoSetup() {
 	SetLoggerVerbosity()
 	timestamp := utils.CurrentTimestamp()
-	CreateBackupLockFile(timestamp)
+	if resumeTimestamp := MustGetFlagString(utils.RESUME); resumeTimestamp != "" {
+		timestamp = resumeTimestamp
+	}
+	writeBackupFiles := WritesBackupFiles()
+	if writeBackupFiles {
+		CreateBackupLockFile(timestamp)
+	}
 
-	gplog.Info("Starting backup of database %s", MustGetFlagString(utils.DBNAME))
-	InitializeConnectionPool()
+	consolidate := MustGetFlagString(utils.CONSOLIDATE) != ""
+	if consolidate {
+		/*
+		 * Consolidation only reads and writes backup files, so the database
+		 * is only connected to long enough to find the segments.
+		 */
+		gplog.Info("Starting consolidation of backup with timestamp %s", MustGetFlagString(utils.CONSOLIDATE))
+		connectionPool = dbconn.NewDBConnFromEnvironment(MustGetFlagString(utils.DBNAME))
+		connectionPool.MustConnect(1)
+	} else {
+		gplog.Info("Starting backup of database %s", MustGetFlagString(utils.DBNAME))
+		InitializeConnectionPool()
 
-	InitializeFilterLists()
-	validateFilterLists()
+		InitializeFilterLists()
+		validateFilterLists()
+		if MustGetFlagString(utils.TABLE_PREDICATE_FILE) != "" {
+			InitializeTablePredicates()
+		}
+		if MustGetFlagString(utils.MASKING_RULES_FILE) != "" {
+			rules, err := ReadMaskingRulesFile(MustGetFlagString(utils.MASKING_RULES_FILE))
+			gplog.FatalOnError(err)
+			ValidateMaskingRulesForResume(rules)
+			maskingRules = rules
+			maskingSalt = NewMaskingSalt()
+		}
+	}
 
 	segConfig := cluster.MustGetSegmentConfiguration(connectionPool)
 	globalCluster = cluster.NewCluster(segConfig)
 	segPrefix := backup_filepath.GetSegPrefix(connectionPool)
+	if consolidate {
+		connectionPool.Close()
+		connectionPool = nil
+	}
 	globalFPInfo = backup_filepath.NewFilePathInfo(globalCluster, MustGetFlagString(utils.BACKUP_DIR), timestamp, segPrefix)
-	CreateBackupDirectoriesOnAllHosts()
-	globalTOC = &utils.TOC{}
+	if MustGetFlagString(utils.RESUME) != "" {
+		PrepareBackupDirectoryForResume()
+	}
+	if writeBackupFiles {
+		CreateBackupDirectoriesOnAllHosts()
+	}
+	globalTOC = &utils.TOC{FingerprintVersion: utils.FINGERPRINT_VERSION}
 	globalTOC.InitializeMetadataEntryMap()
 	utils.InitializePipeThroughParameters(!MustGetFlagBool(utils.NO_COMPRESSION), MustGetFlagInt(utils.COMPRESSION_LEVEL))
 
@@ -99,9 +166,11 @@ func DoSetup() {
 		gplog.FatalOnError(err)
 	}
 
-	InitializeBackupReport()
+	if !consolidate && writeBackupFiles {
+		InitializeBackupReport()
+	}
 
-	if pluginConfigFlag != "" {
+	if pluginConfigFlag != "" && writeBackupFiles {
 		pluginConfig.CheckPluginExistsOnAllHosts(globalCluster)
 
 		pluginConfig.CopyPluginConfigToAllHosts(globalCluster, pluginConfigFlag)
@@ -114,7 +183,7 @@ func DoBackup() {
 
 	targetBackupTimestamp := ""
 	var targetBackupFPInfo backup_filepath.FilePathInfo
-	if MustGetFlagBool(utils.INCREMENTAL) {
+	if MustGetFlagBool(utils.INCREMENTAL) || MustGetFlagBool(utils.DIFFERENTIAL) {
 		targetBackupTimestamp = GetTargetBackupTimestamp()
 		targetBackupFPInfo = backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
 			targetBackupTimestamp, globalFPInfo.UserSpecifiedSegPrefix)
@@ -130,13 +199,19 @@ func DoBackup() {
 
 	gplog.Info("Gathering table state information")
 	metadataTables, dataTables := RetrieveAndProcessTables()
+	ValidateTablePredicatesInBackupSet(dataTables)
+	ValidateMaskingRules(connectionPool, dataTables)
+	ValidateTableDataSources(connectionPool, dataTables)
 	if !(MustGetFlagBool(utils.METADATA_ONLY) || MustGetFlagBool(utils.DATA_ONLY)) {
-		BackupIncrementalMetadata()
+		BackupIncrementalMetadata(dataTables)
 	}
 	CheckTablesContainData(dataTables)
 	metadataFilename := globalFPInfo.GetMetadataFilePath()
 	gplog.Info("Metadata will be written to %s", metadataFilename)
 	metadataFile := utils.NewFileWithByteCountFromFile(metadataFilename)
+	if MustGetFlagBool(utils.WITH_CATALOG_MODEL) {
+		catalogModel = NewCatalogModel(connectionPool.DBName, globalFPInfo.Timestamp)
+	}
 
 	BackupSessionGUCs(metadataFile)
 	if !MustGetFlagBool(utils.DATA_ONLY) {
@@ -159,7 +234,7 @@ func DoBackup() {
 
 		targetBackupRestorePlan := make([]backup_history.RestorePlanEntry, 0)
 		if targetBackupTimestamp != "" {
-			gplog.Info("Basing incremental backup off of backup with timestamp = %s", targetBackupTimestamp)
+			gplog.Info("Basing %s backup off of backup with timestamp = %s", backupReport.GetBackupType(), targetBackupTimestamp)
 
 			targetBackupTOC := utils.NewTOC(targetBackupFPInfo.GetTOCFilePath())
 			targetBackupRestorePlan = backup_history.ReadConfigFile(targetBackupFPInfo.GetConfigFilePath()).RestorePlan
@@ -175,14 +250,23 @@ func DoBackup() {
 		backupStatistics(metadataTables)
 	}
 
+	metadataFile.Close()
+	if MustGetFlagString(utils.METADATA_FORMAT) == utils.METADATA_FORMAT_DIRECTORY {
+		WriteMetadataDirectory(metadataFilename)
+	}
 	globalTOC.WriteToFileAndMakeReadOnly(globalFPInfo.GetTOCFilePath())
+	if MustGetFlagBool(utils.WITH_CATALOG_MODEL) {
+		catalogModel.WriteToFileAndMakeReadOnly(globalFPInfo.GetCatalogModelFilePath())
+	}
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		connectionPool.MustCommit(connNum)
 	}
-	metadataFile.Close()
 	if MustGetFlagString(utils.PLUGIN_CONFIG) != "" {
 		pluginConfig.MustBackupFile(metadataFilename)
 		pluginConfig.MustBackupFile(globalFPInfo.GetTOCFilePath())
+		if MustGetFlagBool(utils.WITH_CATALOG_MODEL) {
+			pluginConfig.MustBackupFile(globalFPInfo.GetCatalogModelFilePath())
+		}
 		if MustGetFlagBool(utils.WITH_STATS) {
 			pluginConfig.MustBackupFile(globalFPInfo.GetStatisticsFilePath())
 		}
@@ -190,21 +274,27 @@ func DoBackup() {
 
 	err := backup_history.WriteBackupHistory(globalFPInfo.GetBackupHistoryFilePath(), &backupReport.BackupConfig)
 	gplog.FatalOnError(err)
+	RemoveCompletedTablesFile()
 }
 
 func backupGlobal(metadataFile *utils.FileWithByteCount) {
 	gplog.Info("Writing global database metadata")
 
-	BackupResourceQueues(metadataFile)
-	if connectionPool.Version.AtLeast("5") {
-		BackupResourceGroups(metadataFile)
+	withGlobals := !MustGetFlagBool(utils.WITHOUT_GLOBALS)
+	if withGlobals {
+		BackupResourceQueues(metadataFile)
+		if connectionPool.Version.AtLeast("5") {
+			BackupResourceGroups(metadataFile)
+		}
+		BackupRoles(metadataFile)
+		BackupRoleGrants(metadataFile)
+		BackupTablespaces(metadataFile)
 	}
-	BackupRoles(metadataFile)
-	BackupRoleGrants(metadataFile)
-	BackupTablespaces(metadataFile)
 	BackupCreateDatabase(metadataFile)
 	BackupDatabaseGUCs(metadataFile)
-	BackupRoleGUCs(metadataFile)
+	if withGlobals {
+		BackupRoleGUCs(metadataFile)
+	}
 
 	if wasTerminated {
 		gplog.Info("Global database metadata backup incomplete")
@@ -219,73 +309,70 @@ func backupPredata(metadataFile *utils.FileWithByteCount, tables []Table, tableO
 	}
 	gplog.Info("Writing pre-data metadata")
 
+	objects := RetrievePredataObjects(tableOnly)
+	catalogModel.AddPredataObjects(tables, objects)
+
 	sortables := make([]Sortable, 0)
 	metadataMap := make(MetadataMap)
 	sortables = append(sortables, convertToSortableSlice(tables)...)
-	relationMetadata := GetMetadataForObjectType(connectionPool, TYPE_RELATION)
-	addToMetadataMap(relationMetadata, metadataMap)
-
-	var protocols []ExternalProtocol
-	funcInfoMap := GetFunctionOidToInfoMap(connectionPool)
+	addToMetadataMap(objects.RelationMetadata, metadataMap)
 
 	if !tableOnly {
-		BackupSchemas(metadataFile)
+		BackupSchemas(metadataFile, objects.Schemas, objects.SchemaMetadata)
 		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 && connectionPool.Version.AtLeast("5") {
-			BackupExtensions(metadataFile)
+			BackupExtensions(metadataFile, objects.Extensions, objects.ExtensionMetadata)
 		}
 
 		if connectionPool.Version.AtLeast("6") {
-			BackupCollations(metadataFile)
+			BackupCollations(metadataFile, objects.Collations, objects.CollationMetadata)
 		}
 
-		procLangs := GetProceduralLanguages(connectionPool)
-		langFuncs, functionMetadata := RetrieveFunctions(&sortables, metadataMap, procLangs)
-		types, typeMetadata := RetrieveTypes(&sortables, metadataMap)
+		langFuncs := RetrieveFunctions(&sortables, metadataMap, objects)
+		types := RetrieveTypes(&sortables, metadataMap, objects)
 
 		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 {
-			BackupProceduralLanguages(metadataFile, procLangs, langFuncs, functionMetadata, funcInfoMap)
+			BackupProceduralLanguages(metadataFile, objects.ProcLangs, langFuncs, objects.FunctionMetadata, objects.FuncInfoMap, objects.ProcLangMetadata)
 		}
 
 		BackupShellTypes(metadataFile, types)
 		if connectionPool.Version.AtLeast("5") {
-			BackupEnumTypes(metadataFile, typeMetadata)
+			BackupEnumTypes(metadataFile, objects.EnumTypes, objects.TypeMetadata)
 		}
 
 		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 &&
 			connectionPool.Version.AtLeast("6") {
-			RetrieveForeignDataWrappers(&sortables, metadataMap)
-			RetrieveForeignServers(&sortables, metadataMap)
-			RetrieveUserMappings(&sortables)
+			addSortableObjects(&sortables, metadataMap, "Foreign Data Wrappers", objects.ForeignDataWrappers, objects.ForeignDataWrapperMetadata)
+			addSortableObjects(&sortables, metadataMap, "Foreign Servers", objects.ForeignServers, objects.ForeignServerMetadata)
+			addSortableObjects(&sortables, metadataMap, "User Mappings", objects.UserMappings, nil)
 		}
 
-		protocols = RetrieveProtocols(&sortables, metadataMap)
+		addSortableObjects(&sortables, metadataMap, "Protocols", objects.Protocols, objects.ProtocolMetadata)
 
 		if connectionPool.Version.AtLeast("5") {
-			RetrieveTSParsers(&sortables, metadataMap)
-			RetrieveTSConfigurations(&sortables, metadataMap)
-			RetrieveTSTemplates(&sortables, metadataMap)
-			RetrieveTSDictionaries(&sortables, metadataMap)
+			addSortableObjects(&sortables, metadataMap, "Text Search Parsers", objects.TSParsers, objects.TSParserMetadata)
+			addSortableObjects(&sortables, metadataMap, "Text Search Configurations", objects.TSConfigurations, objects.TSConfigurationMetadata)
+			addSortableObjects(&sortables, metadataMap, "Text Search Templates", objects.TSTemplates, objects.TSTemplateMetadata)
+			addSortableObjects(&sortables, metadataMap, "Text Search Dictionaries", objects.TSDictionaries, objects.TSDictionaryMetadata)
 
-			BackupOperatorFamilies(metadataFile)
+			BackupOperatorFamilies(metadataFile, objects.OperatorFamilies, objects.OperatorFamilyMetadata)
 		}
 
-		RetrieveOperators(&sortables, metadataMap)
-		RetrieveOperatorClasses(&sortables, metadataMap)
-		RetrieveAggregates(&sortables, metadataMap)
-		RetrieveCasts(&sortables, metadataMap)
+		addSortableObjects(&sortables, metadataMap, "Operators", objects.Operators, objects.OperatorMetadata)
+		addSortableObjects(&sortables, metadataMap, "Operator Classes", objects.OperatorClasses, objects.OperatorClassMetadata)
+		addSortableObjects(&sortables, metadataMap, "Aggregates", objects.Aggregates, objects.AggregateMetadata)
+		addSortableObjects(&sortables, metadataMap, "Casts", objects.Casts, objects.CastMetadata)
 	}
 
-	RetrieveViews(&sortables)
-	sequences, sequenceOwnerColumns := RetrieveSequences()
-	BackupCreateSequences(metadataFile, sequences, relationMetadata)
-	constraints, conMetadata := RetrieveConstraints()
+	addSortableObjects(&sortables, metadataMap, "Views", objects.Views, nil)
+	BackupCreateSequences(metadataFile, objects.Sequences, objects.RelationMetadata)
 
-	BackupDependentObjects(metadataFile, tables, protocols, metadataMap, constraints, sortables, funcInfoMap, tableOnly)
+	BackupDependentObjects(metadataFile, tables, objects.Protocols, metadataMap, objects.Constraints, sortables, objects.FuncInfoMap,
+		objects.ExternalPartitions, objects.PartitionInfoMap, tableOnly)
 
-	PrintAlterSequenceStatements(metadataFile, globalTOC, sequences, sequenceOwnerColumns)
+	PrintAlterSequenceStatements(metadataFile, globalTOC, objects.Sequences, objects.SequenceOwnerColumns)
 
-	BackupConversions(metadataFile)
-	BackupConstraints(metadataFile, constraints, conMetadata)
+	BackupConversions(metadataFile, objects.Conversions, objects.ConversionMetadata)
+	BackupConstraints(metadataFile, objects.Constraints, objects.ConstraintMetadata)
 	if wasTerminated {
 		gplog.Info("Pre-data metadata backup incomplete")
 	} else {
@@ -294,6 +381,16 @@ func backupPredata(metadataFile *utils.FileWithByteCount, tables []Table, tableO
 }
 
 func backupData(tables []Table) {
+	reusedRecords := make([]CompletedTableRecord, 0)
+	if MustGetFlagString(utils.RESUME) != "" {
+		records := ReadCompletedTableRecords(globalFPInfo.GetCompletedTablesFilePath())
+		tables, reusedRecords = GetTablesToResume(tables, records, globalTOC)
+		gplog.Info("Reusing data files for %d tables from the previous attempt of this backup", len(reusedRecords))
+		RemoveIncompleteDataFiles(reusedRecords)
+	}
+	if IsTrackingCompletedTables() {
+		OpenCompletedTablesFile(globalFPInfo.GetCompletedTablesFilePath(), reusedRecords)
+	}
 	if MustGetFlagBool(utils.SINGLE_DATA_FILE) {
 		gplog.Verbose("Initializing pipes and gpbackup_helper on segments for single data file backup")
 		utils.VerifyHelperVersionOnSegments(version, globalCluster)
@@ -313,8 +410,11 @@ func backupData(tables []Table) {
 			MustGetFlagString(utils.PLUGIN_CONFIG), compressStr)
 	}
 	gplog.Info("Writing data to file")
-	rowsCopiedMaps := BackupDataForAllTables(tables)
-	AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+	rowsCopiedMaps, fingerprintMaps := BackupDataForAllTables(tables)
+	AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
+	for _, record := range reusedRecords {
+		globalTOC.AddMasterDataEntry(record.DataEntry)
+	}
 	if MustGetFlagBool(utils.SINGLE_DATA_FILE) && MustGetFlagString(utils.PLUGIN_CONFIG) != "" {
 		pluginConfig.BackupSegmentTOCs(globalCluster, globalFPInfo)
 	}
@@ -331,13 +431,16 @@ func backupPostdata(metadataFile *utils.FileWithByteCount) {
 	}
 	gplog.Info("Writing post-data metadata")
 
-	BackupIndexes(metadataFile)
-	BackupRules(metadataFile)
-	BackupTriggers(metadataFile)
+	objects := RetrievePostdataObjects()
+	catalogModel.AddPostdataObjects(objects)
+
+	BackupIndexes(metadataFile, objects.Indexes, objects.IndexMetadata)
+	BackupRules(metadataFile, objects.Rules, objects.RuleMetadata)
+	BackupTriggers(metadataFile, objects.Triggers, objects.TriggerMetadata)
 	if connectionPool.Version.AtLeast("6") {
-		BackupDefaultPrivileges(metadataFile)
+		BackupDefaultPrivileges(metadataFile, objects.DefaultPrivileges)
 		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 {
-			BackupEventTriggers(metadataFile)
+			BackupEventTriggers(metadataFile, objects.EventTriggers, objects.EventTriggerMetadata)
 		}
 	}
 	if wasTerminated {
@@ -368,7 +471,7 @@ func DoTeardown() {
 		DoCleanup()
 
 		errorCode := gplog.GetErrorCode()
-		if errorCode == 0 {
+		if errorCode == 0 && WritesBackupFiles() {
 			gplog.Info("Backup completed successfully")
 		}
 		os.Exit(errorCode)
@@ -461,6 +564,9 @@ func DoCleanup() {
 	if err != nil && backupLockFile != "" {
 		gplog.Warn("Failed to remove lock file %s.", backupLockFile)
 	}
+	if lockConnection != nil {
+		lockConnection.Close()
+	}
 	if connectionPool != nil {
 		connectionPool.Close()
 	}
@@ -0,0 +1,172 @@
+package backup
+
+/*
+ * This file contains structs and functions related to writing a JSON model of
+ * the objects retrieved from the catalog, for --with-catalog-model.
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * Increment this whenever a field of CatalogModel or CatalogObject is added,
+ * removed, or changes meaning.  Fields added to the Definition structs do not
+ * change the version, as consumers are expected to ignore unknown fields.
+ */
+const CATALOG_MODEL_VERSION = 1
+
+/*
+ * The catalog model contains one CatalogObject for each object backed up,
+ * identified by the same Section, ObjectType, Schema, Name, and
+ * ReferenceObject as its entries in the table of contents.
+ *
+ * Definition is the struct that gpbackup retrieved for the object, such as a
+ * Table with its ColumnDefs or a Function, with the same field names as in the
+ * backup package.  Metadata holds the object's owner, privileges, comment, and
+ * security label, if it has any.  Dependencies lists the pre-data objects the
+ * object depends on, in the "<OBJECT TYPE> <schema>.<name>" format used by the
+ * PredataDependencies of the table of contents.
+ */
+type CatalogModel struct {
+	Version      int
+	Database     string
+	Timestamp    string
+	Objects      []CatalogObject
+	dependencies map[UniqueID][]string
+}
+
+type CatalogObject struct {
+	Section         string
+	ObjectType      string
+	Schema          string
+	Name            string
+	ReferenceObject string
+	Definition      interface{}
+	Metadata        *ObjectMetadata `json:",omitempty"`
+	Dependencies    []string        `json:",omitempty"`
+	uniqueID        UniqueID
+}
+
+func NewCatalogModel(database string, timestamp string) *CatalogModel {
+	return &CatalogModel{Version: CATALOG_MODEL_VERSION, Database: database, Timestamp: timestamp, Objects: make([]CatalogObject, 0)}
+}
+
+/*
+ * The model is nil unless --with-catalog-model is passed, in which case
+ * adding objects to it does nothing, so that the metadata backup functions
+ * shared with --list-only and --diff-timestamp need not check the flag.
+ */
+func (model *CatalogModel) AddObjects(objSlice interface{}, metadataMap MetadataMap) {
+	if model == nil {
+		return
+	}
+	objects := reflect.ValueOf(objSlice)
+	for i := 0; i < objects.Len(); i++ {
+		obj := objects.Index(i).Interface()
+		tocObject, ok := obj.(utils.TOCObject)
+		if !ok {
+			continue
+		}
+		section, entry := tocObject.GetMetadataEntry()
+		catalogObject := CatalogObject{Section: section, ObjectType: entry.ObjectType, Schema: entry.Schema,
+			Name: entry.Name, ReferenceObject: entry.ReferenceObject, Definition: obj}
+		if uniqueObj, ok := obj.(interface{ GetUniqueID() UniqueID }); ok {
+			catalogObject.uniqueID = uniqueObj.GetUniqueID()
+			if objMetadata, ok := metadataMap[uniqueObj.GetUniqueID()]; ok {
+				catalogObject.Metadata = &objMetadata
+			}
+		}
+		model.Objects = append(model.Objects, catalogObject)
+	}
+}
+
+func (model *CatalogModel) AddPredataObjects(tables []Table, objects PredataObjects) {
+	model.AddObjects(objects.Schemas, objects.SchemaMetadata)
+	model.AddObjects(objects.Extensions, objects.ExtensionMetadata)
+	model.AddObjects(objects.Collations, objects.CollationMetadata)
+	model.AddObjects(objects.ProcLangs, objects.ProcLangMetadata)
+	model.AddObjects(objects.Functions, objects.FunctionMetadata)
+	for _, types := range [][]Type{objects.ShellTypes, objects.BaseTypes, objects.CompositeTypes,
+		objects.DomainTypes, objects.RangeTypes, objects.EnumTypes} {
+		model.AddObjects(types, objects.TypeMetadata)
+	}
+	model.AddObjects(objects.ForeignDataWrappers, objects.ForeignDataWrapperMetadata)
+	model.AddObjects(objects.ForeignServers, objects.ForeignServerMetadata)
+	model.AddObjects(objects.UserMappings, nil)
+	model.AddObjects(objects.Protocols, objects.ProtocolMetadata)
+	model.AddObjects(objects.TSParsers, objects.TSParserMetadata)
+	model.AddObjects(objects.TSConfigurations, objects.TSConfigurationMetadata)
+	model.AddObjects(objects.TSTemplates, objects.TSTemplateMetadata)
+	model.AddObjects(objects.TSDictionaries, objects.TSDictionaryMetadata)
+	model.AddObjects(objects.OperatorFamilies, objects.OperatorFamilyMetadata)
+	model.AddObjects(objects.Operators, objects.OperatorMetadata)
+	model.AddObjects(objects.OperatorClasses, objects.OperatorClassMetadata)
+	model.AddObjects(objects.Aggregates, objects.AggregateMetadata)
+	model.AddObjects(objects.Casts, objects.CastMetadata)
+	model.AddObjects(tables, objects.RelationMetadata)
+	model.AddObjects(objects.Sequences, objects.RelationMetadata)
+	model.AddObjects(objects.Views, objects.RelationMetadata)
+	model.AddObjects(objects.Constraints, objects.ConstraintMetadata)
+	model.AddObjects(objects.Conversions, objects.ConversionMetadata)
+	model.AddObjects(objects.ExternalPartitions, nil)
+}
+
+func (model *CatalogModel) AddPostdataObjects(objects PostdataObjects) {
+	model.AddObjects(objects.Indexes, objects.IndexMetadata)
+	model.AddObjects(objects.Rules, objects.RuleMetadata)
+	model.AddObjects(objects.Triggers, objects.TriggerMetadata)
+	model.AddObjects(objects.DefaultPrivileges, nil)
+	model.AddObjects(objects.EventTriggers, objects.EventTriggerMetadata)
+}
+
+func (model *CatalogModel) AddRoleGUCs(roleGUCs map[string][]RoleGUC) {
+	if model == nil {
+		return
+	}
+	roleNames := make([]string, 0, len(roleGUCs))
+	for roleName := range roleGUCs {
+		roleNames = append(roleNames, roleName)
+	}
+	sort.Strings(roleNames)
+	for _, roleName := range roleNames {
+		model.AddObjects(roleGUCs[roleName], nil)
+	}
+}
+
+/*
+ * Dependencies are only known once the pre-data objects have been sorted, so
+ * they are stored by the UniqueID of each object and added to the objects when
+ * the model is written.  Looking them up by UniqueID rather than by name keeps
+ * objects that share a type, schema, and name from getting each other's
+ * dependencies.
+ */
+func (model *CatalogModel) SetDependencies(dependencies map[UniqueID][]string) {
+	if model == nil {
+		return
+	}
+	model.dependencies = dependencies
+}
+
+func (model *CatalogModel) WriteToFileAndMakeReadOnly(filename string) {
+	for i, object := range model.Objects {
+		if object.Section == "predata" {
+			model.Objects[i].Dependencies = model.dependencies[object.uniqueID]
+		}
+	}
+	modelFile := iohelper.MustOpenFileForWriting(filename)
+	modelContents, err := json.MarshalIndent(model, "", "  ")
+	gplog.FatalOnError(err)
+	utils.MustPrintBytes(modelFile, append(modelContents, '\n'))
+	err = modelFile.Close()
+	gplog.FatalOnError(err)
+	err = operating.System.Chmod(filename, 0444)
+	gplog.FatalOnError(err)
+}
@@ -0,0 +1,107 @@
+package backup_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("backup/catalog_model tests", func() {
+	var (
+		model    *backup.CatalogModel
+		table    backup.Table
+		schema   backup.Schema
+		metadata backup.MetadataMap
+	)
+	BeforeEach(func() {
+		model = backup.NewCatalogModel("testdb", "20170101010101")
+		table = backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "foo"}}
+		schema = backup.Schema{Oid: 2, Name: "public"}
+		metadata = backup.MetadataMap{table.GetUniqueID(): {Owner: "testrole", Comment: "This is a table comment."}}
+	})
+	Describe("AddObjects", func() {
+		It("adds each object with its table of contents identifiers and metadata", func() {
+			model.AddObjects([]backup.Table{table}, metadata)
+			model.AddObjects([]backup.Schema{schema}, metadata)
+
+			Expect(model.Objects).To(HaveLen(2))
+			Expect(model.Objects[0].Section).To(Equal("predata"))
+			Expect(model.Objects[0].ObjectType).To(Equal("TABLE"))
+			Expect(model.Objects[0].Schema).To(Equal("public"))
+			Expect(model.Objects[0].Name).To(Equal("foo"))
+			Expect(model.Objects[0].Definition).To(Equal(table))
+			Expect(model.Objects[0].Metadata.Owner).To(Equal("testrole"))
+			Expect(model.Objects[1].ObjectType).To(Equal("SCHEMA"))
+			Expect(model.Objects[1].Metadata).To(BeNil())
+		})
+		It("does nothing if the model is nil", func() {
+			var nilModel *backup.CatalogModel
+			nilModel.AddObjects([]backup.Table{table}, metadata)
+			nilModel.AddPredataObjects([]backup.Table{table}, backup.PredataObjects{})
+			nilModel.AddRoleGUCs(map[string][]backup.RoleGUC{"testrole": {{RoleName: "testrole", Config: "SET search_path TO public"}}})
+			nilModel.SetDependencies(map[backup.UniqueID][]string{table.GetUniqueID(): {"SCHEMA public"}})
+		})
+	})
+	Describe("WriteToFileAndMakeReadOnly", func() {
+		var buffer *gbytes.Buffer
+		var chmodMode os.FileMode
+		BeforeEach(func() {
+			buffer = gbytes.NewBuffer()
+			operating.System.OpenFileWrite = func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				return buffer, nil
+			}
+			operating.System.Chmod = func(name string, mode os.FileMode) error {
+				chmodMode = mode
+				return nil
+			}
+		})
+		AfterEach(func() {
+			operating.System.OpenFileWrite = operating.OpenFileWrite
+			operating.System.Chmod = os.Chmod
+		})
+		It("writes the versioned model with the pre-data dependencies of each object", func() {
+			model.AddObjects([]backup.Schema{schema}, nil)
+			model.AddObjects([]backup.Table{table}, metadata)
+
+			model.SetDependencies(map[backup.UniqueID][]string{table.GetUniqueID(): {"SCHEMA public"}})
+			model.WriteToFileAndMakeReadOnly("/tmp/catalog.json")
+
+			written := struct {
+				Version   int
+				Database  string
+				Timestamp string
+				Objects   []map[string]interface{}
+			}{}
+			Expect(json.Unmarshal(buffer.Contents(), &written)).To(Succeed())
+			Expect(written.Version).To(Equal(backup.CATALOG_MODEL_VERSION))
+			Expect(written.Database).To(Equal("testdb"))
+			Expect(written.Timestamp).To(Equal("20170101010101"))
+			Expect(written.Objects).To(HaveLen(2))
+			Expect(written.Objects[0]).ToNot(HaveKey("Dependencies"))
+			Expect(written.Objects[1]["Dependencies"]).To(Equal([]interface{}{"SCHEMA public"}))
+			Expect(written.Objects[1]["Metadata"]).To(HaveKeyWithValue("Owner", "testrole"))
+			Expect(written.Objects[1]["Definition"]).To(HaveKeyWithValue("Name", "foo"))
+			Expect(chmodMode).To(Equal(os.FileMode(0444)))
+		})
+		It("gives objects with the same type, schema, and name their own dependencies", func() {
+			constraint1 := backup.Constraint{Oid: 3, Schema: "public", Name: "check_positive", OwningObject: "public.foo"}
+			constraint2 := backup.Constraint{Oid: 4, Schema: "public", Name: "check_positive", OwningObject: "public.bar"}
+			model.AddObjects([]backup.Constraint{constraint1, constraint2}, nil)
+
+			model.SetDependencies(map[backup.UniqueID][]string{
+				constraint1.GetUniqueID(): {"TABLE public.foo"},
+				constraint2.GetUniqueID(): {"TABLE public.bar"},
+			})
+			model.WriteToFileAndMakeReadOnly("/tmp/catalog.json")
+
+			Expect(model.Objects[0].Dependencies).To(Equal([]string{"TABLE public.foo"}))
+			Expect(model.Objects[1].Dependencies).To(Equal([]string{"TABLE public.bar"}))
+		})
+	})
+})
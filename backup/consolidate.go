@@ -0,0 +1,310 @@
+package backup
+
+/*
+ * This file contains functions related to consolidating an incremental backup
+ * chain into a new full backup set using only the files already on disk.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+func DoConsolidate() {
+	sourceTimestamp := MustGetFlagString(utils.CONSOLIDATE)
+	sourceFPInfo := backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
+		sourceTimestamp, globalFPInfo.UserSpecifiedSegPrefix)
+	sourceConfig := backup_history.ReadConfigFile(sourceFPInfo.GetConfigFilePath())
+	ValidateConsolidationSource(sourceConfig)
+	utils.InitializePipeThroughParameters(sourceConfig.Compressed, 0)
+
+	gplog.Info("Consolidating backup with timestamp = %s into new full backup with timestamp = %s", sourceTimestamp, globalFPInfo.Timestamp)
+	chainFPInfos := make([]backup_filepath.FilePathInfo, 0, len(sourceConfig.RestorePlan))
+	chainTOCs := make([]*utils.TOC, 0, len(sourceConfig.RestorePlan))
+	for _, restorePlanEntry := range sourceConfig.RestorePlan {
+		entryFPInfo := backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
+			restorePlanEntry.Timestamp, globalFPInfo.UserSpecifiedSegPrefix)
+		chainFPInfos = append(chainFPInfos, entryFPInfo)
+		chainTOCs = append(chainTOCs, utils.NewTOC(entryFPInfo.GetTOCFilePath()))
+	}
+	dataEntries := GetConsolidatedDataEntries(sourceConfig.RestorePlan, chainTOCs)
+
+	if sourceConfig.SingleDataFile {
+		ConsolidateSingleDataFiles(chainFPInfos, dataEntries)
+	} else {
+		LinkTableDataFiles(chainFPInfos, dataEntries)
+	}
+
+	gplog.Info("Writing metadata and table of contents for backup with timestamp = %s", globalFPInfo.Timestamp)
+	if sourceConfig.MetadataFormat == utils.METADATA_FORMAT_DIRECTORY {
+		/*
+		 * The paths in the TOC are relative to the backup directory, so the
+		 * copied TOC refers to the copied statement files.
+		 */
+		mustCopyDirectory(path.Join(sourceFPInfo.GetDirForContent(-1), "metadata"), path.Join(globalFPInfo.GetDirForContent(-1), "metadata"))
+	} else {
+		mustCopyFile(sourceFPInfo.GetMetadataFilePath(), globalFPInfo.GetMetadataFilePath())
+	}
+	if sourceConfig.WithStatistics {
+		mustCopyFile(sourceFPInfo.GetStatisticsFilePath(), globalFPInfo.GetStatisticsFilePath())
+	}
+	consolidatedTOC := chainTOCs[len(chainTOCs)-1]
+	consolidatedTOC.DataEntries = make([]utils.MasterDataEntry, 0)
+	tableFQNs := make([]string, 0)
+	for _, entries := range dataEntries {
+		for _, entry := range entries {
+			consolidatedTOC.DataEntries = append(consolidatedTOC.DataEntries, entry)
+			tableFQNs = append(tableFQNs, utils.MakeFQN(entry.Schema, entry.Name))
+		}
+	}
+	consolidatedTOC.WriteToFileAndMakeReadOnly(globalFPInfo.GetTOCFilePath())
+
+	backupReport = &utils.Report{
+		BackupConfig: *NewConsolidatedBackupConfig(sourceConfig, globalFPInfo.Timestamp, tableFQNs),
+	}
+	err := backup_history.WriteBackupHistory(globalFPInfo.GetBackupHistoryFilePath(), &backupReport.BackupConfig)
+	gplog.FatalOnError(err)
+}
+
+func ValidateConsolidationSource(sourceConfig *backup_history.BackupConfig) {
+	if sourceConfig.MetadataOnly || sourceConfig.DataOnly {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s does not contain both data and metadata and cannot be consolidated.", sourceConfig.Timestamp), "")
+	}
+	if sourceConfig.Plugin != "" {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s was taken with a plugin.  Backups stored using a plugin cannot be consolidated.", sourceConfig.Timestamp), "")
+	}
+	if len(sourceConfig.RestorePlan) == 0 || sourceConfig.RestorePlan[len(sourceConfig.RestorePlan)-1].Timestamp != sourceConfig.Timestamp {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s does not have a valid restore plan.", sourceConfig.Timestamp), "")
+	}
+}
+
+/*
+ * The returned slice has one element per restore plan entry, holding the TOC
+ * data entries for the tables whose newest data is in that backup, in the
+ * order they appear in that backup's TOC.  Keeping TOC order matters for
+ * single-data-file backups, as the helper can only read forward through the
+ * data file.
+ */
+func GetConsolidatedDataEntries(restorePlan []backup_history.RestorePlanEntry, tocs []*utils.TOC) [][]utils.MasterDataEntry {
+	dataEntries := make([][]utils.MasterDataEntry, len(restorePlan))
+	for i, restorePlanEntry := range restorePlan {
+		tableSet := utils.NewSet(restorePlanEntry.TableFQNs)
+		dataEntries[i] = make([]utils.MasterDataEntry, 0, len(restorePlanEntry.TableFQNs))
+		for _, entry := range tocs[i].DataEntries {
+			if tableSet.MatchesFilter(utils.MakeFQN(entry.Schema, entry.Name)) {
+				dataEntries[i] = append(dataEntries[i], entry)
+			}
+		}
+		if len(dataEntries[i]) != len(restorePlanEntry.TableFQNs) {
+			gplog.Fatal(errors.Errorf("The table of contents for the backup with timestamp = %s is missing data entries for %d tables in its restore plan.",
+				restorePlanEntry.Timestamp, len(restorePlanEntry.TableFQNs)-len(dataEntries[i])), "")
+		}
+	}
+	return dataEntries
+}
+
+func NewConsolidatedBackupConfig(sourceConfig *backup_history.BackupConfig, timestamp string, tableFQNs []string) *backup_history.BackupConfig {
+	config := *sourceConfig
+	config.BackupType = backup_history.FULL_BACKUP
+	config.BackupVersion = version
+	config.Deleted = false
+	config.Incremental = false
+	config.Timestamp = timestamp
+	config.RestorePlan = []backup_history.RestorePlanEntry{{Timestamp: timestamp, TableFQNs: tableFQNs}}
+	return &config
+}
+
+/*
+ * The commands run on each segment grow with the number of tables in the
+ * backup, so rather than passing them on the ssh command line, where they could
+ * exceed the maximum argument length, each segment's commands are written to a
+ * script file in the master backup directory, which the segment copies to its
+ * data directory and runs.
+ */
+func RunConsolidationScripts(verboseMsg string, errMsg string, generateScript func(contentID int) string) {
+	masterScriptFiles := make(map[int]string, 0)
+	defer func() {
+		for _, scriptFile := range masterScriptFiles {
+			_ = os.Remove(scriptFile)
+		}
+	}()
+	for _, contentID := range globalCluster.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		scriptFile := path.Join(globalFPInfo.GetDirForContent(-1), fmt.Sprintf("gpbackup_%d_%s_consolidate_script", contentID, globalFPInfo.Timestamp))
+		masterScriptFiles[contentID] = scriptFile
+		file := iohelper.MustOpenFileForWriting(scriptFile)
+		_, err := file.Write([]byte(generateScript(contentID)))
+		gplog.FatalOnError(err)
+		err = file.Close()
+		gplog.FatalOnError(err)
+	}
+	masterHost := globalCluster.GetHostForContent(-1)
+	remoteOutput := globalCluster.GenerateAndExecuteCommand(verboseMsg, func(contentID int) string {
+		scriptFile := globalFPInfo.GetSegmentHelperFilePath(contentID, "consolidate_script")
+		return fmt.Sprintf("rsync %s:%s %s && bash %s; status=$?; rm -f %s; exit $status", masterHost, masterScriptFiles[contentID], scriptFile, scriptFile, scriptFile)
+	}, cluster.ON_SEGMENTS)
+	globalCluster.CheckClusterError(remoteOutput, errMsg, func(contentID int) string {
+		return fmt.Sprintf("%s for segment %d on host %s", errMsg, contentID, globalCluster.GetHostForContent(contentID))
+	})
+}
+
+func LinkTableDataFiles(chainFPInfos []backup_filepath.FilePathInfo, dataEntries [][]utils.MasterDataEntry) {
+	RunConsolidationScripts("Linking table data files into consolidated backup directories", "Unable to link table data files into consolidated backup directories", func(contentID int) string {
+		return GetLinkTableDataFilesScript(contentID, chainFPInfos, dataEntries)
+	})
+}
+
+/*
+ * Each table's data file is hard-linked into the new backup directory, falling
+ * back to a copy if the chain is spread across filesystems.
+ */
+func GetLinkTableDataFilesScript(contentID int, chainFPInfos []backup_filepath.FilePathInfo, dataEntries [][]utils.MasterDataEntry) string {
+	extension := utils.GetPipeThroughProgram().Extension
+	script := bytes.NewBufferString("set -e\n")
+	for i, fpInfo := range chainFPInfos {
+		for _, entry := range dataEntries[i] {
+			sourceFile := fpInfo.GetTableBackupFilePath(contentID, entry.Oid, extension, false)
+			targetFile := globalFPInfo.GetTableBackupFilePath(contentID, entry.Oid, extension, false)
+			fmt.Fprintf(script, "ln %s %s 2>/dev/null || cp %s %s\n", sourceFile, targetFile, sourceFile, targetFile)
+		}
+	}
+	return script.String()
+}
+
+/*
+ * A single data file can only be read from front to back, so each data file
+ * in the chain that still holds current data for at least one table is read
+ * through once, and only the byte ranges of those tables are copied into the
+ * new data file.  Offsets refer to uncompressed data, so compressed data files
+ * are decompressed as they are read and the new data file is compressed again.
+ */
+func ConsolidateSingleDataFiles(chainFPInfos []backup_filepath.FilePathInfo, dataEntries [][]utils.MasterDataEntry) {
+	segmentTOCs := make(map[int][]*utils.SegmentTOC, 0)
+	for _, fpInfo := range chainFPInfos {
+		remoteOutput := globalCluster.GenerateAndExecuteCommand(fmt.Sprintf("Reading segment table of contents files for backup %s", fpInfo.Timestamp), func(contentID int) string {
+			return fmt.Sprintf("cat %s", fpInfo.GetSegmentTOCFilePath(contentID))
+		}, cluster.ON_SEGMENTS)
+		globalCluster.CheckClusterError(remoteOutput, fmt.Sprintf("Unable to read segment table of contents files for backup %s", fpInfo.Timestamp), func(contentID int) string {
+			return fmt.Sprintf("Unable to read segment table of contents file %s on host %s", fpInfo.GetSegmentTOCFilePath(contentID), globalCluster.GetHostForContent(contentID))
+		})
+		for contentID, contents := range remoteOutput.Stdouts {
+			segmentTOC := &utils.SegmentTOC{}
+			err := yaml.Unmarshal([]byte(contents), segmentTOC)
+			gplog.FatalOnError(err)
+			segmentTOCs[contentID] = append(segmentTOCs[contentID], segmentTOC)
+		}
+	}
+
+	RunConsolidationScripts("Writing consolidated data files and segment table of contents files", "Unable to write consolidated data files", func(contentID int) string {
+		consolidatedTOC, byteRanges := ConsolidateSegmentTOCs(segmentTOCs[contentID], dataEntries)
+		return GetConsolidatedDataFileScript(contentID, chainFPInfos, byteRanges, consolidatedTOC)
+	})
+}
+
+/*
+ * Each byte range is copied with head -c, which reads no more than the bytes
+ * it is asked for, so a series of head -c commands reading the same pipe
+ * alternately skip and copy the ranges in order.  Whatever follows the last
+ * range is read and discarded so that the decompression does not fail with a
+ * broken pipe.
+ */
+func GetConsolidatedDataFileScript(contentID int, chainFPInfos []backup_filepath.FilePathInfo, byteRanges [][]utils.SegmentDataEntry, consolidatedTOC *utils.SegmentTOC) string {
+	program := utils.GetPipeThroughProgram()
+	tocContents, err := yaml.Marshal(consolidatedTOC)
+	gplog.FatalOnError(err)
+	script := bytes.NewBufferString("set -e -o pipefail\n{\n")
+	for i, ranges := range byteRanges {
+		if len(ranges) == 0 {
+			continue
+		}
+		fmt.Fprintf(script, "%s < %s | {", program.InputCommand, chainFPInfos[i].GetTableBackupFilePath(contentID, 0, program.Extension, true))
+		var position uint64
+		for _, byteRange := range ranges {
+			if byteRange.StartByte > position {
+				fmt.Fprintf(script, " head -c %d > /dev/null;", byteRange.StartByte-position)
+			}
+			if byteRange.EndByte > byteRange.StartByte {
+				fmt.Fprintf(script, " head -c %d;", byteRange.EndByte-byteRange.StartByte)
+			}
+			position = byteRange.EndByte
+		}
+		script.WriteString(" cat > /dev/null; }\n")
+	}
+	tocFile := globalFPInfo.GetSegmentTOCFilePath(contentID)
+	fmt.Fprintf(script, "} | %s > %s\ncat << 'TOC' > %s\n%sTOC\nchmod 444 %s\n",
+		program.OutputCommand, globalFPInfo.GetTableBackupFilePath(contentID, 0, program.Extension, true), tocFile, tocContents, tocFile)
+	return script.String()
+}
+
+/*
+ * This function returns the segment TOC for the consolidated data file along
+ * with, for each data file in the chain, the byte ranges to copy from it in
+ * the order they appear in that file.  The copied ranges are placed one after
+ * another in the consolidated data file.
+ */
+func ConsolidateSegmentTOCs(segmentTOCs []*utils.SegmentTOC, dataEntries [][]utils.MasterDataEntry) (*utils.SegmentTOC, [][]utils.SegmentDataEntry) {
+	consolidatedTOC := &utils.SegmentTOC{DataEntries: make(map[uint]utils.SegmentDataEntry, 0)}
+	byteRanges := make([][]utils.SegmentDataEntry, len(segmentTOCs))
+	var offset uint64
+	for i, segmentTOC := range segmentTOCs {
+		entries := make([]utils.MasterDataEntry, len(dataEntries[i]))
+		copy(entries, dataEntries[i])
+		sort.SliceStable(entries, func(j int, k int) bool {
+			return segmentTOC.DataEntries[uint(entries[j].Oid)].StartByte < segmentTOC.DataEntries[uint(entries[k].Oid)].StartByte
+		})
+		byteRanges[i] = make([]utils.SegmentDataEntry, 0, len(entries))
+		for _, entry := range entries {
+			segmentEntry := segmentTOC.DataEntries[uint(entry.Oid)]
+			length := segmentEntry.EndByte - segmentEntry.StartByte
+			consolidatedTOC.AddSegmentDataEntry(uint(entry.Oid), offset, offset+length)
+			offset += length
+			byteRanges[i] = append(byteRanges[i], segmentEntry)
+		}
+	}
+	return consolidatedTOC, byteRanges
+}
+
+func mustCopyFile(sourceFilename string, targetFilename string) {
+	sourceFile, err := os.Open(sourceFilename)
+	gplog.FatalOnError(err)
+	defer sourceFile.Close()
+	targetFile := iohelper.MustOpenFileForWriting(targetFilename)
+	defer targetFile.Close()
+	_, err = io.Copy(targetFile, sourceFile)
+	gplog.FatalOnError(err)
+}
+
+func mustCopyDirectory(sourceDir string, targetDir string) {
+	err := filepath.Walk(sourceDir, func(sourcePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(sourceDir, sourcePath)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(targetDir, relativePath)
+		if info.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+		mustCopyFile(sourcePath, targetPath)
+		return nil
+	})
+	gplog.FatalOnError(err)
+}
@@ -0,0 +1,166 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/consolidate tests", func() {
+	fooEntry := utils.MasterDataEntry{Schema: "public", Name: "foo", Oid: 1}
+	barEntry := utils.MasterDataEntry{Schema: "public", Name: "bar", Oid: 2}
+	bazEntry := utils.MasterDataEntry{Schema: "public", Name: "baz", Oid: 3}
+	restorePlan := []backup_history.RestorePlanEntry{
+		{Timestamp: "20170101010101", TableFQNs: []string{"public.foo", "public.baz"}},
+		{Timestamp: "20170102010101", TableFQNs: []string{}},
+		{Timestamp: "20170103010101", TableFQNs: []string{"public.bar"}},
+	}
+
+	Describe("ValidateConsolidationSource", func() {
+		It("does not panic for a backup with data and metadata and a valid restore plan", func() {
+			config := backup_history.BackupConfig{Timestamp: "20170103010101", RestorePlan: restorePlan}
+			backup.ValidateConsolidationSource(&config)
+		})
+		It("panics for a metadata-only backup", func() {
+			config := backup_history.BackupConfig{Timestamp: "20170103010101", MetadataOnly: true, RestorePlan: restorePlan}
+			defer testhelper.ShouldPanicWithMessage("The backup with timestamp = 20170103010101 does not contain both data and metadata and cannot be consolidated.")
+			backup.ValidateConsolidationSource(&config)
+		})
+		It("panics for a backup taken with a plugin", func() {
+			config := backup_history.BackupConfig{Timestamp: "20170103010101", Plugin: "/tmp/plugin.sh", RestorePlan: restorePlan}
+			defer testhelper.ShouldPanicWithMessage("The backup with timestamp = 20170103010101 was taken with a plugin.  Backups stored using a plugin cannot be consolidated.")
+			backup.ValidateConsolidationSource(&config)
+		})
+		It("panics if the restore plan does not end with the backup itself", func() {
+			config := backup_history.BackupConfig{Timestamp: "20170104010101", RestorePlan: restorePlan}
+			defer testhelper.ShouldPanicWithMessage("The backup with timestamp = 20170104010101 does not have a valid restore plan.")
+			backup.ValidateConsolidationSource(&config)
+		})
+	})
+	Describe("GetConsolidatedDataEntries", func() {
+		It("returns the newest data entry for each table, grouped by restore plan entry in TOC order", func() {
+			tocs := []*utils.TOC{
+				{DataEntries: []utils.MasterDataEntry{fooEntry, barEntry, bazEntry}},
+				{DataEntries: []utils.MasterDataEntry{}},
+				{DataEntries: []utils.MasterDataEntry{barEntry}},
+			}
+
+			dataEntries := backup.GetConsolidatedDataEntries(restorePlan, tocs)
+
+			Expect(dataEntries).To(Equal([][]utils.MasterDataEntry{
+				{fooEntry, bazEntry},
+				{},
+				{barEntry},
+			}))
+		})
+		It("panics if a table in the restore plan is missing from the TOC", func() {
+			tocs := []*utils.TOC{
+				{DataEntries: []utils.MasterDataEntry{fooEntry}},
+				{DataEntries: []utils.MasterDataEntry{}},
+				{DataEntries: []utils.MasterDataEntry{barEntry}},
+			}
+			defer testhelper.ShouldPanicWithMessage("The table of contents for the backup with timestamp = 20170101010101 is missing data entries for 1 tables in its restore plan.")
+			backup.GetConsolidatedDataEntries(restorePlan, tocs)
+		})
+	})
+	Describe("NewConsolidatedBackupConfig", func() {
+		It("creates a full backup config with a single restore plan entry", func() {
+			backup.SetVersion("1.0.0")
+			sourceConfig := backup_history.BackupConfig{
+				BackupType:        backup_history.INCREMENTAL_BACKUP,
+				BackupVersion:     "0.9.0",
+				Compressed:        true,
+				DatabaseName:      "testdb",
+				Incremental:       true,
+				LeafPartitionData: true,
+				RestorePlan:       restorePlan,
+				Timestamp:         "20170103010101",
+			}
+
+			config := backup.NewConsolidatedBackupConfig(&sourceConfig, "20170104010101", []string{"public.foo", "public.bar"})
+
+			Expect(*config).To(Equal(backup_history.BackupConfig{
+				BackupType:        backup_history.FULL_BACKUP,
+				BackupVersion:     "1.0.0",
+				Compressed:        true,
+				DatabaseName:      "testdb",
+				Incremental:       false,
+				LeafPartitionData: true,
+				RestorePlan:       []backup_history.RestorePlanEntry{{Timestamp: "20170104010101", TableFQNs: []string{"public.foo", "public.bar"}}},
+				Timestamp:         "20170104010101",
+			}))
+			Expect(sourceConfig.RestorePlan).To(Equal(restorePlan))
+		})
+	})
+	Describe("ConsolidateSegmentTOCs", func() {
+		It("places the byte ranges of current data one after another and returns the ranges to copy from each file", func() {
+			segmentTOCs := []*utils.SegmentTOC{
+				{DataEntries: map[uint]utils.SegmentDataEntry{1: {StartByte: 0, EndByte: 10}, 2: {StartByte: 10, EndByte: 25}, 3: {StartByte: 25, EndByte: 40}}},
+				{DataEntries: map[uint]utils.SegmentDataEntry{}},
+				{DataEntries: map[uint]utils.SegmentDataEntry{2: {StartByte: 0, EndByte: 30}}},
+			}
+			dataEntries := [][]utils.MasterDataEntry{{bazEntry, fooEntry}, {}, {barEntry}}
+
+			consolidatedTOC, byteRanges := backup.ConsolidateSegmentTOCs(segmentTOCs, dataEntries)
+
+			Expect(byteRanges).To(Equal([][]utils.SegmentDataEntry{
+				{{StartByte: 0, EndByte: 10}, {StartByte: 25, EndByte: 40}},
+				{},
+				{{StartByte: 0, EndByte: 30}},
+			}))
+			Expect(consolidatedTOC.DataEntries).To(Equal(map[uint]utils.SegmentDataEntry{
+				1: {StartByte: 0, EndByte: 10},
+				3: {StartByte: 10, EndByte: 25},
+				2: {StartByte: 25, EndByte: 55},
+			}))
+		})
+	})
+	Describe("consolidation scripts", func() {
+		var chainFPInfos []backup_filepath.FilePathInfo
+		BeforeEach(func() {
+			testCluster := cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "mdw", DataDir: "/data/gpseg-1"}, {ContentID: 0, Hostname: "sdw1", DataDir: "/data/gpseg0"}})
+			chainFPInfos = []backup_filepath.FilePathInfo{
+				backup_filepath.NewFilePathInfo(testCluster, "/backups", "20170101010101", "gpseg"),
+				backup_filepath.NewFilePathInfo(testCluster, "/backups", "20170103010101", "gpseg"),
+			}
+			backup.SetFPInfo(backup_filepath.NewFilePathInfo(testCluster, "/backups", "20170104010101", "gpseg"))
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "gzip -d -c", Extension: ".gz"})
+		})
+		AfterEach(func() {
+			utils.InitializePipeThroughParameters(false, 0)
+		})
+		It("links or copies each table data file on a separate line", func() {
+			script := backup.GetLinkTableDataFilesScript(0, chainFPInfos, [][]utils.MasterDataEntry{{fooEntry}, {barEntry}})
+
+			Expect(script).To(Equal(`set -e
+ln /backups/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_1.gz 2>/dev/null || cp /backups/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_1.gz /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_1.gz
+ln /backups/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_2.gz /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_2.gz 2>/dev/null || cp /backups/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101_2.gz /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_2.gz
+`))
+		})
+		It("copies only the byte ranges of current data from each single data file", func() {
+			byteRanges := [][]utils.SegmentDataEntry{{{StartByte: 0, EndByte: 10}, {StartByte: 25, EndByte: 40}}, {{StartByte: 5, EndByte: 35}}}
+			consolidatedTOC := &utils.SegmentTOC{DataEntries: map[uint]utils.SegmentDataEntry{1: {StartByte: 0, EndByte: 10}}}
+
+			script := backup.GetConsolidatedDataFileScript(0, chainFPInfos, byteRanges, consolidatedTOC)
+
+			Expect(script).To(Equal(`set -e -o pipefail
+{
+gzip -d -c < /backups/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101.gz | { head -c 10; head -c 15 > /dev/null; head -c 15; cat > /dev/null; }
+gzip -d -c < /backups/gpseg0/backups/20170103/20170103010101/gpbackup_0_20170103010101.gz | { head -c 5 > /dev/null; head -c 30; cat > /dev/null; }
+} | gzip -c -1 > /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101.gz
+cat << 'TOC' > /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_toc.yaml
+dataentries:
+  1:
+    startbyte: 0
+    endbyte: 10
+TOC
+chmod 444 /backups/gpseg0/backups/20170104/20170104010101/gpbackup_0_20170104010101_toc.yaml
+`))
+		})
+	})
+})
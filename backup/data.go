@@ -6,13 +6,19 @@ package backup
 
 import (
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"sync"
+	"time"
 
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
@@ -31,7 +37,54 @@ func ConstructTableAttributesList(columnDefs []ColumnDefinition) string {
 	return ""
 }
 
-func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64) {
+/*
+ * Returns a subquery selecting the rows that match the table's predicate, with
+ * any masking rules applied to its columns, or the table itself if it has
+ * neither, to be copied out or fingerprinted.
+ */
+func GetTableDataSource(table Table) string {
+	predicate := GetTablePredicate(table)
+	selectList := GetMaskedSelectList(table)
+	if predicate == "" && selectList == "" {
+		return table.FQN()
+	}
+	if selectList == "" {
+		selectList = "*"
+	}
+	whereClause := ""
+	if predicate != "" {
+		whereClause = fmt.Sprintf(" WHERE (%s)", predicate)
+	}
+	return fmt.Sprintf("(SELECT %s FROM %s%s)", selectList, table.FQN(), whereClause)
+}
+
+/*
+ * IGNORE EXTERNAL PARTITIONS is not supported when copying out the results of
+ * a query, so a table with a predicate or masking rules cannot be backed up if
+ * it has external partitions, as their data would be read along with its own.
+ */
+func ValidateTableDataSources(connectionPool *dbconn.DBConn, tables []Table) {
+	queryTables := make([]Table, 0)
+	for _, table := range tables {
+		if !table.SkipDataBackup() && GetTableDataSource(table) != table.FQN() {
+			queryTables = append(queryTables, table)
+		}
+	}
+	if len(queryTables) == 0 {
+		return
+	}
+	externalPartitionTables := make(map[string]bool, 0)
+	for _, fqn := range GetPartitionTablesWithExternalPartitions(connectionPool) {
+		externalPartitionTables[fqn] = true
+	}
+	for _, table := range queryTables {
+		if externalPartitionTables[table.FQN()] {
+			gplog.Fatal(errors.Errorf("Cannot apply a table predicate or masking rules to table %s, as it has external partitions", table.FQN()), "")
+		}
+	}
+}
+
+func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64, fingerprintMaps []map[uint32][]utils.SegmentFingerprint) {
 	for _, table := range tables {
 		if !table.SkipDataBackup() {
 			var rowsCopied int64
@@ -41,8 +94,26 @@ func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64)
 					break
 				}
 			}
+			var fingerprints []utils.SegmentFingerprint
+			for _, fingerprintMap := range fingerprintMaps {
+				if val, ok := fingerprintMap[table.Oid]; ok {
+					fingerprints = val
+					break
+				}
+			}
 			attributes := ConstructTableAttributesList(table.ColumnDefs)
-			globalTOC.AddMasterDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName)
+			globalTOC.AddMasterDataEntry(utils.MasterDataEntry{
+				Schema:          table.Schema,
+				Name:            table.Name,
+				Oid:             table.Oid,
+				AttributeString: attributes,
+				RowsCopied:      rowsCopied,
+				PartitionRoot:   table.PartitionLevelInfo.RootName,
+				Fingerprints:    fingerprints,
+				SizeEstimate:    table.SizeEstimate,
+				Predicate:       GetTablePredicate(table),
+				MaskedColumns:   GetMaskedColumns(table),
+			})
 		}
 	}
 }
@@ -73,7 +144,15 @@ func CopyTableOut(connectionPool *dbconn.DBConn, table Table, destinationToWrite
 
 	copyCommand := fmt.Sprintf("PROGRAM '%s%s %s %s'", checkPipeExistsCommand, customPipeThroughCommand, sendToDestinationCommand, destinationToWrite)
 
+	/*
+	 * IGNORE EXTERNAL PARTITIONS is not supported when copying out the results
+	 * of a query; ValidateTableDataSources ensures that tables with a predicate
+	 * or masking rules have no external partitions.
+	 */
 	query := fmt.Sprintf("COPY %s TO %s WITH CSV DELIMITER '%s' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", table.FQN(), copyCommand, tableDelim)
+	if dataSource := GetTableDataSource(table); dataSource != table.FQN() {
+		query = fmt.Sprintf("COPY %s TO %s WITH CSV DELIMITER '%s' ON SEGMENT;", dataSource, copyCommand, tableDelim)
+	}
 	result, err := connectionPool.Exec(query, connNum)
 	if err != nil {
 		return 0, err
@@ -103,7 +182,7 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 		} else {
 			destinationToWrite = globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.GetPipeThroughProgram().Extension, false)
 		}
-		rowsCopied, err := CopyTableOut(connectionPool, table, destinationToWrite, whichConn)
+		rowsCopied, err := CopyTableOutWithRetries(table, destinationToWrite, whichConn)
 		if err != nil {
 			return err
 		}
@@ -113,7 +192,117 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 	return nil
 }
 
-func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
+/*
+ * With --copy-retries, each table's COPY is run inside a savepoint so that a
+ * failed COPY can be rolled back and retried without aborting the worker's
+ * transaction, which would lose its snapshot.  Any partial data files written
+ * by the failed attempt are removed before retrying, and the delay between
+ * attempts doubles after each retry.
+ */
+func CopyTableOutWithRetries(table Table, destinationToWrite string, whichConn int) (int64, error) {
+	maxRetries := MustGetFlagInt(utils.COPY_RETRIES)
+	if maxRetries > 0 {
+		_, err := connectionPool.Exec("SAVEPOINT gpbackup_copy", whichConn)
+		if err != nil {
+			return 0, err
+		}
+	}
+	retryDelay := copyRetryDelay
+	for attempt := 0; ; attempt++ {
+		rowsCopied, copyErr := CopyTableOut(connectionPool, table, destinationToWrite, whichConn)
+		if copyErr == nil {
+			if attempt > 0 {
+				recordTableDataRetries(table, attempt, "succeeded")
+			}
+			if maxRetries > 0 {
+				_, err := connectionPool.Exec("RELEASE SAVEPOINT gpbackup_copy", whichConn)
+				if err != nil {
+					return 0, err
+				}
+			}
+			return rowsCopied, nil
+		}
+		if attempt == maxRetries {
+			if attempt > 0 {
+				recordTableDataRetries(table, attempt, "failed")
+			}
+			return 0, GetTableCopyError(table, copyErr, attempt)
+		}
+		gplog.Warn("Error backing up data for table %s on attempt %d of %d, retrying in %s: %s", table.FQN(), attempt+1, maxRetries+1, retryDelay, copyErr.Error())
+		_, err := connectionPool.Exec("ROLLBACK TO SAVEPOINT gpbackup_copy", whichConn)
+		if err != nil {
+			return 0, err
+		}
+		err = RemoveTableDataFiles(table)
+		if err != nil {
+			return 0, err
+		}
+		time.Sleep(retryDelay)
+		retryDelay *= 2
+	}
+}
+
+/*
+ * Errors from COPY ON SEGMENT identify the segment on which the COPY failed
+ * in the form "(seg3 ...)", so the content ID is used to look up the host.
+ */
+func GetTableCopyError(table Table, copyErr error, numRetries int) error {
+	retryStr := ""
+	if numRetries > 0 {
+		retryStr = fmt.Sprintf(" after %d retries", numRetries)
+	}
+	matches := regexp.MustCompile(`\(seg(\d+)[ )]`).FindStringSubmatch(copyErr.Error())
+	if matches == nil {
+		return errors.Errorf("Error backing up data for table %s%s: %s", table.FQN(), retryStr, copyErr.Error())
+	}
+	contentID, _ := strconv.Atoi(matches[1])
+	return errors.Errorf("Error backing up data for table %s on segment %d on host %s%s: %s", table.FQN(), contentID, globalCluster.GetHostForContent(contentID), retryStr, copyErr.Error())
+}
+
+func RemoveTableDataFiles(table Table) error {
+	extension := utils.GetPipeThroughProgram().Extension
+	remoteOutput := globalCluster.GenerateAndExecuteCommand(fmt.Sprintf("Removing partial data files for table %s", table.FQN()), func(contentID int) string {
+		return fmt.Sprintf("rm -f %s", globalFPInfo.GetTableBackupFilePath(contentID, table.Oid, extension, false))
+	}, cluster.ON_SEGMENTS)
+	errMsg := fmt.Sprintf("Unable to remove partial data files for table %s", table.FQN())
+	globalCluster.CheckClusterError(remoteOutput, errMsg, func(contentID int) string {
+		return errMsg
+	}, true)
+	if remoteOutput.NumErrors > 0 {
+		return errors.Errorf("%s on %d segments.  See %s for a complete list of errors.", errMsg, remoteOutput.NumErrors, gplog.GetLogFilePath())
+	}
+	return nil
+}
+
+func recordTableDataRetries(table Table, numRetries int, result string) {
+	if backupReport == nil {
+		return
+	}
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+	backupReport.TableDataRetries = append(backupReport.TableDataRetries, fmt.Sprintf("%s: %d retries, %s", table.FQN(), numRetries, result))
+}
+
+/*
+ * If --with-fingerprints is set, each table's fingerprint is computed on the
+ * same connection immediately after its data is copied out, so that it is
+ * taken in the same transaction as the COPY.  The fingerprint is computed from
+ * the same rows and masked values as are copied out, as only those are restored.
+ */
+func BackupTableFingerprints(table Table, fingerprintMap map[uint32][]utils.SegmentFingerprint, whichConn int) error {
+	if table.SkipDataBackup() {
+		return nil
+	}
+	gplog.Verbose("Computing data fingerprint for table %s", table.FQN())
+	fingerprints, err := utils.GetTableFingerprints(connectionPool, GetTableDataSource(table), globalCluster.ContentIDs, whichConn)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error computing data fingerprint for table %s", table.FQN()))
+	}
+	fingerprintMap[table.Oid] = fingerprints
+	return nil
+}
+
+func BackupDataForAllTables(tables []Table) ([]map[uint32]int64, []map[uint32][]utils.SegmentFingerprint) {
 	var numExtOrForeignTables int64
 	for _, table := range tables {
 		if table.SkipDataBackup() {
@@ -124,6 +313,8 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 	counters.ProgressBar = utils.NewProgressBar(int(counters.TotalRegTables), "Tables backed up: ", utils.PB_INFO)
 	counters.ProgressBar.Start()
 	rowsCopiedMaps := make([]map[uint32]int64, connectionPool.NumConns)
+	fingerprintMaps := make([]map[uint32][]utils.SegmentFingerprint, connectionPool.NumConns)
+	withFingerprints := MustGetFlagBool(utils.WITH_FINGERPRINTS)
 	/*
 	 * We break when an interrupt is received and rely on
 	 * TerminateHangingCopySessions to kill any COPY statements
@@ -134,6 +325,7 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 	var copyErr error
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		rowsCopiedMaps[connNum] = make(map[uint32]int64, 0)
+		fingerprintMaps[connNum] = make(map[uint32][]utils.SegmentFingerprint, 0)
 		workerPool.Add(1)
 		go func(whichConn int) {
 			defer workerPool.Done()
@@ -143,13 +335,19 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 					return
 				}
 				err := BackupSingleTableData(table, rowsCopiedMaps[whichConn], &counters, whichConn)
+				if err == nil && withFingerprints {
+					err = BackupTableFingerprints(table, fingerprintMaps[whichConn], whichConn)
+				}
+				if err == nil {
+					err = RecordCompletedTable(table, rowsCopiedMaps[whichConn][table.Oid], fingerprintMaps[whichConn][table.Oid])
+				}
 				if err != nil {
 					copyErr = err
 				}
 			}
 		}(connNum)
 	}
-	for _, table := range tables {
+	for _, table := range ScheduleTablesForBackup(tables, connectionPool.NumConns) {
 		tasks <- table
 	}
 	close(tasks)
@@ -172,7 +370,24 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 	counters.ProgressBar.Finish()
 
 	printDataBackupWarnings(numExtOrForeignTables)
-	return rowsCopiedMaps
+	return rowsCopiedMaps, fingerprintMaps
+}
+
+/*
+ * With multiple connections, handing out the largest tables first keeps one
+ * large table that would otherwise be picked up last from running on its own
+ * long after the other connections have finished.
+ */
+func ScheduleTablesForBackup(tables []Table, numConns int) []Table {
+	if numConns == 1 || MustGetFlagBool(utils.PRESERVE_TABLE_ORDER) {
+		return tables
+	}
+	scheduledTables := make([]Table, len(tables))
+	copy(scheduledTables, tables)
+	sort.SliceStable(scheduledTables, func(i int, j int) bool {
+		return scheduledTables[i].SizeEstimate > scheduledTables[j].SizeEstimate
+	})
+	return scheduledTables
 }
 
 func printDataBackupWarnings(numExtTables int64) {
@@ -1,8 +1,11 @@
 package backup_test
 
 import (
+	"errors"
 	"regexp"
 
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
@@ -33,16 +36,86 @@ var _ bool = Describe("backup/data tests", func() {
 			Expect(atts).To(Equal(""))
 		})
 	})
+	Describe("ScheduleTablesForBackup", func() {
+		small := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "small"}, TableDefinition: backup.TableDefinition{SizeEstimate: 8192}}
+		large := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "large"}, TableDefinition: backup.TableDefinition{SizeEstimate: 81920}}
+		empty := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "empty"}}
+		unanalyzed := backup.Table{Relation: backup.Relation{Oid: 4, Schema: "public", Name: "unanalyzed"}}
+		tables := []backup.Table{small, empty, large, unanalyzed}
+		It("schedules the largest tables first, keeping catalog order for tables of equal size", func() {
+			Expect(backup.ScheduleTablesForBackup(tables, 2)).To(Equal([]backup.Table{large, small, empty, unanalyzed}))
+			Expect(tables).To(Equal([]backup.Table{small, empty, large, unanalyzed}))
+		})
+		It("keeps catalog order with a single connection", func() {
+			Expect(backup.ScheduleTablesForBackup(tables, 1)).To(Equal(tables))
+		})
+		It("keeps catalog order when --preserve-table-order is set", func() {
+			_ = cmdFlags.Set(utils.PRESERVE_TABLE_ORDER, "true")
+			Expect(backup.ScheduleTablesForBackup(tables, 2)).To(Equal(tables))
+		})
+	})
+	Describe("GetTableDataSource", func() {
+		columnDefs := []backup.ColumnDefinition{{Name: "i", Type: "integer"}, {Name: "email", Type: "text"}}
+		table := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "events"}, TableDefinition: backup.TableDefinition{ColumnDefs: columnDefs}}
+		AfterEach(func() {
+			backup.SetTablePredicates(nil)
+			backup.SetMaskingRules(nil)
+		})
+		It("returns the table name for a table without a predicate or masking rules", func() {
+			Expect(backup.GetTableDataSource(table)).To(Equal("public.events"))
+		})
+		It("returns a query selecting the rows matching the table's predicate", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5 OR i < 3"})
+
+			Expect(backup.GetTableDataSource(table)).To(Equal("(SELECT * FROM public.events WHERE (i > 5 OR i < 3))"))
+		})
+		It("returns a query selecting the masked columns of the rows matching the table's predicate", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5"})
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.events": {"email": {Transform: backup.MASK_HASH}}})
+			backup.SetMaskingSalt("salt")
+			defer backup.SetMaskingSalt("")
+
+			Expect(backup.GetTableDataSource(table)).To(Equal("(SELECT i, CAST(md5('salt' || email::text) AS text) AS email FROM public.events WHERE (i > 5))"))
+		})
+	})
+	Describe("ValidateTableDataSources", func() {
+		table := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "events"}, TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: "i", Type: "integer"}}}}
+		AfterEach(func() {
+			backup.SetTablePredicates(nil)
+		})
+		It("does not query for external partitions if no table has a predicate or masking rules", func() {
+			backup.ValidateTableDataSources(connectionPool, []backup.Table{table})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("accepts a predicate on a table without external partitions", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5"})
+			mock.ExpectQuery("SELECT DISTINCT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("public.other"))
+
+			backup.ValidateTableDataSources(connectionPool, []backup.Table{table})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("panics if a table with a predicate has external partitions", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5"})
+			mock.ExpectQuery("SELECT DISTINCT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("public.events"))
+
+			defer testhelper.ShouldPanicWithMessage("Cannot apply a table predicate or masking rules to table public.events, as it has external partitions")
+			backup.ValidateTableDataSources(connectionPool, []backup.Table{table})
+		})
+	})
 	Describe("AddTableDataEntriesToTOC", func() {
 		var (
-			toc            *utils.TOC
-			rowsCopiedMaps []map[uint32]int64
-			table          backup.Table
+			toc             *utils.TOC
+			rowsCopiedMaps  []map[uint32]int64
+			fingerprintMaps []map[uint32][]utils.SegmentFingerprint
+			table           backup.Table
 		)
 		BeforeEach(func() {
 			toc = &utils.TOC{}
 			backup.SetTOC(toc)
 			rowsCopiedMaps = make([]map[uint32]int64, connectionPool.NumConns)
+			fingerprintMaps = make([]map[uint32][]utils.SegmentFingerprint, connectionPool.NumConns)
 			columnDefs := []backup.ColumnDefinition{{Oid: 1, Name: "a"}}
 			table = backup.Table{
 				Relation:        backup.Relation{Oid: 1, Schema: "public", Name: "table"},
@@ -51,21 +124,52 @@ var _ bool = Describe("backup/data tests", func() {
 		})
 		It("adds an entry for a regular table to the TOC", func() {
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
 			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)"}}
 			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
 		})
+		It("adds the fingerprints for a table to its entry in the TOC", func() {
+			fingerprints := []utils.SegmentFingerprint{{ContentID: 0, NumRows: 2, Checksum: "12345"}, {ContentID: 1, NumRows: 0, Checksum: "0"}}
+			fingerprintMaps[0] = map[uint32][]utils.SegmentFingerprint{1: fingerprints}
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
+			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", Fingerprints: fingerprints}}
+			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
+		})
+		It("adds the size estimate for a table to its entry in the TOC", func() {
+			table.SizeEstimate = 32768
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
+			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", SizeEstimate: 32768}}
+			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
+		})
+		It("adds the predicate for a table to its entry in the TOC", func() {
+			backup.SetTablePredicates(map[string]string{"public.table": "a > 5"})
+			defer backup.SetTablePredicates(nil)
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
+			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", Predicate: "a > 5"}}
+			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
+		})
+		It("adds the masked columns of a table to its entry in the TOC", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.table": {"a": {Transform: backup.MASK_CONSTANT, Argument: "x"}}})
+			defer backup.SetMaskingRules(nil)
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
+			expectedDataEntries := []utils.MasterDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", MaskedColumns: []string{"a: constant:x"}}}
+			Expect(toc.DataEntries).To(Equal(expectedDataEntries))
+		})
 		It("does not add an entry for an external table to the TOC", func() {
 			table.IsExternal = true
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
 			Expect(toc.DataEntries).To(BeNil())
 		})
 		It("does not add an entry for a foreign table to the TOC", func() {
 			foreignDef := backup.ForeignTableDefinition{Oid: 23, Options: "", Server: "fs"}
 			table.ForeignDef = foreignDef
 			tables := []backup.Table{table}
-			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps, fingerprintMaps)
 			Expect(toc.DataEntries).To(BeNil())
 		})
 	})
@@ -125,6 +229,18 @@ var _ bool = Describe("backup/data tests", func() {
 
 			_, err := backup.CopyTableOut(connectionPool, testTable, filename, defaultConnNum)
 
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will back up only the rows matching a table's predicate", func() {
+			backup.SetTablePredicates(map[string]string{"public.foo": "created_at > '2018-01-01'"})
+			defer backup.SetTablePredicates(nil)
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+			execStr := regexp.QuoteMeta("COPY (SELECT * FROM public.foo WHERE (created_at > '2018-01-01')) TO PROGRAM 'cat - > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT;")
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+
+			_, err := backup.CopyTableOut(connectionPool, testTable, filename, defaultConnNum)
+
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
@@ -190,6 +306,55 @@ var _ bool = Describe("backup/data tests", func() {
 			Expect(counters.NumRegTables).To(Equal(int64(0)))
 		})
 	})
+	Describe("CopyTableOutWithRetries", func() {
+		testTable := backup.Table{Relation: backup.Relation{Oid: 3456, Schema: "public", Name: "foo"}}
+		filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+		BeforeEach(func() {
+			backup.SetCluster(cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "mdw"}, {ContentID: 0, Hostname: "sdw1"}, {ContentID: 1, Hostname: "sdw2"}}))
+		})
+		It("copies the table without a savepoint when --copy-retries is not set", func() {
+			mock.ExpectExec("COPY public.foo TO (.*)").WillReturnResult(sqlmock.NewResult(0, 10))
+
+			rowsCopied, err := backup.CopyTableOutWithRetries(testTable, filename, defaultConnNum)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rowsCopied).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("copies the table inside a savepoint when --copy-retries is set", func() {
+			_ = cmdFlags.Set(utils.COPY_RETRIES, "2")
+			mock.ExpectExec("SAVEPOINT gpbackup_copy").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("COPY public.foo TO (.*)").WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectExec("RELEASE SAVEPOINT gpbackup_copy").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			rowsCopied, err := backup.CopyTableOutWithRetries(testTable, filename, defaultConnNum)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rowsCopied).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("names the segment and host on which the COPY failed", func() {
+			mock.ExpectExec("COPY public.foo TO (.*)").WillReturnError(errors.New("pq: command error message: No space left on device (seg1 10.0.0.3:40001 pid=1234)"))
+
+			_, err := backup.CopyTableOutWithRetries(testTable, filename, defaultConnNum)
+
+			Expect(err).To(MatchError("Error backing up data for table public.foo on segment 1 on host sdw2: pq: command error message: No space left on device (seg1 10.0.0.3:40001 pid=1234)"))
+		})
+	})
+	Describe("GetTableCopyError", func() {
+		testTable := backup.Table{Relation: backup.Relation{Oid: 3456, Schema: "public", Name: "foo"}}
+		BeforeEach(func() {
+			backup.SetCluster(cluster.NewCluster([]cluster.SegConfig{{ContentID: -1, Hostname: "mdw"}, {ContentID: 0, Hostname: "sdw1"}}))
+		})
+		It("includes the number of retries", func() {
+			err := backup.GetTableCopyError(testTable, errors.New("pq: command error message: cat: Input/output error (seg0 slice1 10.0.0.2:40000 pid=1234)"), 3)
+			Expect(err).To(MatchError("Error backing up data for table public.foo on segment 0 on host sdw1 after 3 retries: pq: command error message: cat: Input/output error (seg0 slice1 10.0.0.2:40000 pid=1234)"))
+		})
+		It("omits the segment if the error does not identify one", func() {
+			err := backup.GetTableCopyError(testTable, errors.New("pq: relation does not exist"), 0)
+			Expect(err).To(MatchError("Error backing up data for table public.foo: pq: relation does not exist"))
+		})
+	})
 	Describe("CheckDBContainsData", func() {
 		config := backup_history.BackupConfig{}
 		var testTable backup.Table
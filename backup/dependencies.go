@@ -2,6 +2,7 @@ package backup
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -251,6 +252,39 @@ func breakCircularDependencies(depMap DependencyMap) {
 	}
 }
 
+/*
+ * Store the dependencies between sorted objects in the TOC, so that gprestore
+ * can create objects that do not depend on one another in parallel.  The
+ * dependencies are also returned by the UniqueID of each object, for callers
+ * that need them for a specific object rather than for a TOC entry name.
+ */
+func AddDependenciesToTOC(toc *utils.TOC, objects []Sortable, dependencies DependencyMap) map[UniqueID][]string {
+	keyForUniqueID := make(map[UniqueID]string, len(objects))
+	for _, object := range objects {
+		if tocObject, ok := object.(utils.TOCObject); ok {
+			_, entry := tocObject.GetMetadataEntry()
+			keyForUniqueID[object.GetUniqueID()] = utils.GetDependencyKey(entry.ObjectType, entry.Schema, entry.Name)
+		}
+	}
+	dependencyKeys := make(map[UniqueID][]string, len(keyForUniqueID))
+	for _, object := range objects {
+		key, ok := keyForUniqueID[object.GetUniqueID()]
+		if !ok {
+			continue
+		}
+		objectDeps := make([]string, 0)
+		for dep := range dependencies[object.GetUniqueID()] {
+			if depKey, ok := keyForUniqueID[dep]; ok && depKey != key {
+				objectDeps = append(objectDeps, depKey)
+			}
+		}
+		sort.Strings(objectDeps)
+		toc.AddPredataDependencies(key, objectDeps)
+		dependencyKeys[object.GetUniqueID()] = objectDeps
+	}
+	return dependencyKeys
+}
+
 func PrintDependentObjectStatements(metadataFile *utils.FileWithByteCount, toc *utils.TOC, objects []Sortable, metadataMap MetadataMap, constraints []Constraint, funcInfoMap map[uint32]FunctionInfo) {
 	conMap := make(map[string][]Constraint)
 	for _, constraint := range constraints {
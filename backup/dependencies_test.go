@@ -118,6 +118,54 @@ var _ = Describe("backup/dependencies tests", func() {
 			Expect(result).To(Equal(expected))
 		})
 	})
+	Describe("AddDependenciesToTOC", func() {
+		function := backup.Function{Oid: 1, Schema: "public", Name: "func", IdentArgs: "integer"}
+		view1 := backup.View{Oid: 2, Schema: "public", Name: "view1"}
+		view2 := backup.View{Oid: 3, Schema: "public", Name: "view2"}
+		It("adds an empty dependency list for objects with no dependencies", func() {
+			backup.AddDependenciesToTOC(toc, []backup.Sortable{function, view1}, depMap)
+
+			Expect(toc.PredataDependencies).To(Equal(map[string][]string{
+				"FUNCTION public.func(integer)": {},
+				"VIEW public.view1":             {},
+			}))
+		})
+		It("adds sorted dependencies on other objects to the TOC", func() {
+			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 3}] = map[backup.UniqueID]bool{
+				{ClassID: backup.PG_PROC_OID, Oid: 1}:  true,
+				{ClassID: backup.PG_CLASS_OID, Oid: 2}: true,
+			}
+
+			backup.AddDependenciesToTOC(toc, []backup.Sortable{function, view1, view2}, depMap)
+
+			Expect(toc.PredataDependencies["VIEW public.view2"]).To(Equal([]string{"FUNCTION public.func(integer)", "VIEW public.view1"}))
+		})
+		It("does not add dependencies on objects that are not in the sorted set", func() {
+			depMap[backup.UniqueID{ClassID: backup.PG_CLASS_OID, Oid: 2}] = map[backup.UniqueID]bool{{ClassID: backup.PG_CLASS_OID, Oid: 4}: true}
+
+			backup.AddDependenciesToTOC(toc, []backup.Sortable{view1}, depMap)
+
+			Expect(toc.PredataDependencies["VIEW public.view1"]).To(Equal([]string{}))
+		})
+		It("keeps the dependencies of overloaded operators separate", func() {
+			operator1 := backup.Operator{Oid: 4, Schema: "public", Name: "##", LeftArgType: "integer", RightArgType: "integer"}
+			operator2 := backup.Operator{Oid: 5, Schema: "public", Name: "##", LeftArgType: "text", RightArgType: "text"}
+			depMap[backup.UniqueID{ClassID: backup.PG_OPERATOR_OID, Oid: 5}] = map[backup.UniqueID]bool{{ClassID: backup.PG_PROC_OID, Oid: 1}: true}
+
+			dependencyKeys := backup.AddDependenciesToTOC(toc, []backup.Sortable{function, operator1, operator2}, depMap)
+
+			Expect(toc.PredataDependencies).To(Equal(map[string][]string{
+				"FUNCTION public.func(integer)":         {},
+				"OPERATOR public.## (integer, integer)": {},
+				"OPERATOR public.## (text, text)":       {"FUNCTION public.func(integer)"},
+			}))
+			Expect(dependencyKeys).To(Equal(map[backup.UniqueID][]string{
+				function.GetUniqueID():  {},
+				operator1.GetUniqueID(): {},
+				operator2.GetUniqueID(): {"FUNCTION public.func(integer)"},
+			}))
+		})
+	})
 	Describe("PrintDependentObjectStatements", func() {
 		var (
 			objects     []backup.Sortable
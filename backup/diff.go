@@ -0,0 +1,126 @@
+package backup
+
+/*
+ * This file contains functions related to comparing the metadata of a backup
+ * against another backup or the live database, for --diff-timestamp.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+var metadataSections = []string{"global", "predata", "postdata"}
+
+/*
+ * Without --diff-to-timestamp, the right side of the diff is the metadata
+ * that a backup of the live database would contain, generated by the same
+ * code as a real backup but written to memory instead of a metadata file.
+ */
+func DoDiff() {
+	leftTimestamp := MustGetFlagString(utils.DIFF_TIMESTAMP)
+	leftLabel := fmt.Sprintf("backup %s", leftTimestamp)
+	leftTOC, leftMetadataFile, leftBackupDir := ReadBackupMetadata(leftTimestamp)
+
+	var rightTOC *utils.TOC
+	var rightMetadataFile io.ReaderAt
+	rightBackupDir := ""
+	rightLabel := ""
+	if rightTimestamp := MustGetFlagString(utils.DIFF_TO_TIMESTAMP); rightTimestamp != "" {
+		gplog.Info("Comparing the metadata of backups with timestamps %s and %s", leftTimestamp, rightTimestamp)
+		rightLabel = fmt.Sprintf("backup %s", rightTimestamp)
+		rightTOC, rightMetadataFile, rightBackupDir = ReadBackupMetadata(rightTimestamp)
+	} else {
+		gplog.Info("Comparing the metadata of backup with timestamp %s against database %s", leftTimestamp, connectionPool.DBName)
+		rightLabel = fmt.Sprintf("database %s", connectionPool.DBName)
+		rightMetadataFile = CaptureLiveMetadata()
+		rightTOC = globalTOC
+	}
+
+	diffs := make([]utils.ObjectDiff, 0)
+	for _, section := range metadataSections {
+		leftStatements := GetDiffMetadataStatements(leftTOC, section, leftMetadataFile, leftBackupDir)
+		rightStatements := GetDiffMetadataStatements(rightTOC, section, rightMetadataFile, rightBackupDir)
+		diffs = append(diffs, utils.DiffMetadataStatements(section, leftStatements, rightStatements, leftLabel, rightLabel)...)
+	}
+	PrintObjectDiffs(os.Stdout, diffs)
+}
+
+/*
+ * Backups written with --metadata-format directory have no metadata file, so
+ * the file is only opened if it exists and the statements are otherwise read
+ * from the per-object files referenced by the table of contents.
+ */
+func ReadBackupMetadata(timestamp string) (*utils.TOC, io.ReaderAt, string) {
+	fpInfo := backup_filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
+		timestamp, globalFPInfo.UserSpecifiedSegPrefix)
+	toc := utils.NewTOC(fpInfo.GetTOCFilePath())
+	toc.InitializeMetadataEntryMap()
+	metadataFilename := fpInfo.GetMetadataFilePath()
+	var metadataFile io.ReaderAt
+	if iohelper.FileExistsAndIsReadable(metadataFilename) {
+		metadataFile = iohelper.MustOpenFileForReading(metadataFilename)
+	}
+	return toc, metadataFile, path.Dir(metadataFilename)
+}
+
+func CaptureLiveMetadata() io.ReaderAt {
+	objectCounts = make(map[string]int, 0)
+	metadataTables, _ := RetrieveAndProcessTables()
+	metadataBuffer := &bytes.Buffer{}
+	metadataFile := utils.NewFileWithByteCount(metadataBuffer)
+
+	BackupSessionGUCs(metadataFile)
+	tableOnlyBackup := true
+	if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION)) == 0 {
+		tableOnlyBackup = false
+		backupGlobal(metadataFile)
+	}
+	backupPredata(metadataFile, metadataTables, tableOnlyBackup)
+	backupPostdata(metadataFile)
+	return bytes.NewReader(metadataBuffer.Bytes())
+}
+
+/*
+ * The filter flags are applied to both sides of the diff, so that comparing a
+ * filtered live database against an unfiltered backup does not report every
+ * object outside the filter as removed.
+ */
+func GetDiffMetadataStatements(toc *utils.TOC, section string, metadataFile io.ReaderAt, backupDir string) []utils.StatementWithType {
+	return toc.GetSQLStatementForObjectTypes(section, metadataFile, backupDir, []string{}, []string{},
+		MustGetFlagStringSlice(utils.INCLUDE_SCHEMA), MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA),
+		MustGetFlagStringSlice(utils.INCLUDE_RELATION), MustGetFlagStringSlice(utils.EXCLUDE_RELATION))
+}
+
+/*
+ * The differences are written to output, which is stdout, so that they can be
+ * redirected or piped without the prefixes of log messages, while the summary
+ * is logged along with the rest of the run's status messages.
+ */
+func PrintObjectDiffs(output io.Writer, diffs []utils.ObjectDiff) {
+	if len(diffs) == 0 {
+		gplog.Info("No metadata differences found")
+		return
+	}
+	numChanges := make(map[string]int, 0)
+	for _, diff := range diffs {
+		numChanges[diff.Change]++
+	}
+	gplog.Info("Metadata differences: %d added, %d removed, %d changed", numChanges[utils.DIFF_ADDED],
+		numChanges[utils.DIFF_REMOVED], numChanges[utils.DIFF_CHANGED])
+	for _, diff := range diffs {
+		fmt.Fprintf(output, "  %s\n", diff)
+	}
+	for _, diff := range diffs {
+		fmt.Fprintf(output, "%s (%s section):\n%s\n", diff, diff.Section, strings.TrimSuffix(diff.Diff, "\n"))
+	}
+}
@@ -0,0 +1,44 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("backup/diff tests", func() {
+	Describe("PrintObjectDiffs", func() {
+		It("prints a summary of the changes followed by the diff of each object", func() {
+			diffs := []utils.ObjectDiff{
+				{Section: "global", ObjectType: "ROLE", Name: "testrole", Change: utils.DIFF_REMOVED, Diff: "--- backup 1\n+++ database testdb\n@@ -1 +0,0 @@\n-CREATE ROLE testrole;\n"},
+				{Section: "predata", ObjectType: "TABLE", Schema: "public", Name: "foo", Change: utils.DIFF_CHANGED, Diff: "--- backup 1\n+++ database testdb\n@@ -1 +1 @@\n-CREATE TABLE public.foo (i int);\n+CREATE TABLE public.foo (i bigint);\n"},
+			}
+
+			backup.PrintObjectDiffs(buffer, diffs)
+
+			Expect(stdout).To(gbytes.Say(`Metadata differences: 0 added, 1 removed, 1 changed`))
+			Expect(string(buffer.Contents())).To(Equal(`  Removed ROLE testrole
+  Changed TABLE public.foo
+Removed ROLE testrole (global section):
+--- backup 1
++++ database testdb
+@@ -1 +0,0 @@
+-CREATE ROLE testrole;
+Changed TABLE public.foo (predata section):
+--- backup 1
++++ database testdb
+@@ -1 +1 @@
+-CREATE TABLE public.foo (i int);
++CREATE TABLE public.foo (i bigint);
+`))
+		})
+		It("prints a message when there are no differences", func() {
+			backup.PrintObjectDiffs(buffer, []utils.ObjectDiff{})
+
+			Expect(stdout).To(gbytes.Say(`No metadata differences found`))
+			Expect(buffer.Contents()).To(BeEmpty())
+		})
+	})
+})
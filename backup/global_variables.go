@@ -1,7 +1,9 @@
 package backup
 
 import (
+	"io"
 	"sync"
+	"time"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -21,16 +23,28 @@ import (
  * Non-flag variables
  */
 var (
-	backupReport   *utils.Report
-	connectionPool *dbconn.DBConn
-	globalCluster  *cluster.Cluster
-	globalFPInfo   backup_filepath.FilePathInfo
-	globalTOC      *utils.TOC
-	objectCounts   map[string]int
-	pluginConfig   *utils.PluginConfig
-	version        string
-	wasTerminated  bool
-	backupLockFile lockfile.Lockfile
+	backupReport    *utils.Report
+	catalogModel    *CatalogModel
+	connectionPool  *dbconn.DBConn
+	dataConsistency string
+	globalCluster   *cluster.Cluster
+	globalFPInfo    backup_filepath.FilePathInfo
+	globalTOC       *utils.TOC
+	lockConnection  *dbconn.DBConn
+	maskingRules    map[string]map[string]MaskingRule
+	maskingSalt     string
+	objectCounts    map[string]int
+	pluginConfig    *utils.PluginConfig
+	tablePredicates map[string]string
+	version         string
+	wasTerminated   bool
+	backupLockFile  lockfile.Lockfile
+
+	completedTablesFile  io.WriteCloser
+	completedTablesMutex sync.Mutex
+
+	copyRetryDelay = time.Second
+	reportMutex    sync.Mutex
 
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
@@ -61,10 +75,22 @@ func SetCluster(cluster *cluster.Cluster) {
 	globalCluster = cluster
 }
 
+func SetDataConsistency(consistency string) {
+	dataConsistency = consistency
+}
+
 func SetFPInfo(fpInfo backup_filepath.FilePathInfo) {
 	globalFPInfo = fpInfo
 }
 
+func SetMaskingRules(rules map[string]map[string]MaskingRule) {
+	maskingRules = rules
+}
+
+func SetMaskingSalt(salt string) {
+	maskingSalt = salt
+}
+
 func SetPluginConfig(config *utils.PluginConfig) {
 	pluginConfig = config
 }
@@ -77,10 +103,22 @@ func GetReport() *utils.Report {
 	return backupReport
 }
 
+func SetTablePredicates(predicates map[string]string) {
+	tablePredicates = predicates
+}
+
 func SetTOC(toc *utils.TOC) {
 	globalTOC = toc
 }
 
+func SetCompletedTablesFile(file io.WriteCloser) {
+	completedTablesFile = file
+}
+
+func SetCopyRetryDelay(delay time.Duration) {
+	copyRetryDelay = delay
+}
+
 func SetVersion(v string) {
 	version = v
 }
@@ -102,3 +140,7 @@ func MustGetFlagBool(flagName string) bool {
 func MustGetFlagStringSlice(flagName string) []string {
 	return utils.MustGetFlagStringSlice(cmdFlags, flagName)
 }
+
+func MustGetFlagStringArray(flagName string) []string {
+	return utils.MustGetFlagStringArray(cmdFlags, flagName)
+}
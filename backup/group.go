@@ -0,0 +1,64 @@
+package backup
+
+/*
+ * This file contains functions related to backing up several databases in one
+ * run as a backup group, for --all-databases or --include-database.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+func IsBackupGroup() bool {
+	return MustGetFlagBool(utils.ALL_DATABASES) || len(MustGetFlagStringArray(utils.INCLUDE_DATABASE)) > 0
+}
+
+/*
+ * Each database is backed up by its own run of gpbackup with its own
+ * timestamp, as the backup files of a database are identified by timestamp
+ * alone; each run starts in a later second than the previous run finished, so
+ * that no two members can be given the same timestamp.  All of the backups
+ * record the same backup group, which is the timestamp at which the group
+ * started, so that gprestore can find them.  Global objects are only backed
+ * up with the first database, since they are shared by every database in the
+ * cluster.
+ */
+func DoBackupGroup() {
+	SetLoggerVerbosity()
+	databases := MustGetFlagStringArray(utils.INCLUDE_DATABASE)
+	if MustGetFlagBool(utils.ALL_DATABASES) {
+		databaseConn := dbconn.NewDBConnFromEnvironment("postgres")
+		databaseConn.MustConnect(1)
+		databases = GetDatabaseNames(databaseConn)
+		databaseConn.Close()
+	}
+	groupID := utils.CurrentTimestamp()
+	gplog.Info("Backing up databases %s as backup group %s", strings.Join(databases, ", "), groupID)
+	for i, database := range databases {
+		if wasTerminated {
+			return
+		}
+		gplog.Info("Backing up database %s (%d of %d) in backup group %s", database, i+1, len(databases), groupID)
+		err := utils.RunUtilityWithArgs(GetBackupGroupMemberArgs(database, groupID, i == 0))
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Backup of database %s failed: %v.  Backup group %s is incomplete.", database, err, groupID), "")
+		}
+		utils.WaitForNextTimestamp(utils.CurrentTimestamp())
+	}
+	gplog.Info("Backup group %s complete", groupID)
+}
+
+func GetBackupGroupMemberArgs(database string, groupID string, withGlobals bool) []string {
+	args := utils.GetChangedFlagArgs(cmdFlags, utils.ALL_DATABASES, utils.INCLUDE_DATABASE)
+	args = append(args, fmt.Sprintf("--%s=%s", utils.DBNAME, database), fmt.Sprintf("--%s=%s", utils.BACKUP_GROUP, groupID))
+	if !withGlobals && !MustGetFlagBool(utils.WITHOUT_GLOBALS) {
+		args = append(args, fmt.Sprintf("--%s", utils.WITHOUT_GLOBALS))
+	}
+	return args
+}
@@ -0,0 +1,41 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/group tests", func() {
+	Describe("IsBackupGroup", func() {
+		It("backs up a single database on its own", func() {
+			_ = cmdFlags.Set(utils.DBNAME, "testdb")
+			Expect(backup.IsBackupGroup()).To(BeFalse())
+		})
+		It("backs up the included databases as a group", func() {
+			_ = cmdFlags.Set(utils.INCLUDE_DATABASE, "testdb1")
+			_ = cmdFlags.Set(utils.INCLUDE_DATABASE, "testdb2")
+			Expect(backup.IsBackupGroup()).To(BeTrue())
+		})
+		It("backs up all databases as a group", func() {
+			_ = cmdFlags.Set(utils.ALL_DATABASES, "true")
+			Expect(backup.IsBackupGroup()).To(BeTrue())
+		})
+	})
+	Describe("GetBackupGroupMemberArgs", func() {
+		BeforeEach(func() {
+			_ = cmdFlags.Set(utils.INCLUDE_DATABASE, "testdb1")
+			_ = cmdFlags.Set(utils.INCLUDE_DATABASE, "test,db2")
+			_ = cmdFlags.Set(utils.JOBS, "4")
+		})
+		It("passes the other flags on with the member's database and the group", func() {
+			Expect(backup.GetBackupGroupMemberArgs("testdb1", "20170101010101", true)).To(Equal([]string{
+				"--jobs=4", "--dbname=testdb1", "--backup-group=20170101010101"}))
+		})
+		It("skips global objects for every member but the first", func() {
+			Expect(backup.GetBackupGroupMemberArgs("test,db2", "20170101010101", false)).To(Equal([]string{
+				"--jobs=4", "--dbname=test,db2", "--backup-group=20170101010101", "--without-globals"}))
+		})
+	})
+})
@@ -12,13 +12,30 @@ func FilterTablesForIncremental(lastBackupTOC, currentTOC *utils.TOC, tables []T
 	var filteredTables []Table
 	for _, table := range tables {
 		currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[table.FQN()]
-		if !isAOTable {
-			filteredTables = append(filteredTables, table)
+		if isAOTable {
+			/*
+			 * Modcounts are summed over all of a table's aoseg rows, while older
+			 * versions of gpbackup recorded the value from a single row, so AO
+			 * tables with more than one segfile are copied again when the last
+			 * backup was taken by an older version.
+			 */
+			previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[table.FQN()]
+			if previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp {
+				filteredTables = append(filteredTables, table)
+			}
 			continue
 		}
-		previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[table.FQN()]
 
-		if previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp {
+		/*
+		 * Heap tables are only skipped if their contents were checksummed in both
+		 * backups, which requires --track-heap-changes to have been passed to both,
+		 * and the checksums were computed the same way.
+		 */
+		currentHeapEntry, hasCurrentHeapEntry := currentTOC.IncrementalMetadata.Heap[table.FQN()]
+		previousHeapEntry, hasPreviousHeapEntry := lastBackupTOC.IncrementalMetadata.Heap[table.FQN()]
+		if !hasCurrentHeapEntry || !hasPreviousHeapEntry || lastBackupTOC.FingerprintVersion != currentTOC.FingerprintVersion ||
+			previousHeapEntry.LastDDLTimestamp != currentHeapEntry.LastDDLTimestamp ||
+			len(utils.CompareTableFingerprints(table.Schema, table.Name, previousHeapEntry.Fingerprints, currentHeapEntry.Fingerprints)) > 0 {
 			filteredTables = append(filteredTables, table)
 		}
 	}
@@ -41,15 +58,24 @@ func GetLatestMatchingBackupTimestamp() string {
 	var history *backup_history.History
 	var latestMatchingBackupHistoryEntry *backup_history.BackupConfig
 	var err error
+	isDifferential := MustGetFlagBool(utils.DIFFERENTIAL)
 	if iohelper.FileExistsAndIsReadable(globalFPInfo.GetBackupHistoryFilePath()) {
 		history, err = backup_history.NewHistory(globalFPInfo.GetBackupHistoryFilePath())
 		gplog.FatalOnError(err)
-		latestMatchingBackupHistoryEntry = GetLatestMatchingBackupConfig(history, &backupReport.BackupConfig)
+		if isDifferential {
+			latestMatchingBackupHistoryEntry = GetLatestMatchingFullBackupConfig(history, &backupReport.BackupConfig)
+		} else {
+			latestMatchingBackupHistoryEntry = GetLatestMatchingBackupConfig(history, &backupReport.BackupConfig)
+		}
 	}
 
 	if latestMatchingBackupHistoryEntry == nil {
-		gplog.FatalOnError(errors.Errorf("There was no matching previous backup found with the flags provided. " +
-			"Please take a full backup."))
+		backupDescription := "previous backup"
+		if isDifferential {
+			backupDescription = "previous full backup"
+		}
+		gplog.FatalOnError(errors.Errorf("There was no matching %s found with the flags provided. "+
+			"Please take a full backup.", backupDescription))
 	}
 
 	return latestMatchingBackupHistoryEntry.Timestamp
@@ -65,17 +91,39 @@ func GetLatestMatchingBackupConfig(history *backup_history.History, currentBacku
 	return nil
 }
 
+/*
+ * Differential backups are always based on the latest full backup, so that
+ * restoring one requires at most two backup sets.
+ */
+func GetLatestMatchingFullBackupConfig(history *backup_history.History, currentBackupConfig *backup_history.BackupConfig) *backup_history.BackupConfig {
+	for _, backupConfig := range history.BackupConfigs {
+		if IsFullDataBackup(&backupConfig) && MatchesIncrementalFlags(&backupConfig, currentBackupConfig) {
+			return &backupConfig
+		}
+	}
+
+	return nil
+}
+
+func IsFullDataBackup(backupConfig *backup_history.BackupConfig) bool {
+	return backupConfig.GetBackupType() == backup_history.FULL_BACKUP && !backupConfig.DataOnly && !backupConfig.MetadataOnly
+}
+
+/*
+ * A backup taken with --table-predicate-file is missing rows from some tables,
+ * and one taken with --masking-rules-file contains masked data, so neither
+ * ever matches, as basing an incremental backup on it would restore partial
+ * or masked data for any table that has not changed since.
+ */
 func MatchesIncrementalFlags(backupConfig *backup_history.BackupConfig, currentBackupConfig *backup_history.BackupConfig) bool {
-	return backupConfig.BackupDir == MustGetFlagString(utils.BACKUP_DIR) &&
+	return !backupConfig.Subset && len(backupConfig.MaskingRules) == 0 &&
+		backupConfig.BackupDir == MustGetFlagString(utils.BACKUP_DIR) &&
 		backupConfig.DatabaseName == currentBackupConfig.DatabaseName &&
 		backupConfig.LeafPartitionData == MustGetFlagBool(utils.LEAF_PARTITION_DATA) &&
 		backupConfig.Plugin == currentBackupConfig.Plugin &&
 		backupConfig.SingleDataFile == MustGetFlagBool(utils.SINGLE_DATA_FILE) &&
 		backupConfig.Compressed == currentBackupConfig.Compressed &&
-		utils.NewIncludeSet(backupConfig.IncludeRelations).Equals(utils.NewIncludeSet(MustGetFlagStringSlice(utils.INCLUDE_RELATION))) &&
-		utils.NewIncludeSet(backupConfig.IncludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA))) &&
-		utils.NewIncludeSet(backupConfig.ExcludeRelations).Equals(utils.NewIncludeSet(MustGetFlagStringSlice(utils.EXCLUDE_RELATION))) &&
-		utils.NewIncludeSet(backupConfig.ExcludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA)))
+		utils.NewFilterFromBackupConfig(backupConfig).Equals(utils.NewFilterFromBackupConfig(currentBackupConfig))
 }
 
 func PopulateRestorePlan(changedTables []Table,
@@ -74,6 +74,76 @@ var _ = Describe("backup/incremental tests", func() {
 		It("Should NOT include the unmodified AO table", func() {
 			Expect(filteredTables).To(Not(ContainElement(tblAOUnchanged)))
 		})
+
+		Context("Heap tables with tracked changes", func() {
+			defaultHeapEntry := utils.HeapEntry{
+				LastDDLTimestamp: "00000",
+				Fingerprints:     []utils.SegmentFingerprint{{ContentID: 0, NumRows: 1, Checksum: "123"}, {ContentID: 1, NumRows: 0, Checksum: "0"}},
+			}
+			prevHeapTOC := utils.TOC{
+				IncrementalMetadata: utils.IncrementalEntries{
+					Heap: map[string]utils.HeapEntry{
+						"public.heap_changed_data":      defaultHeapEntry,
+						"public.heap_changed_timestamp": defaultHeapEntry,
+						"public.heap_unchanged":         defaultHeapEntry,
+					},
+				},
+			}
+			currHeapTOC := utils.TOC{
+				IncrementalMetadata: utils.IncrementalEntries{
+					Heap: map[string]utils.HeapEntry{
+						"public.heap_changed_data": {
+							LastDDLTimestamp: "00000",
+							Fingerprints:     []utils.SegmentFingerprint{{ContentID: 0, NumRows: 1, Checksum: "123"}, {ContentID: 1, NumRows: 1, Checksum: "456"}},
+						},
+						"public.heap_changed_timestamp": {
+							LastDDLTimestamp: "00001",
+							Fingerprints:     defaultHeapEntry.Fingerprints,
+						},
+						"public.heap_unchanged": defaultHeapEntry,
+						"public.heap_new":       defaultHeapEntry,
+					},
+				},
+			}
+
+			tblHeapChangedData := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_data"}}
+			tblHeapChangedTS := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_timestamp"}}
+			tblHeapUnchanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_unchanged"}}
+			tblHeapNew := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_new"}}
+			heapTables := []backup.Table{
+				tblHeapChangedData,
+				tblHeapChangedTS,
+				tblHeapUnchanged,
+				tblHeapNew,
+			}
+
+			filteredHeapTables := backup.FilterTablesForIncremental(&prevHeapTOC, &currHeapTOC, heapTables)
+
+			It("Should include the heap table having modified contents", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedData))
+			})
+
+			It("Should include the heap table having a modified last DDL timestamp", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapChangedTS))
+			})
+
+			It("Should include the heap table that was not tracked in the previous backup", func() {
+				Expect(filteredHeapTables).To(ContainElement(tblHeapNew))
+			})
+
+			It("Should NOT include the unmodified heap table", func() {
+				Expect(filteredHeapTables).To(Not(ContainElement(tblHeapUnchanged)))
+			})
+
+			It("Should include the unmodified heap table if the previous backup used a different fingerprint format", func() {
+				currVersionTOC := currHeapTOC
+				currVersionTOC.FingerprintVersion = utils.FINGERPRINT_VERSION
+
+				filteredVersionTables := backup.FilterTablesForIncremental(&prevHeapTOC, &currVersionTOC, heapTables)
+
+				Expect(filteredVersionTables).To(ContainElement(tblHeapUnchanged))
+			})
+		})
 	})
 
 	Describe("GetLatestMatchingBackupConfig", func() {
@@ -97,6 +167,41 @@ var _ = Describe("backup/incremental tests", func() {
 
 			Expect(latestBackupHistoryEntry).To(BeNil())
 		})
+		It("should return the latest backup whose filters include the same objects", func() {
+			filteredHistory := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+				{DatabaseName: "test1", Timestamp: "timestamp3", IncludeSchemas: []string{"public"}},
+				{DatabaseName: "test1", Timestamp: "timestamp2", IncludeSchemas: []string{"public"}, ExcludeRelations: []string{"public.audit2", "public.audit1"}},
+				{DatabaseName: "test1", Timestamp: "timestamp1"},
+			}}
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1", IncludeSchemas: []string{"public"},
+				ExcludeSchemas: []string{"other"}, ExcludeRelations: []string{"public.audit1", "public.audit2"}}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(&filteredHistory, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(filteredHistory.BackupConfigs[1], latestBackupHistoryEntry)
+		})
+		It("should not return a backup of a subset of table rows", func() {
+			subsetHistory := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+				{DatabaseName: "test1", Timestamp: "timestamp2", Subset: true},
+				{DatabaseName: "test1", Timestamp: "timestamp1"},
+			}}
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(&subsetHistory, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(subsetHistory.BackupConfigs[1], latestBackupHistoryEntry)
+		})
+		It("should not return a backup with masked data", func() {
+			maskedHistory := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+				{DatabaseName: "test1", Timestamp: "timestamp2", MaskingRules: []string{"public.customers.email: hash"}},
+				{DatabaseName: "test1", Timestamp: "timestamp1"},
+			}}
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(&maskedHistory, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(maskedHistory.BackupConfigs[1], latestBackupHistoryEntry)
+		})
 		It("should return nil with an empty history", func() {
 			currentBackupConfig := backup_history.BackupConfig{}
 
@@ -107,6 +212,39 @@ var _ = Describe("backup/incremental tests", func() {
 		})
 	})
 
+	Describe("GetLatestMatchingFullBackupConfig", func() {
+		history := backup_history.History{BackupConfigs: []backup_history.BackupConfig{
+			{DatabaseName: "test1", Timestamp: "timestamp5", BackupType: "differential", Incremental: true},
+			{DatabaseName: "test1", Timestamp: "timestamp4", BackupType: "incremental", Incremental: true},
+			{DatabaseName: "test1", Timestamp: "timestamp3", BackupType: "full", MetadataOnly: true},
+			{DatabaseName: "test1", Timestamp: "timestamp2", BackupType: "full"},
+			{DatabaseName: "test1", Timestamp: "timestamp1"},
+		}}
+		It("Should return the latest full backup with matching Dbname", func() {
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingFullBackupConfig(&history, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(history.BackupConfigs[3], latestBackupHistoryEntry)
+		})
+		It("Should treat a backup without a recorded type as a full backup", func() {
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+			olderHistory := backup_history.History{BackupConfigs: history.BackupConfigs[4:]}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingFullBackupConfig(&olderHistory, &currentBackupConfig)
+
+			structmatcher.ExpectStructsToMatch(history.BackupConfigs[4], latestBackupHistoryEntry)
+		})
+		It("should return nil with no matching full backup", func() {
+			currentBackupConfig := backup_history.BackupConfig{DatabaseName: "test1"}
+			incrementalHistory := backup_history.History{BackupConfigs: history.BackupConfigs[:3]}
+
+			latestBackupHistoryEntry := backup.GetLatestMatchingFullBackupConfig(&incrementalHistory, &currentBackupConfig)
+
+			Expect(latestBackupHistoryEntry).To(BeNil())
+		})
+	})
+
 	Describe("PopulateRestorePlan", func() {
 		testCluster := testutils.SetDefaultSegmentConfiguration()
 		testFPInfo := backup_filepath.NewFilePathInfo(testCluster, "", "ts0",
@@ -0,0 +1,133 @@
+package backup
+
+/*
+ * This file contains functions related to listing the contents of a backup
+ * without taking it, for --list-only.
+ */
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+/*
+ * The metadata is generated exactly as it would be for a backup, but written
+ * to a discarded stream, so that the objects listed are the objects whose
+ * metadata would be added to the table of contents, using the same filtering
+ * logic as a real backup.  The list is written to stdout rather than logged,
+ * so that it can be redirected or piped without the prefixes of log messages.
+ */
+func DoListOnly() {
+	gplog.Info("Listing the contents of a backup of database %s; no backup files will be written", connectionPool.DBName)
+	objectCounts = make(map[string]int, 0)
+	metadataTables, dataTables := RetrieveAndProcessTables()
+
+	if !MustGetFlagBool(utils.DATA_ONLY) {
+		metadataFile := utils.NewFileWithByteCount(ioutil.Discard)
+		tableOnlyBackup := true
+		if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION)) == 0 {
+			tableOnlyBackup = false
+			backupGlobal(metadataFile)
+		}
+		backupPredata(metadataFile, metadataTables, tableOnlyBackup)
+		backupPostdata(metadataFile)
+		entries := make([]utils.MetadataEntry, 0)
+		entries = append(entries, globalTOC.GlobalEntries...)
+		entries = append(entries, globalTOC.PredataEntries...)
+		entries = append(entries, globalTOC.PostdataEntries...)
+		PrintObjectList(os.Stdout, entries)
+	}
+	if !MustGetFlagBool(utils.METADATA_ONLY) {
+		PrintTableDataList(os.Stdout, dataTables)
+	}
+}
+
+func PrintObjectList(output io.Writer, entries []utils.MetadataEntry) {
+	entriesByType := make(map[string]map[string][]string, 0)
+	for _, entry := range entries {
+		if entriesByType[entry.ObjectType] == nil {
+			entriesByType[entry.ObjectType] = make(map[string][]string, 0)
+		}
+		entriesByType[entry.ObjectType][entry.Schema] = append(entriesByType[entry.ObjectType][entry.Schema], entry.Name)
+	}
+	objectTypes := make([]string, 0)
+	for objectType := range entriesByType {
+		objectTypes = append(objectTypes, objectType)
+	}
+	sort.Strings(objectTypes)
+
+	fmt.Fprintf(output, "Objects that would be backed up:\n")
+	for _, objectType := range objectTypes {
+		schemas := make([]string, 0)
+		numObjects := 0
+		for schema, names := range entriesByType[objectType] {
+			schemas = append(schemas, schema)
+			numObjects += len(names)
+		}
+		sort.Strings(schemas)
+		fmt.Fprintf(output, "  %s (%d)\n", objectType, numObjects)
+		for _, schema := range schemas {
+			names := entriesByType[objectType][schema]
+			sort.Strings(names)
+			indent := "    "
+			if schema != "" {
+				fmt.Fprintf(output, "    Schema %s:\n", schema)
+				indent = "      "
+			}
+			for _, name := range names {
+				fmt.Fprintf(output, "%s%s\n", indent, name)
+			}
+		}
+	}
+}
+
+func PrintTableDataList(output io.Writer, tables []Table) {
+	fmt.Fprintf(output, "Table data that would be backed up:\n")
+	fmt.Fprintf(output, "  %-16s%-20s%s\n", "Estimated Size", "Partition Handling", "Table")
+	var totalSize int64
+	numTables := 0
+	for _, table := range tables {
+		if table.SkipDataBackup() {
+			fmt.Fprintf(output, "  %-16s%-20s%s\n", "-", "Skipped", table.FQN())
+			continue
+		}
+		fmt.Fprintf(output, "  %-16s%-20s%s\n", FormatSizeEstimate(table.SizeEstimate), GetPartitionHandling(table), table.FQN())
+		totalSize += table.SizeEstimate
+		numTables++
+	}
+	fmt.Fprintf(output, "Total estimated data size: %s in %d tables\n", FormatSizeEstimate(totalSize), numTables)
+}
+
+/*
+ * Without --leaf-partition-data, the data for all of a partition table's
+ * leaf partitions is backed up through the root partition.
+ */
+func GetPartitionHandling(table Table) string {
+	switch table.PartitionLevelInfo.Level {
+	case "p":
+		return "Root"
+	case "i":
+		return "Intermediate"
+	case "l":
+		return "Leaf"
+	}
+	return "None"
+}
+
+// Formats a size in bytes in the same way as pg_size_pretty
+func FormatSizeEstimate(size int64) string {
+	units := []string{"bytes", "kB", "MB", "GB"}
+	for _, unit := range units {
+		if size < 10*1024 {
+			return fmt.Sprintf("%d %s", size, unit)
+		}
+		size = (size + 512) / 1024
+	}
+	return fmt.Sprintf("%d TB", size)
+}
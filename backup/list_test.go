@@ -0,0 +1,72 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("backup/list tests", func() {
+	Describe("PrintObjectList", func() {
+		It("prints objects grouped by type and schema", func() {
+			entries := []utils.MetadataEntry{
+				{Schema: "", Name: "testrole", ObjectType: "ROLE"},
+				{Schema: "schema2", Name: "foo", ObjectType: "TABLE"},
+				{Schema: "schema1", Name: "bar", ObjectType: "TABLE"},
+				{Schema: "schema1", Name: "baz", ObjectType: "TABLE"},
+				{Schema: "schema1", Name: "foo_idx", ObjectType: "INDEX", ReferenceObject: "schema1.bar"},
+			}
+
+			backup.PrintObjectList(buffer, entries)
+
+			Expect(buffer).To(gbytes.Say(`Objects that would be backed up:`))
+			Expect(buffer).To(gbytes.Say(`  INDEX \(1\)`))
+			Expect(buffer).To(gbytes.Say(`    Schema schema1:`))
+			Expect(buffer).To(gbytes.Say(`      foo_idx`))
+			Expect(buffer).To(gbytes.Say(`  ROLE \(1\)`))
+			Expect(buffer).To(gbytes.Say(`    testrole`))
+			Expect(buffer).To(gbytes.Say(`  TABLE \(3\)`))
+			Expect(buffer).To(gbytes.Say(`    Schema schema1:`))
+			Expect(buffer).To(gbytes.Say(`      bar`))
+			Expect(buffer).To(gbytes.Say(`      baz`))
+			Expect(buffer).To(gbytes.Say(`    Schema schema2:`))
+			Expect(buffer).To(gbytes.Say(`      foo`))
+			Expect(stdout).ToNot(gbytes.Say(`Objects that would be backed up:`))
+		})
+	})
+	Describe("PrintTableDataList", func() {
+		It("prints the estimated size and partition handling of each table and the total size", func() {
+			tables := []backup.Table{
+				{Relation: backup.Relation{Schema: "public", Name: "foo"}, TableDefinition: backup.TableDefinition{SizeEstimate: 8192}},
+				{Relation: backup.Relation{Schema: "public", Name: "part"}, TableDefinition: backup.TableDefinition{SizeEstimate: 52428800, PartitionLevelInfo: backup.PartitionLevelInfo{Level: "p"}}},
+				{Relation: backup.Relation{Schema: "public", Name: "ext"}, TableDefinition: backup.TableDefinition{IsExternal: true}},
+			}
+
+			backup.PrintTableDataList(buffer, tables)
+
+			Expect(buffer).To(gbytes.Say(`  Estimated Size  Partition Handling  Table`))
+			Expect(buffer).To(gbytes.Say(`  8192 bytes      None                public.foo`))
+			Expect(buffer).To(gbytes.Say(`  50 MB           Root                public.part`))
+			Expect(buffer).To(gbytes.Say(`  -               Skipped             public.ext`))
+			Expect(buffer).To(gbytes.Say(`Total estimated data size: 50 MB in 2 tables`))
+		})
+	})
+	Describe("GetPartitionHandling", func() {
+		It("describes how each level of a partition table is backed up", func() {
+			Expect(backup.GetPartitionHandling(backup.Table{TableDefinition: backup.TableDefinition{PartitionLevelInfo: backup.PartitionLevelInfo{Level: "p"}}})).To(Equal("Root"))
+			Expect(backup.GetPartitionHandling(backup.Table{TableDefinition: backup.TableDefinition{PartitionLevelInfo: backup.PartitionLevelInfo{Level: "l"}}})).To(Equal("Leaf"))
+			Expect(backup.GetPartitionHandling(backup.Table{})).To(Equal("None"))
+		})
+	})
+	Describe("FormatSizeEstimate", func() {
+		It("formats sizes in the largest unit that keeps at least 10 of that unit", func() {
+			Expect(backup.FormatSizeEstimate(0)).To(Equal("0 bytes"))
+			Expect(backup.FormatSizeEstimate(10239)).To(Equal("10239 bytes"))
+			Expect(backup.FormatSizeEstimate(10240)).To(Equal("10 kB"))
+			Expect(backup.FormatSizeEstimate(5 * 1024 * 1024 * 1024)).To(Equal("5120 MB"))
+			Expect(backup.FormatSizeEstimate(20 * 1024 * 1024 * 1024 * 1024 * 1024)).To(Equal("20480 TB"))
+		})
+	})
+})
@@ -0,0 +1,207 @@
+package backup
+
+/*
+ * This file contains structs and functions related to acquiring locks on the
+ * tables being backed up and diagnosing sessions that block those locks.
+ */
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+const tablesPerLockStatement = 1000
+
+var (
+	// This is a variable rather than a constant so that tests can shorten it
+	lockWaitWarningInterval = 60 * time.Second
+
+	conflictingLockModes = map[string][]string{
+		"ACCESS SHARE": {"AccessExclusiveLock"},
+		"EXCLUSIVE": {"RowExclusiveLock", "ShareUpdateExclusiveLock", "ShareLock", "ShareRowExclusiveLock",
+			"ExclusiveLock", "AccessExclusiveLock"},
+	}
+)
+
+type LockBlocker struct {
+	Pid      int
+	Usename  string
+	Query    string
+	Mode     string
+	Relation string
+}
+
+func (blocker LockBlocker) String() string {
+	return fmt.Sprintf("%s on table %s held by pid %d (user %s): %s", blocker.Mode, blocker.Relation, blocker.Pid, blocker.Usename, blocker.Query)
+}
+
+/*
+ * Tables are locked in batches to avoid one round trip per table.  If a batch
+ * waits for longer than lockWaitWarningInterval, or times out waiting due to
+ * --lock-wait-timeout, the sessions holding conflicting locks are looked up on
+ * a separate connection and written to the log and the backup report.
+ */
+func LockTables(connectionPool *dbconn.DBConn, tables []Relation, lockMode string, lockWaitTimeout int) {
+	gplog.Info("Acquiring %s locks on tables", lockMode)
+	backendPid := dbconn.MustSelectString(connectionPool, "SELECT pg_backend_pid()::text AS string")
+	var diagnosticConn *dbconn.DBConn
+	defer func() {
+		if diagnosticConn != nil {
+			diagnosticConn.Close()
+		}
+	}()
+	getBlockers := func(batch []Relation) []LockBlocker {
+		if diagnosticConn == nil {
+			diagnosticConn = dbconn.NewDBConnFromEnvironment(connectionPool.DBName)
+			err := diagnosticConn.Connect(1)
+			if err != nil {
+				gplog.Verbose("Unable to connect to look up sessions blocking table locks: %s", err.Error())
+				diagnosticConn = nil
+				return []LockBlocker{}
+			}
+		}
+		return GetLockBlockers(diagnosticConn, backendPid, batch, lockMode)
+	}
+	LockTableBatches(connectionPool, tables, lockMode, lockWaitTimeout, getBlockers)
+}
+
+/*
+ * --lock-wait-timeout limits the total time spent waiting for all of the
+ * locks.  lock_timeout applies to each lock separately, so statement_timeout
+ * is instead set to the time remaining before each batch is locked.
+ */
+func LockTableBatches(connectionPool *dbconn.DBConn, tables []Relation, lockMode string, lockWaitTimeout int, getBlockers func([]Relation) []LockBlocker) {
+	deadline := time.Now().Add(time.Duration(lockWaitTimeout) * time.Second)
+
+	progressBar := utils.NewProgressBar(len(tables), "Locks acquired: ", utils.PB_VERBOSE)
+	progressBar.Start()
+	reportedBlockers := make(map[LockBlocker]bool, 0)
+	for start := 0; start < len(tables); start += tablesPerLockStatement {
+		end := start + tablesPerLockStatement
+		if end > len(tables) {
+			end = len(tables)
+		}
+		batch := tables[start:end]
+		if lockWaitTimeout > 0 {
+			remaining := time.Until(deadline) / time.Millisecond
+			if remaining < 1 {
+				remaining = 1
+			}
+			connectionPool.MustExec(fmt.Sprintf("SET statement_timeout = %d", remaining))
+		}
+		tableFQNs := make([]string, 0, len(batch))
+		for _, table := range batch {
+			tableFQNs = append(tableFQNs, table.FQN())
+		}
+
+		done := make(chan struct{})
+		var monitor sync.WaitGroup
+		monitor.Add(1)
+		go func() {
+			defer monitor.Done()
+			ticker := time.NewTicker(lockWaitWarningInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					for _, blocker := range getBlockers(batch) {
+						if !reportedBlockers[blocker] {
+							reportedBlockers[blocker] = true
+							gplog.Warn("Waiting for %s lock; blocked by %s", lockMode, blocker.String())
+							recordLockWait(blocker)
+						}
+					}
+				}
+			}
+		}()
+		_, err := connectionPool.Exec(fmt.Sprintf("LOCK TABLE %s IN %s MODE", strings.Join(tableFQNs, ", "), lockMode))
+		close(done)
+		monitor.Wait()
+
+		if err != nil {
+			if !IsLockTimeoutError(err) {
+				gplog.FatalOnError(err)
+			}
+			for _, blocker := range getBlockers(batch) {
+				gplog.Error("Timed out waiting for %s lock; blocked by %s", lockMode, blocker.String())
+				recordLockWait(blocker)
+			}
+			gplog.Fatal(errors.Errorf("Timed out after %d seconds waiting to acquire %s locks on tables.  "+
+				"See the log and report files for the sessions holding conflicting locks.", lockWaitTimeout, lockMode), "")
+		}
+		for range batch {
+			progressBar.Increment()
+		}
+	}
+	progressBar.Finish()
+
+	if lockWaitTimeout > 0 {
+		connectionPool.MustExec("SET statement_timeout = 0")
+	}
+}
+
+func IsLockTimeoutError(err error) bool {
+	// 55P03 is lock_not_available and 57014 is query_canceled, raised by lock_timeout and statement_timeout respectively
+	sqlState := utils.GetSQLState(err)
+	return sqlState == "55P03" || sqlState == "57014"
+}
+
+func recordLockWait(blocker LockBlocker) {
+	if backupReport != nil {
+		backupReport.LockWaits = append(backupReport.LockWaits, blocker.String())
+	}
+}
+
+/*
+ * This query looks for granted locks on the given tables held by other
+ * sessions in modes that conflict with the lock mode being requested, rather
+ * than for the backup's ungranted lock requests, so that it still finds the
+ * blockers after a lock request has timed out.
+ */
+func GetLockBlockers(connectionPool *dbconn.DBConn, backendPid string, tables []Relation, lockMode string) []LockBlocker {
+	pidColumn := "pid"
+	queryColumn := "query"
+	if connectionPool.Version.Before("6") {
+		pidColumn = "procpid"
+		queryColumn = "current_query"
+	}
+	tableOids := make([]string, 0, len(tables))
+	for _, table := range tables {
+		tableOids = append(tableOids, fmt.Sprintf("%d", table.Oid))
+	}
+	query := fmt.Sprintf(`
+SELECT DISTINCT
+	l.pid,
+	coalesce(a.usename, '') AS usename,
+	coalesce(a.%s, '') AS query,
+	l.mode,
+	quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS relation
+FROM pg_locks l
+JOIN pg_class c ON c.oid = l.relation
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_stat_activity a ON a.%s = l.pid
+WHERE l.locktype = 'relation'
+AND l.granted
+AND l.gp_segment_id = -1
+AND l.pid <> %s
+AND l.relation IN (%s)
+AND l.mode IN (%s)
+ORDER BY l.pid, relation, l.mode`, queryColumn, pidColumn, backendPid, strings.Join(tableOids, ", "), utils.SliceToQuotedString(conflictingLockModes[lockMode]))
+
+	results := make([]LockBlocker, 0)
+	err := connectionPool.Select(&results, query)
+	if err != nil {
+		gplog.Verbose("Unable to look up sessions blocking table locks: %s", err.Error())
+		return []LockBlocker{}
+	}
+	return results
+}
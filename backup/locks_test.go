@@ -0,0 +1,81 @@
+package backup_test
+
+import (
+	"fmt"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("backup/locks tests", func() {
+	blocker := backup.LockBlocker{Pid: 1234, Usename: "testrole", Query: "ALTER TABLE public.foo ADD COLUMN j int", Mode: "AccessExclusiveLock", Relation: "public.foo"}
+	noBlockers := func([]backup.Relation) []backup.LockBlocker { return []backup.LockBlocker{} }
+
+	Describe("LockTableBatches", func() {
+		It("locks tables in batches of 1000", func() {
+			tables := make([]backup.Relation, 0)
+			for i := 0; i < 1001; i++ {
+				tables = append(tables, backup.Relation{Oid: uint32(i), Schema: "public", Name: fmt.Sprintf("t%d", i)})
+			}
+			mock.ExpectExec(`LOCK TABLE public.t0, public.t1, (.*), public.t999 IN ACCESS SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`LOCK TABLE public.t1000 IN ACCESS SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.LockTableBatches(connectionPool, tables, "ACCESS SHARE", 0, noBlockers)
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("sets the time remaining before --lock-wait-timeout expires as the timeout for each batch", func() {
+			tables := make([]backup.Relation, 0)
+			for i := 0; i < 1001; i++ {
+				tables = append(tables, backup.Relation{Oid: uint32(i), Schema: "public", Name: fmt.Sprintf("t%d", i)})
+			}
+			mock.ExpectExec(`SET statement_timeout = (29|30)[0-9]{3}$`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`LOCK TABLE public.t0, (.*) IN ACCESS SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`SET statement_timeout = (29|30)[0-9]{3}$`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`LOCK TABLE public.t1000 IN ACCESS SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`SET statement_timeout = 0`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.LockTableBatches(connectionPool, tables, "ACCESS SHARE", 30, noBlockers)
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("reports the sessions blocking the locks when the timeout expires", func() {
+			report := &utils.Report{}
+			backup.SetReport(report)
+			defer backup.SetReport(nil)
+			tables := []backup.Relation{{Oid: 1, Schema: "public", Name: "foo"}}
+			mock.ExpectExec(`SET statement_timeout = [0-9]+`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`LOCK TABLE public.foo IN ACCESS SHARE MODE`).WillReturnError(errors.New("ERROR: canceling statement due to statement timeout (SQLSTATE 57014)"))
+			getBlockers := func([]backup.Relation) []backup.LockBlocker { return []backup.LockBlocker{blocker} }
+
+			defer func() {
+				Expect(logfile).To(gbytes.Say(`Timed out waiting for ACCESS SHARE lock; blocked by AccessExclusiveLock on table public.foo held by pid 1234 \(user testrole\): ALTER TABLE public.foo ADD COLUMN j int`))
+				Expect(report.LockWaits).To(Equal([]string{blocker.String()}))
+			}()
+			defer testhelper.ShouldPanicWithMessage("Timed out after 30 seconds waiting to acquire ACCESS SHARE locks on tables.")
+			backup.LockTableBatches(connectionPool, tables, "ACCESS SHARE", 30, getBlockers)
+		})
+	})
+	Describe("GetLockBlockers", func() {
+		It("returns sessions holding conflicting locks on the given tables", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			tables := []backup.Relation{{Oid: 1, Schema: "public", Name: "foo"}, {Oid: 2, Schema: "public", Name: "bar"}}
+			blockerRows := sqlmock.NewRows([]string{"pid", "usename", "query", "mode", "relation"}).
+				AddRow(blocker.Pid, blocker.Usename, blocker.Query, blocker.Mode, blocker.Relation)
+			mock.ExpectQuery(`(.*)AND l.pid <> 5678
+AND l.relation IN \(1, 2\)
+AND l.mode IN \('AccessExclusiveLock'\)(.*)`).WillReturnRows(blockerRows)
+
+			blockers := backup.GetLockBlockers(connectionPool, "5678", tables, "ACCESS SHARE")
+
+			Expect(blockers).To(Equal([]backup.LockBlocker{blocker}))
+		})
+	})
+})
@@ -0,0 +1,338 @@
+package backup
+
+/*
+ * This file contains structs and functions related to masking the data in
+ * table columns with the rules given in the --masking-rules-file.
+ */
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	MASK_CONSTANT    = "constant"
+	MASK_DATE_SHIFT  = "date-shift"
+	MASK_HASH        = "hash"
+	MASK_KEEP_PREFIX = "keep-prefix"
+	MASK_NULL        = "null"
+)
+
+/*
+ * A rule is written as the name of a transform, followed by a colon and the
+ * transform's argument for those transforms that take one, e.g. "hash",
+ * "constant:REDACTED", "keep-prefix:3", or "date-shift:-30 days".
+ */
+type MaskingRule struct {
+	Transform string
+	Argument  string
+}
+
+func (rule MaskingRule) String() string {
+	if rule.Transform == MASK_HASH || rule.Transform == MASK_NULL {
+		return rule.Transform
+	}
+	return fmt.Sprintf("%s:%s", rule.Transform, rule.Argument)
+}
+
+func ParseMaskingRule(ruleStr string) (MaskingRule, error) {
+	transform, argument := ruleStr, ""
+	hasArgument := false
+	if index := strings.Index(ruleStr, ":"); index != -1 {
+		transform, argument = ruleStr[:index], ruleStr[index+1:]
+		hasArgument = true
+	}
+	rule := MaskingRule{Transform: transform, Argument: argument}
+	switch transform {
+	case MASK_HASH, MASK_NULL:
+		if hasArgument {
+			return MaskingRule{}, errors.Errorf("Masking rule %s does not take an argument", transform)
+		}
+	case MASK_CONSTANT:
+		if !hasArgument {
+			return MaskingRule{}, errors.Errorf("Masking rule %s requires a value, e.g. %s:REDACTED", transform, transform)
+		}
+	case MASK_KEEP_PREFIX:
+		length, err := strconv.Atoi(argument)
+		if err != nil || length < 0 {
+			return MaskingRule{}, errors.Errorf("Masking rule %s requires a non-negative number of characters to keep, e.g. %s:3", transform, transform)
+		}
+	case MASK_DATE_SHIFT:
+		if strings.TrimSpace(argument) == "" {
+			return MaskingRule{}, errors.Errorf("Masking rule %s requires an interval, e.g. %s:-30 days", transform, transform)
+		}
+	default:
+		return MaskingRule{}, errors.Errorf("Masking rule %s is invalid.  Valid rules are %s, %s, %s, %s, and %s.",
+			ruleStr, MASK_CONSTANT, MASK_DATE_SHIFT, MASK_HASH, MASK_KEEP_PREFIX, MASK_NULL)
+	}
+	return rule, nil
+}
+
+/*
+ * The masking rules file is a YAML map from fully-qualified table names to
+ * maps from column names to rules, e.g.
+ *
+ *   public.customers:
+ *     email: hash
+ *     phone: keep-prefix:3
+ *     birth_date: date-shift:-30 days
+ */
+func ReadMaskingRulesFile(filename string) (map[string]map[string]MaskingRule, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	ruleStrs := make(map[string]map[string]string, 0)
+	err = yaml.Unmarshal(contents, &ruleStrs)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Unable to parse masking rules file %s", filename))
+	}
+	if len(ruleStrs) == 0 {
+		return nil, errors.Errorf("Masking rules file %s does not contain any rules", filename)
+	}
+	rules := make(map[string]map[string]MaskingRule, len(ruleStrs))
+	for table, columnRuleStrs := range ruleStrs {
+		rules[table] = make(map[string]MaskingRule, len(columnRuleStrs))
+		for column, ruleStr := range columnRuleStrs {
+			rule, err := ParseMaskingRule(ruleStr)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("Invalid rule for column %s of table %s in masking rules file %s", column, table, filename))
+			}
+			rules[table][column] = rule
+		}
+	}
+	return rules, nil
+}
+
+/*
+ * With --leaf-partition-data, the data of a partition table is backed up from
+ * its leaf partitions, so the rules for the partition table apply to those.
+ */
+func GetTableMaskingRules(table Table) map[string]MaskingRule {
+	if rules, ok := maskingRules[table.FQN()]; ok {
+		return rules
+	}
+	if table.PartitionLevelInfo.RootName != "" {
+		return maskingRules[utils.MakeFQN(table.Schema, table.PartitionLevelInfo.RootName)]
+	}
+	return nil
+}
+
+func GetMaskedColumns(table Table) []string {
+	rules := GetTableMaskingRules(table)
+	if len(rules) == 0 {
+		return nil
+	}
+	maskedColumns := make([]string, 0, len(rules))
+	for column, rule := range rules {
+		maskedColumns = append(maskedColumns, fmt.Sprintf("%s: %s", column, rule))
+	}
+	sort.Strings(maskedColumns)
+	return maskedColumns
+}
+
+func GetMaskingRuleList() []string {
+	if len(maskingRules) == 0 {
+		return nil
+	}
+	ruleList := make([]string, 0)
+	for table, rules := range maskingRules {
+		for column, rule := range rules {
+			ruleList = append(ruleList, fmt.Sprintf("%s.%s: %s", table, column, rule))
+		}
+	}
+	sort.Strings(ruleList)
+	return ruleList
+}
+
+/*
+ * The salt for hashed values is not stored, so a resumed backup could not hash
+ * the tables it copies again with the salt used for the data files it reuses,
+ * and equal values would no longer hash to the same result across tables.
+ */
+func ValidateMaskingRulesForResume(rules map[string]map[string]MaskingRule) {
+	if MustGetFlagString(utils.RESUME) == "" {
+		return
+	}
+	for _, columnRules := range rules {
+		for _, rule := range columnRules {
+			if rule.Transform == MASK_HASH {
+				gplog.Fatal(errors.Errorf("Cannot use --resume with a masking rules file containing hash rules, as the salt for hashed values is not saved"), "")
+			}
+		}
+	}
+}
+
+/*
+ * The hash rule salts each value with a random string generated for each
+ * backup and not stored anywhere, so that equal values hash to the same
+ * result within a backup, keeping joins between masked columns intact, but
+ * the original values cannot be found by hashing candidate values.
+ */
+func NewMaskingSalt() string {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	gplog.FatalOnError(err)
+	return hex.EncodeToString(salt)
+}
+
+/*
+ * Each masked value is cast back to the column's type, so that the data file
+ * can be restored into the original table definition.
+ */
+func GetMaskingExpression(column ColumnDefinition, rule MaskingRule) string {
+	switch rule.Transform {
+	case MASK_CONSTANT:
+		return fmt.Sprintf("CAST('%s' AS %s)", utils.EscapeSingleQuotes(rule.Argument), column.Type)
+	case MASK_DATE_SHIFT:
+		return fmt.Sprintf("CAST(%s + interval '%s' AS %s)", column.Name, utils.EscapeSingleQuotes(rule.Argument), column.Type)
+	case MASK_HASH:
+		return fmt.Sprintf("CAST(md5('%s' || %s::text) AS %s)", maskingSalt, column.Name, column.Type)
+	case MASK_KEEP_PREFIX:
+		return fmt.Sprintf("CAST(substr(%[1]s::text, 1, %[2]s) || repeat('*', greatest(length(%[1]s::text) - %[2]s, 0)) AS %[3]s)", column.Name, rule.Argument, column.Type)
+	case MASK_NULL:
+		return fmt.Sprintf("CAST(NULL AS %s)", column.Type)
+	}
+	return column.Name
+}
+
+func GetMaskedSelectList(table Table) string {
+	rules := GetTableMaskingRules(table)
+	if len(rules) == 0 {
+		return ""
+	}
+	selectList := make([]string, 0, len(table.ColumnDefs))
+	for _, column := range table.ColumnDefs {
+		if rule, ok := rules[column.Name]; ok {
+			selectList = append(selectList, fmt.Sprintf("%s AS %s", GetMaskingExpression(column, rule), column.Name))
+		} else {
+			selectList = append(selectList, column.Name)
+		}
+	}
+	return strings.Join(selectList, ", ")
+}
+
+func isTextType(columnType string) bool {
+	return columnType == "text" || strings.HasPrefix(columnType, "character")
+}
+
+func isDateType(columnType string) bool {
+	return columnType == "date" || strings.HasPrefix(columnType, "timestamp")
+}
+
+func ValidateMaskingRuleForColumn(table string, column ColumnDefinition, rule MaskingRule) error {
+	switch rule.Transform {
+	case MASK_HASH, MASK_KEEP_PREFIX:
+		if !isTextType(column.Type) {
+			return errors.Errorf("Cannot apply masking rule %s to column %s of table %s with type %s.  Only character types can be masked with %s.",
+				rule, column.Name, table, column.Type, rule.Transform)
+		}
+	case MASK_DATE_SHIFT:
+		if !isDateType(column.Type) {
+			return errors.Errorf("Cannot apply masking rule %s to column %s of table %s with type %s.  Only date and timestamp types can be masked with %s.",
+				rule, column.Name, table, column.Type, rule.Transform)
+		}
+	case MASK_NULL:
+		if column.NotNull {
+			return errors.Errorf("Cannot apply masking rule %s to column %s of table %s, as the column is NOT NULL", rule, column.Name, table)
+		}
+	}
+	return nil
+}
+
+/*
+ * Masking a distribution key or partition key column would change which
+ * segment or partition a row belongs to, so the restored rows could not be
+ * loaded into the original table definition.  The key columns of a leaf
+ * partition's partition table are looked up by name from that table, since
+ * the leaf's attribute numbers may differ after dropped columns.
+ */
+func GetKeyColumnsForTable(connectionPool *dbconn.DBConn, table Table) []string {
+	partitionTable := table.FQN()
+	if table.PartitionLevelInfo.RootName != "" {
+		partitionTable = utils.MakeFQN(table.Schema, table.PartitionLevelInfo.RootName)
+	}
+	query := fmt.Sprintf(`
+SELECT quote_ident(a.attname) AS string
+FROM gp_distribution_policy p
+JOIN pg_attribute a ON a.attrelid = p.localoid AND a.attnum = ANY(p.attrnums)
+WHERE p.localoid = %d
+UNION
+SELECT quote_ident(a.attname) AS string
+FROM pg_partition p
+JOIN pg_attribute a ON a.attrelid = p.parrelid AND a.attnum = ANY(p.paratts)
+WHERE p.parrelid = '%s'::regclass
+ORDER BY string`, table.Oid, utils.EscapeSingleQuotes(partitionTable))
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
+/*
+ * The rules are checked against the definitions of the tables being backed
+ * up, and each table's masked select list is planned once, so that an
+ * invalid constant or interval fails the backup before any data is copied.
+ */
+func ValidateMaskingRules(connectionPool *dbconn.DBConn, tables []Table) {
+	tableSet := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		tableSet[table.FQN()] = true
+		if table.PartitionLevelInfo.RootName != "" {
+			tableSet[utils.MakeFQN(table.Schema, table.PartitionLevelInfo.RootName)] = true
+		}
+	}
+	ruleTables := make([]string, 0, len(maskingRules))
+	for table := range maskingRules {
+		ruleTables = append(ruleTables, table)
+	}
+	sort.Strings(ruleTables)
+	for _, table := range ruleTables {
+		if !tableSet[table] {
+			gplog.Fatal(errors.Errorf("Table %s in the masking rules file does not have its data backed up by this backup", table), "")
+		}
+	}
+	for _, table := range tables {
+		rules := GetTableMaskingRules(table)
+		if len(rules) == 0 || table.SkipDataBackup() {
+			continue
+		}
+		columnMap := make(map[string]ColumnDefinition, len(table.ColumnDefs))
+		for _, column := range table.ColumnDefs {
+			columnMap[column.Name] = column
+		}
+		keyColumns := make(map[string]bool, 0)
+		for _, columnName := range GetKeyColumnsForTable(connectionPool, table) {
+			keyColumns[columnName] = true
+		}
+		columnNames := make([]string, 0, len(rules))
+		for columnName := range rules {
+			columnNames = append(columnNames, columnName)
+		}
+		sort.Strings(columnNames)
+		for _, columnName := range columnNames {
+			rule := rules[columnName]
+			column, ok := columnMap[columnName]
+			if !ok {
+				gplog.Fatal(errors.Errorf("Column %s of table %s in the masking rules file does not exist", columnName, table.FQN()), "")
+			}
+			if keyColumns[columnName] {
+				gplog.Fatal(errors.Errorf("Cannot apply masking rule %s to column %s of table %s, as the column is part of the table's distribution key or partition key", rule, columnName, table.FQN()), "")
+			}
+			err := ValidateMaskingRuleForColumn(table.FQN(), column, rule)
+			gplog.FatalOnError(err)
+		}
+		_, err := connectionPool.Exec(fmt.Sprintf("SELECT %s FROM %s LIMIT 0", GetMaskedSelectList(table), table.FQN()))
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Invalid masking rules for table %s: %s", table.FQN(), err.Error()), "")
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/masking tests", func() {
+	columnDefs := []backup.ColumnDefinition{
+		{Name: "id", Type: "integer", NotNull: true},
+		{Name: "email", Type: "character varying(64)"},
+		{Name: "phone", Type: "text"},
+		{Name: "birth_date", Type: "date"},
+	}
+	customers := backup.Table{
+		Relation:        backup.Relation{Oid: 1, Schema: "public", Name: "customers"},
+		TableDefinition: backup.TableDefinition{ColumnDefs: columnDefs},
+	}
+	AfterEach(func() {
+		backup.SetMaskingRules(nil)
+	})
+	Describe("ParseMaskingRule", func() {
+		It("parses rules with and without arguments", func() {
+			Expect(backup.ParseMaskingRule("hash")).To(Equal(backup.MaskingRule{Transform: backup.MASK_HASH}))
+			Expect(backup.ParseMaskingRule("constant:a:b")).To(Equal(backup.MaskingRule{Transform: backup.MASK_CONSTANT, Argument: "a:b"}))
+			Expect(backup.ParseMaskingRule("keep-prefix:3")).To(Equal(backup.MaskingRule{Transform: backup.MASK_KEEP_PREFIX, Argument: "3"}))
+			Expect(backup.ParseMaskingRule("date-shift:-30 days")).To(Equal(backup.MaskingRule{Transform: backup.MASK_DATE_SHIFT, Argument: "-30 days"}))
+		})
+		It("rejects an unknown rule", func() {
+			_, err := backup.ParseMaskingRule("shuffle")
+			Expect(err).To(MatchError("Masking rule shuffle is invalid.  Valid rules are constant, date-shift, hash, keep-prefix, and null."))
+		})
+		It("rejects an argument to a rule that does not take one", func() {
+			_, err := backup.ParseMaskingRule("null:0")
+			Expect(err).To(MatchError("Masking rule null does not take an argument"))
+		})
+		It("rejects keep-prefix without a number of characters", func() {
+			_, err := backup.ParseMaskingRule("keep-prefix:abc")
+			Expect(err).To(MatchError("Masking rule keep-prefix requires a non-negative number of characters to keep, e.g. keep-prefix:3"))
+		})
+	})
+	Describe("ReadMaskingRulesFile", func() {
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("reads a map of tables and columns to rules", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) {
+				return []byte(`public.customers:
+  email: hash
+  phone: keep-prefix:3
+`), nil
+			}
+
+			rules, err := backup.ReadMaskingRulesFile("/tmp/masking.yaml")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rules).To(Equal(map[string]map[string]backup.MaskingRule{
+				"public.customers": {
+					"email": {Transform: backup.MASK_HASH},
+					"phone": {Transform: backup.MASK_KEEP_PREFIX, Argument: "3"},
+				},
+			}))
+		})
+		It("returns an error naming the column with an invalid rule", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) { return []byte("public.customers:\n  email: shuffle\n"), nil }
+
+			_, err := backup.ReadMaskingRulesFile("/tmp/masking.yaml")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Invalid rule for column email of table public.customers in masking rules file /tmp/masking.yaml"))
+		})
+		It("returns an error if the file contains no rules", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) { return []byte(""), nil }
+
+			_, err := backup.ReadMaskingRulesFile("/tmp/masking.yaml")
+
+			Expect(err).To(MatchError("Masking rules file /tmp/masking.yaml does not contain any rules"))
+		})
+	})
+	Describe("ValidateMaskingRulesForResume", func() {
+		It("accepts hash rules when not resuming a backup", func() {
+			backup.ValidateMaskingRulesForResume(map[string]map[string]backup.MaskingRule{"public.customers": {"email": {Transform: backup.MASK_HASH}}})
+		})
+		It("accepts rules other than hash rules when resuming a backup", func() {
+			cmdFlags.Set(utils.RESUME, "20170101010101")
+
+			backup.ValidateMaskingRulesForResume(map[string]map[string]backup.MaskingRule{"public.customers": {"phone": {Transform: backup.MASK_KEEP_PREFIX, Argument: "3"}}})
+		})
+		It("panics if a hash rule is given when resuming a backup", func() {
+			cmdFlags.Set(utils.RESUME, "20170101010101")
+
+			defer testhelper.ShouldPanicWithMessage("Cannot use --resume with a masking rules file containing hash rules, as the salt for hashed values is not saved")
+			backup.ValidateMaskingRulesForResume(map[string]map[string]backup.MaskingRule{"public.customers": {
+				"email": {Transform: backup.MASK_HASH},
+				"phone": {Transform: backup.MASK_KEEP_PREFIX, Argument: "3"},
+			}})
+		})
+	})
+	Describe("GetMaskedSelectList", func() {
+		It("returns an empty string for a table without masking rules", func() {
+			Expect(backup.GetMaskedSelectList(customers)).To(Equal(""))
+		})
+		It("applies each rule to its column, casting the result back to the column's type", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {
+				"email":      {Transform: backup.MASK_CONSTANT, Argument: "o'brien@example.com"},
+				"phone":      {Transform: backup.MASK_KEEP_PREFIX, Argument: "3"},
+				"birth_date": {Transform: backup.MASK_DATE_SHIFT, Argument: "-30 days"},
+			}})
+
+			Expect(backup.GetMaskedSelectList(customers)).To(Equal("id, " +
+				"CAST('o''brien@example.com' AS character varying(64)) AS email, " +
+				"CAST(substr(phone::text, 1, 3) || repeat('*', greatest(length(phone::text) - 3, 0)) AS text) AS phone, " +
+				"CAST(birth_date + interval '-30 days' AS date) AS birth_date"))
+		})
+		It("salts hashed values with the salt for the backup", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"email": {Transform: backup.MASK_HASH}}})
+			backup.SetMaskingSalt("0123456789abcdef")
+			defer backup.SetMaskingSalt("")
+
+			Expect(backup.GetMaskedSelectList(customers)).To(Equal("id, CAST(md5('0123456789abcdef' || email::text) AS character varying(64)) AS email, phone, birth_date"))
+		})
+		It("applies the rules for a partition table to its leaf partitions", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"phone": {Transform: backup.MASK_NULL}}})
+			leaf := backup.Table{
+				Relation:        backup.Relation{Oid: 2, Schema: "public", Name: "customers_1_prt_1"},
+				TableDefinition: backup.TableDefinition{ColumnDefs: columnDefs, PartitionLevelInfo: backup.PartitionLevelInfo{Level: "l", RootName: "customers"}},
+			}
+
+			Expect(backup.GetMaskedSelectList(leaf)).To(Equal("id, email, CAST(NULL AS text) AS phone, birth_date"))
+			Expect(backup.GetMaskedColumns(leaf)).To(Equal([]string{"phone: null"}))
+		})
+	})
+	Describe("ValidateMaskingRuleForColumn", func() {
+		It("accepts rules that apply to the column's type", func() {
+			Expect(backup.ValidateMaskingRuleForColumn("public.customers", columnDefs[1], backup.MaskingRule{Transform: backup.MASK_HASH})).To(Succeed())
+			Expect(backup.ValidateMaskingRuleForColumn("public.customers", columnDefs[3], backup.MaskingRule{Transform: backup.MASK_DATE_SHIFT, Argument: "1 day"})).To(Succeed())
+			Expect(backup.ValidateMaskingRuleForColumn("public.customers", columnDefs[0], backup.MaskingRule{Transform: backup.MASK_CONSTANT, Argument: "0"})).To(Succeed())
+		})
+		It("rejects hashing a non-character column", func() {
+			err := backup.ValidateMaskingRuleForColumn("public.customers", columnDefs[0], backup.MaskingRule{Transform: backup.MASK_HASH})
+			Expect(err).To(MatchError("Cannot apply masking rule hash to column id of table public.customers with type integer.  Only character types can be masked with hash."))
+		})
+		It("rejects nulling a NOT NULL column", func() {
+			err := backup.ValidateMaskingRuleForColumn("public.customers", columnDefs[0], backup.MaskingRule{Transform: backup.MASK_NULL})
+			Expect(err).To(MatchError("Cannot apply masking rule null to column id of table public.customers, as the column is NOT NULL"))
+		})
+	})
+	Describe("ValidateMaskingRules", func() {
+		It("panics if a table with masking rules is not backed up", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.orders": {"email": {Transform: backup.MASK_HASH}}})
+
+			defer testhelper.ShouldPanicWithMessage("Table public.orders in the masking rules file does not have its data backed up by this backup")
+			backup.ValidateMaskingRules(connectionPool, []backup.Table{customers})
+		})
+		It("panics if a masked column does not exist", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"ssn": {Transform: backup.MASK_HASH}}})
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"string"}))
+
+			defer testhelper.ShouldPanicWithMessage("Column ssn of table public.customers in the masking rules file does not exist")
+			backup.ValidateMaskingRules(connectionPool, []backup.Table{customers})
+		})
+		It("panics if a masked column is part of the table's distribution key", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"email": {Transform: backup.MASK_HASH}}})
+			mock.ExpectQuery(`(.*)WHERE p.localoid = 1(.*)WHERE p.parrelid = 'public.customers'::regclass`).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("email"))
+
+			defer testhelper.ShouldPanicWithMessage("Cannot apply masking rule hash to column email of table public.customers, as the column is part of the table's distribution key or partition key")
+			backup.ValidateMaskingRules(connectionPool, []backup.Table{customers})
+		})
+		It("panics if a masked column of a leaf partition is part of its partition table's partition key", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"birth_date": {Transform: backup.MASK_DATE_SHIFT, Argument: "1 day"}}})
+			leaf := backup.Table{
+				Relation:        backup.Relation{Oid: 2, Schema: "public", Name: "customers_1_prt_1"},
+				TableDefinition: backup.TableDefinition{ColumnDefs: columnDefs, PartitionLevelInfo: backup.PartitionLevelInfo{Level: "l", RootName: "customers"}},
+			}
+			mock.ExpectQuery(`(.*)WHERE p.localoid = 2(.*)WHERE p.parrelid = 'public.customers'::regclass`).WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("birth_date").AddRow("id"))
+
+			defer testhelper.ShouldPanicWithMessage("Cannot apply masking rule date-shift:1 day to column birth_date of table public.customers_1_prt_1, as the column is part of the table's distribution key or partition key")
+			backup.ValidateMaskingRules(connectionPool, []backup.Table{leaf})
+		})
+		It("plans the masked select list for a table whose masked columns are not key columns", func() {
+			backup.SetMaskingRules(map[string]map[string]backup.MaskingRule{"public.customers": {"email": {Transform: backup.MASK_HASH}}})
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"string"}).AddRow("id"))
+			mock.ExpectExec(`SELECT id, CAST\(md5(.*) AS email, phone, birth_date FROM public.customers LIMIT 0`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.ValidateMaskingRules(connectionPool, []backup.Table{customers})
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+})
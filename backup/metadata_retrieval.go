@@ -0,0 +1,290 @@
+package backup
+
+/*
+ * This file contains functions related to retrieving the pre-data and
+ * post-data metadata from the catalog before any of it is written out.
+ */
+
+import (
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+type PredataObjects struct {
+	RelationMetadata           MetadataMap
+	FuncInfoMap                map[uint32]FunctionInfo
+	Schemas                    []Schema
+	SchemaMetadata             MetadataMap
+	Extensions                 []Extension
+	ExtensionMetadata          MetadataMap
+	Collations                 []Collation
+	CollationMetadata          MetadataMap
+	ProcLangs                  []ProceduralLanguage
+	ProcLangMetadata           MetadataMap
+	Functions                  []Function
+	FunctionMetadata           MetadataMap
+	ShellTypes                 []Type
+	BaseTypes                  []Type
+	CompositeTypes             []Type
+	DomainTypes                []Type
+	RangeTypes                 []Type
+	EnumTypes                  []Type
+	TypeMetadata               MetadataMap
+	ForeignDataWrappers        []ForeignDataWrapper
+	ForeignDataWrapperMetadata MetadataMap
+	ForeignServers             []ForeignServer
+	ForeignServerMetadata      MetadataMap
+	UserMappings               []UserMapping
+	Protocols                  []ExternalProtocol
+	ProtocolMetadata           MetadataMap
+	TSParsers                  []TextSearchParser
+	TSParserMetadata           MetadataMap
+	TSConfigurations           []TextSearchConfiguration
+	TSConfigurationMetadata    MetadataMap
+	TSTemplates                []TextSearchTemplate
+	TSTemplateMetadata         MetadataMap
+	TSDictionaries             []TextSearchDictionary
+	TSDictionaryMetadata       MetadataMap
+	OperatorFamilies           []OperatorFamily
+	OperatorFamilyMetadata     MetadataMap
+	Operators                  []Operator
+	OperatorMetadata           MetadataMap
+	OperatorClasses            []OperatorClass
+	OperatorClassMetadata      MetadataMap
+	Aggregates                 []Aggregate
+	AggregateMetadata          MetadataMap
+	Casts                      []Cast
+	CastMetadata               MetadataMap
+	Views                      []View
+	Sequences                  []Sequence
+	SequenceOwnerColumns       map[string]string
+	Constraints                []Constraint
+	ConstraintMetadata         MetadataMap
+	Conversions                []Conversion
+	ConversionMetadata         MetadataMap
+	ExternalPartitions         []PartitionInfo
+	PartitionInfoMap           map[uint32]PartitionInfo
+}
+
+type PostdataObjects struct {
+	Indexes              []IndexDefinition
+	IndexMetadata        MetadataMap
+	Rules                []RuleDefinition
+	RuleMetadata         MetadataMap
+	Triggers             []TriggerDefinition
+	TriggerMetadata      MetadataMap
+	DefaultPrivileges    []DefaultPrivileges
+	EventTriggers        []EventTrigger
+	EventTriggerMetadata MetadataMap
+}
+
+/*
+ * The metadata queries can only be spread across the connection pool when
+ * every connection reads from the same snapshot, as otherwise objects created
+ * or dropped during the backup could appear in the results of some queries
+ * but not others.
+ */
+func CanRunMetadataQueriesInParallel() bool {
+	return dataConsistency == CONSISTENCY_SYNCHRONIZED && connectionPool.NumConns > 1
+}
+
+/*
+ * The catalog query functions all run on connection 0, so each worker is
+ * given a copy of the connection pool containing only its own connection.
+ */
+func GetConnectionForWorker(connNum int) *dbconn.DBConn {
+	workerConn := *connectionPool
+	workerConn.ConnPool = connectionPool.ConnPool[connNum : connNum+1]
+	workerConn.Tx = connectionPool.Tx[connNum : connNum+1]
+	workerConn.NumConns = 1
+	return &workerConn
+}
+
+/*
+ * Each query stores its results where the caller can read them once this
+ * function returns, so the queries must not depend on one another.  A query
+ * that fails on a worker connection is re-raised here, so that the failure
+ * is handled in the same way as for a serial query.
+ */
+func RunMetadataQueries(queries []func(*dbconn.DBConn)) {
+	if !CanRunMetadataQueriesInParallel() {
+		for _, query := range queries {
+			query(connectionPool)
+		}
+		return
+	}
+	tasks := make(chan func(*dbconn.DBConn), len(queries))
+	for _, query := range queries {
+		tasks <- query
+	}
+	close(tasks)
+	var workerPool sync.WaitGroup
+	var panicMutex sync.Mutex
+	var queryPanic interface{}
+	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
+		workerPool.Add(1)
+		go func(connNum int) {
+			defer workerPool.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panicMutex.Lock()
+					if queryPanic == nil {
+						queryPanic = r
+					}
+					panicMutex.Unlock()
+				}
+			}()
+			workerConn := GetConnectionForWorker(connNum)
+			for query := range tasks {
+				query(workerConn)
+			}
+		}(connNum)
+	}
+	workerPool.Wait()
+	if queryPanic != nil {
+		panic(queryPanic)
+	}
+}
+
+func RetrievePredataObjects(tableOnly bool) PredataObjects {
+	gplog.Verbose("Retrieving pre-data metadata")
+	objects := PredataObjects{}
+	queries := []func(*dbconn.DBConn){
+		func(conn *dbconn.DBConn) { objects.RelationMetadata = GetMetadataForObjectType(conn, TYPE_RELATION) },
+		func(conn *dbconn.DBConn) { objects.FuncInfoMap = GetFunctionOidToInfoMap(conn) },
+	}
+	if !tableOnly {
+		queries = append(queries,
+			func(conn *dbconn.DBConn) { objects.Schemas = GetAllUserSchemas(conn) },
+			func(conn *dbconn.DBConn) { objects.SchemaMetadata = GetMetadataForObjectType(conn, TYPE_SCHEMA) },
+			func(conn *dbconn.DBConn) { objects.ProcLangs = GetProceduralLanguages(conn) },
+			func(conn *dbconn.DBConn) { objects.Functions = GetFunctionsAllVersions(conn) },
+			func(conn *dbconn.DBConn) { objects.FunctionMetadata = GetMetadataForObjectType(conn, TYPE_FUNCTION) },
+			func(conn *dbconn.DBConn) { objects.ShellTypes = GetShellTypes(conn) },
+			func(conn *dbconn.DBConn) { objects.BaseTypes = GetBaseTypes(conn) },
+			func(conn *dbconn.DBConn) { objects.CompositeTypes = GetCompositeTypes(conn) },
+			func(conn *dbconn.DBConn) { objects.DomainTypes = GetDomainTypes(conn) },
+			func(conn *dbconn.DBConn) { objects.TypeMetadata = GetMetadataForObjectType(conn, TYPE_TYPE) },
+			func(conn *dbconn.DBConn) { objects.Protocols = GetExternalProtocols(conn) },
+			func(conn *dbconn.DBConn) { objects.ProtocolMetadata = GetMetadataForObjectType(conn, TYPE_PROTOCOL) },
+			func(conn *dbconn.DBConn) { objects.Operators = GetOperators(conn) },
+			func(conn *dbconn.DBConn) { objects.OperatorMetadata = GetMetadataForObjectType(conn, TYPE_OPERATOR) },
+			func(conn *dbconn.DBConn) { objects.OperatorClasses = GetOperatorClasses(conn) },
+			func(conn *dbconn.DBConn) {
+				objects.OperatorClassMetadata = GetMetadataForObjectType(conn, TYPE_OPERATORCLASS)
+			},
+			func(conn *dbconn.DBConn) { objects.Aggregates = GetAggregates(conn) },
+			func(conn *dbconn.DBConn) { objects.AggregateMetadata = GetMetadataForObjectType(conn, TYPE_AGGREGATE) },
+			func(conn *dbconn.DBConn) { objects.Casts = GetCasts(conn) },
+			func(conn *dbconn.DBConn) { objects.CastMetadata = GetCommentsForObjectType(conn, TYPE_CAST) },
+		)
+		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) {
+					objects.ProcLangMetadata = GetMetadataForObjectType(conn, TYPE_PROCLANGUAGE)
+				},
+			)
+		}
+		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 && connectionPool.Version.AtLeast("5") {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) { objects.Extensions = GetExtensions(conn) },
+				func(conn *dbconn.DBConn) { objects.ExtensionMetadata = GetCommentsForObjectType(conn, TYPE_EXTENSION) },
+			)
+		}
+		if connectionPool.Version.AtLeast("5") {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) { objects.EnumTypes = GetEnumTypes(conn) },
+				func(conn *dbconn.DBConn) { objects.TSParsers = GetTextSearchParsers(conn) },
+				func(conn *dbconn.DBConn) { objects.TSParserMetadata = GetCommentsForObjectType(conn, TYPE_TSPARSER) },
+				func(conn *dbconn.DBConn) { objects.TSConfigurations = GetTextSearchConfigurations(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.TSConfigurationMetadata = GetMetadataForObjectType(conn, TYPE_TSCONFIGURATION)
+				},
+				func(conn *dbconn.DBConn) { objects.TSTemplates = GetTextSearchTemplates(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.TSTemplateMetadata = GetCommentsForObjectType(conn, TYPE_TSTEMPLATE)
+				},
+				func(conn *dbconn.DBConn) { objects.TSDictionaries = GetTextSearchDictionaries(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.TSDictionaryMetadata = GetMetadataForObjectType(conn, TYPE_TSDICTIONARY)
+				},
+				func(conn *dbconn.DBConn) { objects.OperatorFamilies = GetOperatorFamilies(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.OperatorFamilyMetadata = GetMetadataForObjectType(conn, TYPE_OPERATORFAMILY)
+				},
+			)
+		}
+		if connectionPool.Version.AtLeast("6") {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) { objects.Collations = GetCollations(conn) },
+				func(conn *dbconn.DBConn) { objects.CollationMetadata = GetMetadataForObjectType(conn, TYPE_COLLATION) },
+				func(conn *dbconn.DBConn) { objects.RangeTypes = GetRangeTypes(conn) },
+			)
+		}
+		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 && connectionPool.Version.AtLeast("6") {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) { objects.ForeignDataWrappers = GetForeignDataWrappers(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.ForeignDataWrapperMetadata = GetMetadataForObjectType(conn, TYPE_FOREIGNDATAWRAPPER)
+				},
+				func(conn *dbconn.DBConn) { objects.ForeignServers = GetForeignServers(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.ForeignServerMetadata = GetMetadataForObjectType(conn, TYPE_FOREIGNSERVER)
+				},
+				func(conn *dbconn.DBConn) { objects.UserMappings = GetUserMappings(conn) },
+			)
+		}
+	}
+	queries = append(queries,
+		func(conn *dbconn.DBConn) { objects.Views = GetViews(conn) },
+		func(conn *dbconn.DBConn) {
+			sequenceOwnerTables, sequenceOwnerColumns := GetSequenceColumnOwnerMap(conn)
+			objects.Sequences = GetAllSequences(conn, sequenceOwnerTables)
+			objects.SequenceOwnerColumns = sequenceOwnerColumns
+		},
+		func(conn *dbconn.DBConn) { objects.Constraints = GetConstraints(conn) },
+		func(conn *dbconn.DBConn) {
+			objects.ConstraintMetadata = GetCommentsForObjectType(conn, TYPE_CONSTRAINT)
+		},
+		func(conn *dbconn.DBConn) { objects.Conversions = GetConversions(conn) },
+		func(conn *dbconn.DBConn) {
+			objects.ConversionMetadata = GetMetadataForObjectType(conn, TYPE_CONVERSION)
+		},
+		func(conn *dbconn.DBConn) {
+			objects.ExternalPartitions, objects.PartitionInfoMap = GetExternalPartitionInfo(conn)
+		},
+	)
+	RunMetadataQueries(queries)
+	return objects
+}
+
+func RetrievePostdataObjects() PostdataObjects {
+	gplog.Verbose("Retrieving post-data metadata")
+	objects := PostdataObjects{}
+	queries := []func(*dbconn.DBConn){
+		func(conn *dbconn.DBConn) { objects.Indexes = GetIndexes(conn) },
+		func(conn *dbconn.DBConn) { objects.IndexMetadata = GetCommentsForObjectType(conn, TYPE_INDEX) },
+		func(conn *dbconn.DBConn) { objects.Rules = GetRules(conn) },
+		func(conn *dbconn.DBConn) { objects.RuleMetadata = GetCommentsForObjectType(conn, TYPE_RULE) },
+		func(conn *dbconn.DBConn) { objects.Triggers = GetTriggers(conn) },
+		func(conn *dbconn.DBConn) { objects.TriggerMetadata = GetCommentsForObjectType(conn, TYPE_TRIGGER) },
+	}
+	if connectionPool.Version.AtLeast("6") {
+		queries = append(queries,
+			func(conn *dbconn.DBConn) { objects.DefaultPrivileges = GetDefaultPrivileges(conn) },
+		)
+		if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) == 0 {
+			queries = append(queries,
+				func(conn *dbconn.DBConn) { objects.EventTriggers = GetEventTriggers(conn) },
+				func(conn *dbconn.DBConn) {
+					objects.EventTriggerMetadata = GetMetadataForObjectType(conn, TYPE_EVENTTRIGGER)
+				},
+			)
+		}
+	}
+	RunMetadataQueries(queries)
+	return objects
+}
@@ -0,0 +1,155 @@
+package backup_test
+
+import (
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/metadata_retrieval tests", func() {
+	var multiConnPool *dbconn.DBConn
+	BeforeEach(func() {
+		multiConnPool, _ = testhelper.CreateAndConnectMockDB(3)
+		backup.SetConnection(multiConnPool)
+	})
+	AfterEach(func() {
+		backup.SetConnection(connectionPool)
+		backup.SetDataConsistency("")
+	})
+	Describe("CanRunMetadataQueriesInParallel", func() {
+		It("runs queries in parallel when all connections share a synchronized snapshot", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			Expect(backup.CanRunMetadataQueriesInParallel()).To(BeTrue())
+		})
+		It("runs queries serially when connections may read from different snapshots", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_TABLE_LOCKS)
+			Expect(backup.CanRunMetadataQueriesInParallel()).To(BeFalse())
+			backup.SetDataConsistency(backup.CONSISTENCY_PER_CONNECTION)
+			Expect(backup.CanRunMetadataQueriesInParallel()).To(BeFalse())
+		})
+	})
+	Describe("GetConnectionForWorker", func() {
+		It("returns a single-connection pool for the given connection", func() {
+			workerConn := backup.GetConnectionForWorker(2)
+
+			Expect(workerConn.NumConns).To(Equal(1))
+			Expect(workerConn.ConnPool).To(HaveLen(1))
+			Expect(workerConn.ConnPool[0]).To(BeIdenticalTo(multiConnPool.ConnPool[2]))
+			Expect(workerConn.Version).To(Equal(multiConnPool.Version))
+			Expect(multiConnPool.NumConns).To(Equal(3))
+		})
+	})
+	Describe("RunMetadataQueries", func() {
+		It("runs each query in order on the connection pool when running serially", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_PER_CONNECTION)
+			order := make([]int, 0)
+			queries := make([]func(*dbconn.DBConn), 0)
+			for i := 0; i < 5; i++ {
+				i := i
+				queries = append(queries, func(conn *dbconn.DBConn) {
+					Expect(conn).To(BeIdenticalTo(multiConnPool))
+					order = append(order, i)
+				})
+			}
+
+			backup.RunMetadataQueries(queries)
+
+			Expect(order).To(Equal([]int{0, 1, 2, 3, 4}))
+		})
+		It("runs every query on a single worker connection when running in parallel", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			results := make([]int, 10)
+			queries := make([]func(*dbconn.DBConn), 0)
+			for i := range results {
+				i := i
+				queries = append(queries, func(conn *dbconn.DBConn) {
+					if conn.NumConns == 1 {
+						results[i] = i + 1
+					}
+				})
+			}
+
+			backup.RunMetadataQueries(queries)
+
+			Expect(results).To(Equal([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}))
+		})
+		It("re-raises a query failure after all workers finish", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			var mutex sync.Mutex
+			numRun := 0
+			queries := make([]func(*dbconn.DBConn), 0)
+			for i := 0; i < 5; i++ {
+				queries = append(queries, func(conn *dbconn.DBConn) {
+					mutex.Lock()
+					numRun++
+					mutex.Unlock()
+				})
+			}
+			queries = append(queries, func(conn *dbconn.DBConn) { panic("query failed") })
+
+			defer func() {
+				Expect(recover()).To(Equal("query failed"))
+				Expect(numRun).To(Equal(5))
+			}()
+			backup.RunMetadataQueries(queries)
+		})
+		It("runs the role name queries for privileges on the same worker connection as the privileges queries", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			testhelper.SetDBVersion(multiConnPool, "6.0.0")
+			backup.InitializeMetadataParams(multiConnPool)
+			connMocks := make([]sqlmock.Sqlmock, 0)
+			for connNum := 0; connNum < multiConnPool.NumConns; connNum++ {
+				mockdb, connMock := testhelper.CreateMockDB()
+				multiConnPool.ConnPool[connNum] = mockdb
+				connMocks = append(connMocks, connMock)
+				aclRows := sqlmock.NewRows([]string{"classid", "oid", "privileges", "kind", "owner", "comment"}).
+					AddRow(1259, 1, "testrole=r/testrole", "", "testrole", "")
+				connMock.ExpectQuery("SELECT (.*)").WillReturnRows(aclRows)
+				connMock.ExpectQuery("SELECT rolname (.*)").WillReturnRows(sqlmock.NewRows([]string{"rolename", "quotedrolename"}).AddRow("testrole", "testrole"))
+				defaultPrivilegesRows := sqlmock.NewRows([]string{"oid", "owner", "schema", "privileges", "kind", "objecttype"}).
+					AddRow(1, "testrole", "", "testrole=r/testrole", "", "r")
+				connMock.ExpectQuery("SELECT (.*)").WillReturnRows(defaultPrivilegesRows)
+				connMock.ExpectQuery("SELECT rolname (.*)").WillReturnRows(sqlmock.NewRows([]string{"rolename", "quotedrolename"}).AddRow("testrole", "testrole"))
+			}
+
+			/*
+			 * Each query waits until every worker has started one, so that each
+			 * worker runs exactly one query on its own connection.
+			 */
+			var allStarted sync.WaitGroup
+			allStarted.Add(multiConnPool.NumConns)
+			var mutex sync.Mutex
+			metadataMaps := make([]backup.MetadataMap, 0)
+			defaultPrivileges := make([][]backup.DefaultPrivileges, 0)
+			queries := make([]func(*dbconn.DBConn), 0)
+			for i := 0; i < multiConnPool.NumConns; i++ {
+				queries = append(queries, func(conn *dbconn.DBConn) {
+					allStarted.Done()
+					allStarted.Wait()
+					metadataMap := backup.GetMetadataForObjectType(conn, backup.TYPE_RELATION)
+					privileges := backup.GetDefaultPrivileges(conn)
+					mutex.Lock()
+					metadataMaps = append(metadataMaps, metadataMap)
+					defaultPrivileges = append(defaultPrivileges, privileges)
+					mutex.Unlock()
+				})
+			}
+
+			backup.RunMetadataQueries(queries)
+
+			for _, connMock := range connMocks {
+				Expect(connMock.ExpectationsWereMet()).To(Succeed())
+			}
+			for i := range metadataMaps {
+				Expect(metadataMaps[i]).To(HaveLen(1))
+				Expect(defaultPrivileges[i]).To(HaveLen(1))
+			}
+		})
+	})
+})
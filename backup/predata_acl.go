@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gpbackup/utils"
 )
 
@@ -85,7 +86,7 @@ func PrintObjectMetadata(file *utils.FileWithByteCount, toc *utils.TOC, metadata
 	PrintStatements(file, toc, obj, statements)
 }
 
-func ConstructMetadataMap(results []MetadataQueryStruct) MetadataMap {
+func ConstructMetadataMap(connectionPool *dbconn.DBConn, results []MetadataQueryStruct) MetadataMap {
 	metadataMap := make(MetadataMap)
 	if len(results) == 0 {
 		return MetadataMap{}
@@ -218,7 +219,7 @@ func ParseACL(aclStr string, quotedRoleNames map[string]string) *ACL {
 func (obj ObjectMetadata) GetPrivilegesStatements(objectName string, objectType string, columnName ...string) string {
 	statements := []string{}
 	typeStr := fmt.Sprintf("%s ", objectType)
-	if objectType == "VIEW" || objectType == "FOREIGN TABLE" {
+	if objectType == "VIEW" || objectType == "MATERIALIZED VIEW" || objectType == "FOREIGN TABLE" {
 		typeStr = ""
 	} else if objectType == "COLUMN" {
 		typeStr = "TABLE "
@@ -306,7 +307,7 @@ func createPrivilegeStrings(acl ACL, objectType string) (string, string) {
 	case "TYPE":
 		hasAllPrivileges = acl.Usage
 		hasAllPrivilegesWithGrant = acl.UsageWithGrant
-	case "VIEW":
+	case "VIEW", "MATERIALIZED VIEW":
 		hasAllPrivileges = acl.Select && acl.Insert && acl.Update && acl.Delete && acl.Truncate && acl.References && acl.Trigger
 		hasAllPrivilegesWithGrant = acl.SelectWithGrant && acl.InsertWithGrant && acl.UpdateWithGrant && acl.DeleteWithGrant &&
 			acl.TruncateWithGrant && acl.ReferencesWithGrant && acl.TriggerWithGrant
@@ -484,7 +485,7 @@ func PrintDefaultPrivilegesStatements(metadataFile *utils.FileWithByteCount, toc
 	}
 }
 
-func ConstructDefaultPrivileges(results []DefaultPrivilegesQueryStruct) []DefaultPrivileges {
+func ConstructDefaultPrivileges(connectionPool *dbconn.DBConn, results []DefaultPrivilegesQueryStruct) []DefaultPrivileges {
 	if len(results) == 0 {
 		return []DefaultPrivileges{}
 	}
@@ -279,12 +279,12 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 			metadataList = []backup.MetadataQueryStruct{}
 		})
 		It("No objects", func() {
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			Expect(metadataMap).To(BeEmpty())
 		})
 		It("One object", func() {
 			metadataList = []backup.MetadataQueryStruct{object2}
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[backup.UniqueID{Oid: 2}]).To(Equal(expectedObjectMetadata))
@@ -292,14 +292,14 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 		})
 		It("One object with two ACL entries", func() {
 			metadataList = []backup.MetadataQueryStruct{object1A, object1B}
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[backup.UniqueID{Oid: 1}]).To(Equal(expectedObjectMetadata))
 		})
 		It("Multiple objects", func() {
 			metadataList = []backup.MetadataQueryStruct{object1A, object1B, object2}
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			expectedObjectMetadataOne := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole"}
 			expectedObjectMetadataTwo := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Comment: "this is a comment", SecurityLabelProvider: "some_provider", SecurityLabel: "some_label"}
 			Expect(metadataMap).To(HaveLen(2))
@@ -308,14 +308,14 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 		})
 		It("Default Kind", func() {
 			metadataList = []backup.MetadataQueryStruct{objectDefaultKind}
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[backup.UniqueID{Oid: 3}]).To(Equal(expectedObjectMetadata))
 		})
 		It("'Empty' Kind", func() {
 			metadataList = []backup.MetadataQueryStruct{objectEmptyKind}
-			metadataMap := backup.ConstructMetadataMap(metadataList)
+			metadataMap := backup.ConstructMetadataMap(connectionPool, metadataList)
 			expectedObjectMetadata := backup.ObjectMetadata{Privileges: []backup.ACL{{Grantee: "GRANTEE"}}, Owner: "testrole"}
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[backup.UniqueID{Oid: 4}]).To(Equal(expectedObjectMetadata))
@@ -395,26 +395,26 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{}
 		})
 		It("returns no privileges when no default privileges exist", func() {
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			Expect(privilegesList).To(BeEmpty())
 		})
 		It("constructs a single sequence default privilege in a specific schema", func() {
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{object2}
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			expectedDefaultPrivileges := backup.DefaultPrivileges{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Schema: "myschema", ObjectType: "S"}
 			Expect(privilegesList).To(HaveLen(1))
 			Expect(privilegesList[0]).To(Equal(expectedDefaultPrivileges))
 		})
 		It("constructs multiple default privileges on a single relation in a specific schema", func() {
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{object1A, object1B}
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			expectedObjectMetadata := backup.DefaultPrivileges{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole", Schema: "myschema", ObjectType: "r"}
 			Expect(privilegesList).To(HaveLen(1))
 			Expect(privilegesList[0]).To(Equal(expectedObjectMetadata))
 		})
 		It("constructs multiple default privileges on multiple objects in a specific schema", func() {
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{object1A, object1B, object2}
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			expectedObjectMetadataOne := backup.DefaultPrivileges{Privileges: []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}, Owner: "testrole", Schema: "myschema", ObjectType: "r"}
 			expectedObjectMetadataTwo := backup.DefaultPrivileges{Privileges: []backup.ACL{{Grantee: "testrole", Select: true}}, Owner: "testrole", Schema: "myschema", ObjectType: "S"}
 			Expect(privilegesList).To(HaveLen(2))
@@ -423,14 +423,14 @@ ALTER DEFAULT PRIVILEGES FOR ROLE testrole GRANT USAGE ON TABLES TO somerole WIT
 		})
 		It("constructs a default privilege for a type with a 'Default' kind", func() {
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{objectDefaultKind}
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			expectedObjectMetadata := backup.DefaultPrivileges{Privileges: []backup.ACL{}, Owner: "testrole", Schema: "", ObjectType: "T"}
 			Expect(privilegesList).To(HaveLen(1))
 			Expect(privilegesList[0]).To(Equal(expectedObjectMetadata))
 		})
 		It("constructs a default privilege for a function with an 'Empty' kind", func() {
 			privilegesQuerylist = []backup.DefaultPrivilegesQueryStruct{objectEmptyKind}
-			privilegesList := backup.ConstructDefaultPrivileges(privilegesQuerylist)
+			privilegesList := backup.ConstructDefaultPrivileges(connectionPool, privilegesQuerylist)
 			expectedObjectMetadata := backup.DefaultPrivileges{Privileges: []backup.ACL{{Grantee: "GRANTEE"}}, Owner: "testrole", Schema: "", ObjectType: "f"}
 			Expect(privilegesList).To(HaveLen(1))
 			Expect(privilegesList[0]).To(Equal(expectedObjectMetadata))
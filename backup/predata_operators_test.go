@@ -24,7 +24,7 @@ var _ = Describe("backup/predata_operators tests", func() {
 		It("prints a basic operator", func() {
 			backup.PrintCreateOperatorStatement(backupfile, toc, operator, emptyMetadata)
 
-			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "##", "OPERATOR")
+			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", `## (public.path, public."PATH")`, "OPERATOR")
 			testutils.AssertBufferContents(toc.PredataEntries, buffer, `CREATE OPERATOR public.## (
 	PROCEDURE = public.path_inter,
 	LEFTARG = public.path,
@@ -79,7 +79,7 @@ var _ = Describe("backup/predata_operators tests", func() {
 
 			backup.PrintCreateOperatorFamilyStatements(backupfile, toc, []backup.OperatorFamily{operatorFamily}, backup.MetadataMap{})
 
-			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "testfam", "OPERATOR FAMILY")
+			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "testfam USING hash", "OPERATOR FAMILY")
 			testutils.AssertBufferContents(toc.PredataEntries, buffer, `CREATE OPERATOR FAMILY public.testfam USING hash;`)
 		})
 		It("prints an operator family with an owner and comment", func() {
@@ -110,7 +110,7 @@ var _ = Describe("backup/predata_operators tests", func() {
 		It("prints a basic operator class", func() {
 			backup.PrintCreateOperatorClassStatement(backupfile, toc, operatorClass, emptyMetadata)
 
-			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "testclass", "OPERATOR CLASS")
+			testutils.ExpectEntry(toc.PredataEntries, 0, "public", "", "testclass USING hash", "OPERATOR CLASS")
 			testutils.AssertBufferContents(toc.PredataEntries, buffer, `CREATE OPERATOR CLASS public.testclass
 	FOR TYPE uuid USING hash AS
 	STORAGE uuid;`)
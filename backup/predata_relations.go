@@ -11,6 +11,7 @@ import (
 
 	"math"
 
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/utils"
 )
@@ -95,7 +96,7 @@ func ExpandIncludeRelations(tables []Relation) {
 	}
 }
 
-func ConstructColumnPrivilegesMap(results []ColumnPrivilegesQueryStruct) map[uint32]map[string][]ACL {
+func ConstructColumnPrivilegesMap(connectionPool *dbconn.DBConn, results []ColumnPrivilegesQueryStruct) map[uint32]map[string][]ACL {
 	metadataMap := make(map[uint32]map[string][]ACL)
 	var tableMetadata map[string][]ACL
 	var columnMetadata []ACL
@@ -326,7 +327,7 @@ func PrintAlterSequenceStatements(metadataFile *utils.FileWithByteCount, toc *ut
 			start := metadataFile.ByteCount
 			metadataFile.MustPrintf("\n\nALTER SEQUENCE %s OWNED BY %s;\n", seqFQN, owningColumn)
 			//TODO: see if the SEQUENCE OWNER type is being utilized in restore or if it could be SEQUENCE. I think we should be using it for filtering, but aren't
-			entry := utils.MetadataEntry{sequence.Relation.Schema, sequence.Relation.Name, "SEQUENCE OWNER", sequence.OwningTable, 0, 0}
+			entry := utils.MetadataEntry{sequence.Relation.Schema, sequence.Relation.Name, "SEQUENCE OWNER", sequence.OwningTable, 0, 0, ""}
 			toc.AddMetadataEntry("predata", entry, start, metadataFile.ByteCount)
 		}
 	}
@@ -334,7 +335,30 @@ func PrintAlterSequenceStatements(metadataFile *utils.FileWithByteCount, toc *ut
 
 func PrintCreateViewStatement(metadataFile *utils.FileWithByteCount, toc *utils.TOC, view View, viewMetadata ObjectMetadata) {
 	start := metadataFile.ByteCount
-	metadataFile.MustPrintf("\n\nCREATE VIEW %s%s AS %s\n", view.FQN(), view.Options, view.Definition)
+	if view.IsMaterialized {
+		/*
+		 * Materialized views are created empty, as the tables they select from
+		 * have not been restored yet, and are refreshed by gprestore after the
+		 * data and post-data metadata have been restored.  Those that were not
+		 * populated in the source database are recorded in the TOC so that
+		 * gprestore leaves them empty.
+		 */
+		tablespaceStr := ""
+		if view.Tablespace != "" {
+			tablespaceStr = fmt.Sprintf(" TABLESPACE %s", view.Tablespace)
+		}
+		distPolicyStr := ""
+		if view.DistPolicy != "" {
+			distPolicyStr = fmt.Sprintf("\n%s", view.DistPolicy)
+		}
+		metadataFile.MustPrintf("\n\nCREATE MATERIALIZED VIEW %s%s%s AS %s\nWITH NO DATA%s;\n",
+			view.FQN(), view.Options, tablespaceStr, strings.TrimSuffix(strings.TrimSpace(view.Definition), ";"), distPolicyStr)
+		if !view.IsPopulated {
+			toc.UnpopulatedMatviews = append(toc.UnpopulatedMatviews, view.FQN())
+		}
+	} else {
+		metadataFile.MustPrintf("\n\nCREATE VIEW %s%s AS %s\n", view.FQN(), view.Options, view.Definition)
+	}
 
 	section, entry := view.GetMetadataEntry()
 	toc.AddMetadataEntry(section, entry, start, metadataFile.ByteCount)
@@ -251,6 +251,50 @@ GRANT ALL ON shamwow.shazam TO testrole;`,
 			testutils.AssertBufferContents(toc.PredataEntries, buffer,
 				`CREATE VIEW shamwow.shazam WITH (security_barrier=true) AS SELECT count(*) FROM pg_tables;`)
 		})
+		It("can print a basic materialized view", func() {
+			view.IsMaterialized = true
+			view.IsPopulated = true
+			backup.PrintCreateViewStatement(backupfile, toc, view, emptyMetadata)
+			testutils.ExpectEntry(toc.PredataEntries, 0, "shamwow", "", "shazam", "MATERIALIZED VIEW")
+			testutils.AssertBufferContents(toc.PredataEntries, buffer,
+				`CREATE MATERIALIZED VIEW shamwow.shazam AS SELECT count(*) FROM pg_tables
+WITH NO DATA;`)
+			Expect(toc.UnpopulatedMatviews).To(BeEmpty())
+		})
+		It("records a materialized view that was not populated in the TOC", func() {
+			view.IsMaterialized = true
+			backup.PrintCreateViewStatement(backupfile, toc, view, emptyMetadata)
+			Expect(toc.UnpopulatedMatviews).To(Equal([]string{"shamwow.shazam"}))
+		})
+		It("can print a materialized view with options, a tablespace, and a distribution policy", func() {
+			view.IsMaterialized = true
+			view.Options = " WITH (fillfactor=10)"
+			view.Tablespace = "test_tablespace"
+			view.DistPolicy = "DISTRIBUTED BY (tablename)"
+			backup.PrintCreateViewStatement(backupfile, toc, view, emptyMetadata)
+			testutils.ExpectEntry(toc.PredataEntries, 0, "shamwow", "", "shazam", "MATERIALIZED VIEW")
+			testutils.AssertBufferContents(toc.PredataEntries, buffer,
+				`CREATE MATERIALIZED VIEW shamwow.shazam WITH (fillfactor=10) TABLESPACE test_tablespace AS SELECT count(*) FROM pg_tables
+WITH NO DATA
+DISTRIBUTED BY (tablename);`)
+		})
+		It("can print a materialized view with privileges, an owner, security label, and a comment", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			defer testhelper.SetDBVersion(connectionPool, "5.1.0")
+
+			view.IsMaterialized = true
+			viewMetadata := testutils.DefaultMetadata("MATERIALIZED VIEW", true, true, true, true)
+			backup.PrintCreateViewStatement(backupfile, toc, view, viewMetadata)
+			expectedEntries := []string{`CREATE MATERIALIZED VIEW shamwow.shazam AS SELECT count(*) FROM pg_tables
+WITH NO DATA;`,
+				"COMMENT ON MATERIALIZED VIEW shamwow.shazam IS 'This is a materialized view comment.';",
+				"ALTER MATERIALIZED VIEW shamwow.shazam OWNER TO testrole;",
+				`REVOKE ALL ON shamwow.shazam FROM PUBLIC;
+REVOKE ALL ON shamwow.shazam FROM testrole;
+GRANT ALL ON shamwow.shazam TO testrole;`,
+				"SECURITY LABEL FOR dummy ON MATERIALIZED VIEW shamwow.shazam IS 'unclassified';"}
+			testutils.AssertBufferContents(toc.PredataEntries, buffer, expectedEntries...)
+		})
 	})
 	Describe("PrintAlterSequenceStatements", func() {
 		baseSequence := backup.Relation{Schema: "public", Name: "seq_name"}
@@ -522,19 +566,19 @@ GRANT ALL ON shamwow.shazam TO testrole;`,
 			privileges = []backup.ColumnPrivilegesQueryStruct{}
 		})
 		It("No columns", func() {
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 			Expect(metadataMap).To(BeEmpty())
 		})
 		It("One column", func() {
 			privileges = []backup.ColumnPrivilegesQueryStruct{colI}
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[1]).To(HaveLen(1))
 			Expect(metadataMap[1]["i"]).To(Equal(expectedACL))
 		})
 		It("Multiple columns on same table", func() {
 			privileges = []backup.ColumnPrivilegesQueryStruct{colI, colJ}
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 			Expect(metadataMap).To(HaveLen(1))
 			Expect(metadataMap[1]).To(HaveLen(2))
 			Expect(metadataMap[1]["i"]).To(Equal(expectedACL))
@@ -542,7 +586,7 @@ GRANT ALL ON shamwow.shazam TO testrole;`,
 		})
 		It("Multiple columns on multiple tables", func() {
 			privileges = []backup.ColumnPrivilegesQueryStruct{colI, colJ, colK1, colK2}
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 
 			expectedACLForK := []backup.ACL{{Grantee: "gpadmin", Select: true}, {Grantee: "testrole", Select: true}}
 
@@ -555,7 +599,7 @@ GRANT ALL ON shamwow.shazam TO testrole;`,
 		})
 		It("Default kind", func() {
 			privileges = []backup.ColumnPrivilegesQueryStruct{colDefault}
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 
 			expectedACLForDefaultKind := []backup.ACL{}
 
@@ -565,7 +609,7 @@ GRANT ALL ON shamwow.shazam TO testrole;`,
 		})
 		It("'Empty' kind", func() {
 			privileges = []backup.ColumnPrivilegesQueryStruct{colEmpty}
-			metadataMap := backup.ConstructColumnPrivilegesMap(privileges)
+			metadataMap := backup.ConstructColumnPrivilegesMap(connectionPool, privileges)
 
 			expectedACLForEmptyKind := []backup.ACL{{Grantee: "GRANTEE"}}
 
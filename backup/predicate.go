@@ -0,0 +1,89 @@
+package backup
+
+/*
+ * This file contains functions related to backing up only the rows of a table
+ * that match a predicate given in the --table-predicate-file.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+ * The predicate file is a YAML map from fully-qualified table names to the
+ * SQL predicates used to select the rows to back up from each table, e.g.
+ *
+ *   public.events: created_at > now() - interval '90 days'
+ */
+func ReadTablePredicateFile(filename string) (map[string]string, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	predicates := make(map[string]string, 0)
+	err = yaml.Unmarshal(contents, &predicates)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("Unable to parse table predicate file %s", filename))
+	}
+	if len(predicates) == 0 {
+		return nil, errors.Errorf("Table predicate file %s does not contain any predicates", filename)
+	}
+	for table, predicate := range predicates {
+		if strings.TrimSpace(predicate) == "" {
+			return nil, errors.Errorf("The predicate for table %s in table predicate file %s is empty", table, filename)
+		}
+	}
+	return predicates, nil
+}
+
+/*
+ * Each predicate is checked by planning a query that returns no rows, so that
+ * a typo in the predicate file fails the backup before any data is copied.
+ */
+func ValidateTablePredicates(connectionPool *dbconn.DBConn, predicates map[string]string) {
+	tables := GetTablePredicateTables(predicates)
+	ValidateFilterTables(connectionPool, tables, false)
+	for _, table := range tables {
+		_, err := connectionPool.Exec(fmt.Sprintf("SELECT * FROM %s WHERE (%s) LIMIT 0", table, predicates[table]))
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Invalid predicate for table %s: %s", table, err.Error()), "")
+		}
+	}
+}
+
+/*
+ * With --leaf-partition-data, the data of a partition table is backed up from
+ * its leaf partitions, so the predicates must be given for those instead.
+ */
+func ValidateTablePredicatesInBackupSet(tables []Table) {
+	tableSet := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		tableSet[table.FQN()] = true
+	}
+	for _, table := range GetTablePredicateTables(tablePredicates) {
+		if !tableSet[table] {
+			gplog.Fatal(errors.Errorf("Table %s in the table predicate file does not have its data backed up by this backup", table), "")
+		}
+	}
+}
+
+func GetTablePredicateTables(predicates map[string]string) []string {
+	tables := make([]string, 0, len(predicates))
+	for table := range predicates {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+func GetTablePredicate(table Table) string {
+	return tablePredicates[table.FQN()]
+}
@@ -0,0 +1,72 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/predicate tests", func() {
+	AfterEach(func() {
+		backup.SetTablePredicates(nil)
+	})
+	Describe("ReadTablePredicateFile", func() {
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("reads a map of tables to predicates", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) {
+				return []byte(`public.events: created_at > now() - interval '90 days'
+public."Logs": level = 'ERROR'
+`), nil
+			}
+
+			predicates, err := backup.ReadTablePredicateFile("/tmp/predicates.yaml")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(predicates).To(Equal(map[string]string{
+				"public.events": "created_at > now() - interval '90 days'",
+				`public."Logs"`: "level = 'ERROR'",
+			}))
+		})
+		It("returns an error if the file is not a map of tables to predicates", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) { return []byte("- public.events"), nil }
+
+			_, err := backup.ReadTablePredicateFile("/tmp/predicates.yaml")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Unable to parse table predicate file /tmp/predicates.yaml"))
+		})
+		It("returns an error if the file contains no predicates", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) { return []byte(""), nil }
+
+			_, err := backup.ReadTablePredicateFile("/tmp/predicates.yaml")
+
+			Expect(err).To(MatchError("Table predicate file /tmp/predicates.yaml does not contain any predicates"))
+		})
+		It("returns an error if a predicate is empty", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) { return []byte("public.events: ''"), nil }
+
+			_, err := backup.ReadTablePredicateFile("/tmp/predicates.yaml")
+
+			Expect(err).To(MatchError("The predicate for table public.events in table predicate file /tmp/predicates.yaml is empty"))
+		})
+	})
+	Describe("ValidateTablePredicatesInBackupSet", func() {
+		events := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "events"}}
+		It("does nothing if every table with a predicate is backed up", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5"})
+
+			backup.ValidateTablePredicatesInBackupSet([]backup.Table{events})
+		})
+		It("panics if a table with a predicate is not backed up", func() {
+			backup.SetTablePredicates(map[string]string{"public.events": "i > 5", "public.partitioned": "i > 5"})
+
+			defer testhelper.ShouldPanicWithMessage("Table public.partitioned in the table predicate file does not have its data backed up by this backup")
+			backup.ValidateTablePredicatesInBackupSet([]backup.Table{events})
+		})
+	})
+})
@@ -176,7 +176,7 @@ ORDER BY o.oid;
 	results := make([]MetadataQueryStruct, 0)
 	err := connectionPool.Select(&results, query)
 	gplog.FatalOnError(err)
-	return ConstructMetadataMap(results)
+	return ConstructMetadataMap(connectionPool, results)
 }
 
 func sortACLs(privileges []ACL) []ACL {
@@ -277,7 +277,7 @@ ORDER BY n.nspname, a.defaclobjtype, r.rolname`
 	err := connectionPool.Select(&results, query)
 	gplog.FatalOnError(err)
 
-	return ConstructDefaultPrivileges(results)
+	return ConstructDefaultPrivileges(connectionPool, results)
 }
 
 func GetQuotedRoleNames(connectionPool *dbconn.DBConn) map[string]string {
@@ -235,3 +235,20 @@ FROM (
 	return extPartitions, partInfoMap
 
 }
+
+/*
+ * Returns the FQNs of the partition tables that have at least one external
+ * leaf partition.
+ */
+func GetPartitionTablesWithExternalPartitions(connectionPool *dbconn.DBConn) []string {
+	query := `
+SELECT DISTINCT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS string
+FROM pg_partition p
+JOIN pg_partition_rule r ON r.paroid = p.oid
+JOIN pg_exttable e ON e.reloid = r.parchildrelid
+JOIN pg_class c ON c.oid = p.parrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE p.paristemplate = false
+ORDER BY string`
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
@@ -108,6 +108,16 @@ WHERE d.datname = '%s';`, lcQuery, utils.EscapeSingleQuotes(connectionPool.DBNam
 	return result
 }
 
+func GetDatabaseNames(connectionPool *dbconn.DBConn) []string {
+	query := `
+SELECT datname AS string
+FROM pg_database
+WHERE datallowconn
+AND NOT datistemplate
+ORDER BY datname;`
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
 func GetDatabaseGUCs(connectionPool *dbconn.DBConn) []string {
 	//We do not want to quote list type config settings such as search_path and DateStyle
 	query := `
@@ -2,6 +2,9 @@ package backup
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
@@ -12,7 +15,7 @@ func GetAOIncrementalMetadata(connectionPool *dbconn.DBConn) map[string]utils.AO
 	gplog.Verbose("Querying table row mod counts")
 	var modCounts = getAllModCounts(connectionPool)
 	gplog.Verbose("Querying last DDL modification timestamp for tables")
-	var lastDDLTimestamps = getLastDDLTimestamps(connectionPool)
+	var lastDDLTimestamps = getLastDDLTimestamps(connectionPool, "'ao', 'co'")
 	aoTableEntries := make(map[string]utils.AOEntry)
 	for aoTableFQN := range modCounts {
 		aoTableEntries[aoTableFQN] = utils.AOEntry{
@@ -24,12 +27,101 @@ func GetAOIncrementalMetadata(connectionPool *dbconn.DBConn) map[string]utils.AO
 	return aoTableEntries
 }
 
+/*
+ * Heap tables have no equivalent of the AO modcount, so changes to their data
+ * are detected by comparing a checksum of their contents on each segment.  As
+ * this reads every heap table in full, the checksums are computed in parallel
+ * on all connections.
+ */
+func GetHeapIncrementalMetadata(connectionPool *dbconn.DBConn, tables []Table, aoTableEntries map[string]utils.AOEntry) map[string]utils.HeapEntry {
+	gplog.Verbose("Querying last DDL modification timestamp for heap tables")
+	var lastDDLTimestamps = getLastDDLTimestamps(connectionPool, "'h'")
+	heapTableEntries := make(map[string]utils.HeapEntry)
+	tasks := make(chan Table, len(tables))
+	for _, table := range tables {
+		if _, isAOTable := aoTableEntries[table.FQN()]; isAOTable || table.SkipDataBackup() {
+			continue
+		}
+		tasks <- table
+	}
+	close(tasks)
+
+	var mutex sync.Mutex
+	var workerPool sync.WaitGroup
+	var queryErr error
+	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
+		workerPool.Add(1)
+		go func(whichConn int) {
+			defer workerPool.Done()
+			for table := range tasks {
+				gplog.Verbose("Computing content checksum for heap table %s", table.FQN())
+				fingerprints, err := utils.GetTableFingerprints(connectionPool, table.FQN(), globalCluster.ContentIDs, whichConn)
+				mutex.Lock()
+				if err != nil {
+					queryErr = err
+				} else {
+					heapTableEntries[table.FQN()] = utils.HeapEntry{
+						LastDDLTimestamp: lastDDLTimestamps[table.FQN()],
+						Fingerprints:     fingerprints,
+					}
+				}
+				mutex.Unlock()
+			}
+		}(connNum)
+	}
+	workerPool.Wait()
+	gplog.FatalOnError(queryErr)
+
+	return heapTableEntries
+}
+
+/*
+ * The modcounts for many AO tables are queried at once, with one query per
+ * batch of aoseg tables, and the batches are spread across the connection pool.
+ */
+const aosegTablesPerModCountQuery = 1000
+
 func getAllModCounts(connectionPool *dbconn.DBConn) map[string]int64 {
 	var segTableFQNs = getAOSegTableFQNs(connectionPool)
-	modCounts := make(map[string]int64)
-	for aoTableFQN, segTableFQN := range segTableFQNs {
-		modCounts[aoTableFQN] = getModCount(connectionPool, segTableFQN)
+	aoTableFQNs := make([]string, 0, len(segTableFQNs))
+	for aoTableFQN := range segTableFQNs {
+		aoTableFQNs = append(aoTableFQNs, aoTableFQN)
+	}
+	sort.Strings(aoTableFQNs)
+
+	batches := make(chan []string, len(aoTableFQNs)/aosegTablesPerModCountQuery+1)
+	for start := 0; start < len(aoTableFQNs); start += aosegTablesPerModCountQuery {
+		end := start + aosegTablesPerModCountQuery
+		if end > len(aoTableFQNs) {
+			end = len(aoTableFQNs)
+		}
+		batches <- aoTableFQNs[start:end]
+	}
+	close(batches)
+
+	modCounts := make(map[string]int64, len(aoTableFQNs))
+	var mutex sync.Mutex
+	var workerPool sync.WaitGroup
+	var queryErr error
+	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
+		workerPool.Add(1)
+		go func(whichConn int) {
+			defer workerPool.Done()
+			for batch := range batches {
+				batchModCounts, err := getModCounts(connectionPool, batch, segTableFQNs, whichConn)
+				mutex.Lock()
+				if err != nil {
+					queryErr = err
+				}
+				for aoTableFQN, modCount := range batchModCounts {
+					modCounts[aoTableFQN] = modCount
+				}
+				mutex.Unlock()
+			}
+		}(connNum)
 	}
+	workerPool.Wait()
+	gplog.FatalOnError(queryErr)
 	return modCounts
 }
 
@@ -68,7 +160,7 @@ func getAOSegTableFQNs(connectionPool *dbconn.DBConn) map[string]string {
 		) seg
 	ON
 		aoseg_c.oid = seg.segrelid
-`, relationAndSchemaFilterClause())
+`, relationAndSchemaFilterClause(connectionPool))
 	results := make([]struct {
 		AOTableFQN    string
 		AOSegTableFQN string
@@ -82,34 +174,41 @@ func getAOSegTableFQNs(connectionPool *dbconn.DBConn) map[string]string {
 	return resultMap
 }
 
-func getModCount(connectionPool *dbconn.DBConn, aosegtablefqn string) int64 {
-	query := fmt.Sprintf(`
-	SELECT modcount FROM %s
-`, aosegtablefqn)
+func getModCounts(connectionPool *dbconn.DBConn, aoTableFQNs []string, segTableFQNs map[string]string, whichConn int) (map[string]int64, error) {
+	modCountQueries := make([]string, 0, len(aoTableFQNs))
+	for _, aoTableFQN := range aoTableFQNs {
+		modCountQueries = append(modCountQueries, fmt.Sprintf(`
+	SELECT '%s' AS aotablefqn, coalesce(sum(modcount), 0)::bigint AS modcount FROM %s`, utils.EscapeSingleQuotes(aoTableFQN), segTableFQNs[aoTableFQN]))
+	}
+	query := strings.Join(modCountQueries, `
+	UNION ALL`)
 
 	var results []struct {
-		Modcount int64
+		AOTableFQN string
+		Modcount   int64
 	}
-	err := connectionPool.Select(&results, query)
-	gplog.FatalOnError(err)
-
-	if len(results) == 0 {
-		return 0
+	err := connectionPool.Select(&results, query, whichConn)
+	if err != nil {
+		return nil, err
+	}
+	resultMap := make(map[string]int64, len(results))
+	for _, result := range results {
+		resultMap[result.AOTableFQN] = result.Modcount
 	}
-	return results[0].Modcount
+	return resultMap, nil
 }
 
-func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
+func getLastDDLTimestamps(connectionPool *dbconn.DBConn, relStorageTypes string) map[string]string {
 	query := fmt.Sprintf(`
 	SELECT
-		quote_ident(aoschema) || '.' || quote_ident(aorelname) as aotablefqn,
+		quote_ident(tabschema) || '.' || quote_ident(tabname) as tablefqn,
 		lastddltimestamp
 	FROM
 		(
 			SELECT
-				c.oid AS aooid,
-				n.nspname AS aoschema,
-				c.relname AS aorelname
+				c.oid AS taboid,
+				n.nspname AS tabschema,
+				c.relname AS tabname
 			FROM
 				pg_class c
 			JOIN
@@ -117,10 +216,10 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 			ON
 				c.relnamespace = n.oid
 			WHERE
-				c.relstorage IN ('ao', 'co')
+				c.relstorage IN (%s)
 			AND
 				%s
-		) aotables
+		) tabs
 	JOIN
 		(
 			SELECT
@@ -134,18 +233,18 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 				lo.objid
 		) lastop
 	ON
-		aotables.aooid = lastop.objid
-`, relationAndSchemaFilterClause())
+		tabs.taboid = lastop.objid
+`, relStorageTypes, relationAndSchemaFilterClause(connectionPool))
 
 	var results []struct {
-		AOTableFQN       string
+		TableFQN         string
 		LastDDLTimestamp string
 	}
 	err := connectionPool.Select(&results, query)
 	gplog.FatalOnError(err)
 	resultMap := make(map[string]string)
 	for _, result := range results {
-		resultMap[result.AOTableFQN] = result.LastDDLTimestamp
+		resultMap[result.TableFQN] = result.LastDDLTimestamp
 	}
 	return resultMap
 }
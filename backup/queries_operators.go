@@ -32,7 +32,7 @@ func (o Operator) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return "predata",
 		utils.MetadataEntry{
 			Schema:          o.Schema,
-			Name:            o.Name,
+			Name:            o.nameWithArgs(),
 			ObjectType:      "OPERATOR",
 			ReferenceObject: "",
 			StartByte:       0,
@@ -44,7 +44,11 @@ func (o Operator) GetUniqueID() UniqueID {
 	return UniqueID{ClassID: PG_OPERATOR_OID, Oid: o.Oid}
 }
 
-func (o Operator) FQN() string {
+/*
+ * Operators may be overloaded, so the argument types are needed to identify
+ * an operator, in the same way as the argument list for a function.
+ */
+func (o Operator) nameWithArgs() string {
 	leftArg := "NONE"
 	rightArg := "NONE"
 	if o.LeftArgType != "-" {
@@ -53,7 +57,11 @@ func (o Operator) FQN() string {
 	if o.RightArgType != "-" {
 		rightArg = o.RightArgType
 	}
-	return fmt.Sprintf("%s.%s (%s, %s)", o.Schema, o.Name, leftArg, rightArg)
+	return fmt.Sprintf("%s (%s, %s)", o.Name, leftArg, rightArg)
+}
+
+func (o Operator) FQN() string {
+	return fmt.Sprintf("%s.%s", o.Schema, o.nameWithArgs())
 }
 
 func GetOperators(connectionPool *dbconn.DBConn) []Operator {
@@ -120,7 +128,7 @@ func (opf OperatorFamily) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return "predata",
 		utils.MetadataEntry{
 			Schema:          opf.Schema,
-			Name:            opf.Name,
+			Name:            fmt.Sprintf("%s USING %s", opf.Name, opf.IndexMethod),
 			ObjectType:      "OPERATOR FAMILY",
 			ReferenceObject: "",
 			StartByte:       0,
@@ -171,7 +179,7 @@ func (opc OperatorClass) GetMetadataEntry() (string, utils.MetadataEntry) {
 	return "predata",
 		utils.MetadataEntry{
 			Schema:          opc.Schema,
-			Name:            opc.Name,
+			Name:            fmt.Sprintf("%s USING %s", opc.Name, opc.IndexMethod),
 			ObjectType:      "OPERATOR CLASS",
 			ReferenceObject: "",
 			StartByte:       0,
@@ -117,7 +117,7 @@ WHERE %s
 AND i.indisvalid
 AND n.nspname || '.' || c.relname NOT IN (SELECT partitionschemaname || '.' || partitiontablename FROM pg_partitions)
 AND %s
-ORDER BY name;`, implicitIndexStr, relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+ORDER BY name;`, implicitIndexStr, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c"))
 
 		err := connectionPool.Select(&resultIndexes, query)
 		gplog.FatalOnError(err)
@@ -154,7 +154,7 @@ AND i.indisready
 AND i.indisprimary = 'f'
 AND n.nspname || '.' || c.relname NOT IN (SELECT partitionschemaname || '.' || partitiontablename FROM pg_partitions)
 AND %s
-ORDER BY name;`, relationAndSchemaFilterClause(), ExtensionFilterClause("c")) // The index itself does not have a dependency on the extension, but the index's table does
+ORDER BY name;`, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c")) // The index itself does not have a dependency on the extension, but the index's table does
 		err := connectionPool.Select(&resultIndexes, query)
 		gplog.FatalOnError(err)
 	}
@@ -212,7 +212,7 @@ WHERE %s
 AND rulename NOT LIKE '%%RETURN'
 AND rulename NOT LIKE 'pg_%%'
 AND %s
-ORDER BY rulename;`, relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+ORDER BY rulename;`, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c"))
 
 	results := make([]RuleDefinition, 0)
 	err := connectionPool.Select(&results, query)
@@ -264,7 +264,7 @@ WHERE %s
 AND tgname NOT LIKE 'pg_%%'
 AND %s
 AND %s
-ORDER BY tgname;`, relationAndSchemaFilterClause(), constraintClause, ExtensionFilterClause("c"))
+ORDER BY tgname;`, relationAndSchemaFilterClause(connectionPool), constraintClause, ExtensionFilterClause("c"))
 
 	results := make([]TriggerDefinition, 0)
 	err := connectionPool.Select(&results, query)
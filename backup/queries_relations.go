@@ -14,14 +14,12 @@ import (
 	"github.com/greenplum-db/gpbackup/utils"
 )
 
-func relationAndSchemaFilterClause() string {
-	filterClause := SchemaFilterClause("n")
-	if len(MustGetFlagStringSlice(utils.EXCLUDE_RELATION)) > 0 {
-		excludeOids := GetOidsFromRelationList(connectionPool, MustGetFlagStringSlice(utils.EXCLUDE_RELATION))
-		if len(excludeOids) > 0 {
-			filterClause += fmt.Sprintf("\nAND c.oid NOT IN (%s)", strings.Join(excludeOids, ", "))
-		}
-	}
+/*
+ * The schema and relation filters are combined with AND, so that an excluded
+ * schema or table is left out even if it is also included.
+ */
+func relationAndSchemaFilterClause(connectionPool *dbconn.DBConn) string {
+	filterClause := SchemaFilterClause("n") + excludeRelationFilterClause(connectionPool)
 	if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION)) > 0 {
 		includeOids := GetOidsFromRelationList(connectionPool, MustGetFlagStringSlice(utils.INCLUDE_RELATION))
 		filterClause += fmt.Sprintf("\nAND c.oid IN (%s)", strings.Join(includeOids, ", "))
@@ -29,6 +27,30 @@ func relationAndSchemaFilterClause() string {
 	return filterClause
 }
 
+/*
+ * Excluding a partition table also excludes its leaf partitions, which are
+ * backed up as separate tables with --leaf-partition-data.
+ */
+func excludeRelationFilterClause(connectionPool *dbconn.DBConn) string {
+	if len(MustGetFlagStringSlice(utils.EXCLUDE_RELATION)) == 0 {
+		return ""
+	}
+	excludeOids := GetOidsFromRelationList(connectionPool, MustGetFlagStringSlice(utils.EXCLUDE_RELATION))
+	if len(excludeOids) == 0 {
+		return ""
+	}
+	oidStr := strings.Join(excludeOids, ", ")
+	return fmt.Sprintf(`
+AND c.oid NOT IN (%s)
+AND c.oid NOT IN (
+	SELECT
+		r.parchildrelid
+	FROM pg_partition p
+	JOIN pg_partition_rule r ON p.oid = r.paroid
+	WHERE p.paristemplate = false
+	AND p.parrelid IN (%s))`, oidStr, oidStr)
+}
+
 func GetOidsFromRelationList(connectionPool *dbconn.DBConn, relationNames []string) []string {
 	relList := utils.SliceToQuotedString(relationNames)
 	query := fmt.Sprintf(`
@@ -40,6 +62,42 @@ WHERE quote_ident(n.nspname) || '.' || quote_ident(c.relname) IN (%s)`, relList)
 	return dbconn.MustSelectStringSlice(connectionPool, query)
 }
 
+/*
+ * The names against which --include-schema-pattern and --exclude-schema-pattern
+ * are matched, in the same form as the names passed to --include-schema.
+ */
+func GetSchemaNamesForPatternFiltering(connectionPool *dbconn.DBConn) []string {
+	query := fmt.Sprintf(`
+SELECT
+	n.nspname AS string
+FROM pg_namespace n
+WHERE %s
+ORDER BY n.nspname`, SchemaFilterClause("n"))
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
+/*
+ * The names against which --include-table-pattern and --exclude-table-pattern
+ * are matched, in the same form as the names passed to --include-table.  Leaf
+ * and intermediate partitions are left out, so that a pattern matching a
+ * partition table's name filters on the whole table as it would with
+ * --include-table.
+ */
+func GetTableNamesForPatternFiltering(connectionPool *dbconn.DBConn) []string {
+	query := fmt.Sprintf(`
+SELECT
+	quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS string
+FROM pg_class c
+JOIN pg_namespace n
+	ON c.relnamespace = n.oid
+WHERE %s
+AND relkind = 'r'
+AND c.oid NOT IN (SELECT parchildrelid FROM pg_partition_rule)
+AND %s
+ORDER BY n.nspname, c.relname`, SchemaFilterClause("n"), ExtensionFilterClause("c"))
+	return dbconn.MustSelectStringSlice(connectionPool, query)
+}
+
 func GetAllUserTableRelations(connectionPool *dbconn.DBConn) []Relation {
 	if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION)) > 0 {
 		return GetUserTableRelationsWithIncludeFiltering(connectionPool)
@@ -93,7 +151,7 @@ WHERE %s
 %s
 AND relkind = 'r'
 AND %s
-ORDER BY c.oid;`, relationAndSchemaFilterClause(), childPartitionFilter, ExtensionFilterClause("c"))
+ORDER BY c.oid;`, relationAndSchemaFilterClause(connectionPool), childPartitionFilter, ExtensionFilterClause("c"))
 
 	results := make([]Relation, 0)
 	err := connectionPool.Select(&results, query)
@@ -169,7 +227,7 @@ AND (
 )
 AND (relkind = 'r')
 AND %s
-ORDER BY c.oid;`, SchemaFilterClause("n"), oidStr, oidStr, oidStr, childPartitionFilter, ExtensionFilterClause("c"))
+ORDER BY c.oid;`, SchemaFilterClause("n")+excludeRelationFilterClause(connectionPool), oidStr, oidStr, oidStr, childPartitionFilter, ExtensionFilterClause("c"))
 
 	results := make([]Relation, 0)
 	err := connectionPool.Select(&results, query)
@@ -190,7 +248,7 @@ JOIN pg_namespace n
 WHERE %s
 AND relkind = 'f'
 AND %s
-ORDER BY c.oid;`, relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+ORDER BY c.oid;`, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c"))
 
 	results := make([]Relation, 0)
 	err := connectionPool.Select(&results, query)
@@ -252,7 +310,7 @@ LEFT JOIN pg_namespace n
 WHERE relkind = 'S'
 AND %s
 AND %s
-ORDER BY n.nspname, c.relname;`, relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+ORDER BY n.nspname, c.relname;`, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c"))
 
 	results := make([]Relation, 0)
 	err := connectionPool.Select(&results, query)
@@ -299,7 +357,7 @@ JOIN pg_class c
 JOIN pg_namespace n
 	ON n.oid = s.relnamespace
 WHERE s.relkind = 'S'
-AND %s;`, relationAndSchemaFilterClause())
+AND %s;`, relationAndSchemaFilterClause(connectionPool))
 
 	results := make([]struct {
 		Schema     string
@@ -321,20 +379,33 @@ AND %s;`, relationAndSchemaFilterClause())
 	return sequenceOwnerTables, sequenceOwnerColumns
 }
 
+/*
+ * Materialized views are backed up as Views with IsMaterialized set, as they
+ * are retrieved and sorted together with regular views.  Their Tablespace,
+ * DistPolicy, and IsPopulated are only set for materialized views.
+ */
 type View struct {
-	Oid        uint32
-	Schema     string
-	Name       string
-	Options    string
-	Definition string
+	Oid            uint32
+	Schema         string
+	Name           string
+	Options        string
+	Definition     string
+	Tablespace     string
+	IsMaterialized bool
+	IsPopulated    bool
+	DistPolicy     string
 }
 
 func (v View) GetMetadataEntry() (string, utils.MetadataEntry) {
+	objectType := "VIEW"
+	if v.IsMaterialized {
+		objectType = "MATERIALIZED VIEW"
+	}
 	return "predata",
 		utils.MetadataEntry{
 			Schema:          v.Schema,
 			Name:            v.Name,
-			ObjectType:      "VIEW",
+			ObjectType:      objectType,
 			ReferenceObject: "",
 			StartByte:       0,
 			EndByte:         0,
@@ -352,8 +423,15 @@ func (v View) FQN() string {
 func GetViews(connectionPool *dbconn.DBConn) []View {
 	results := make([]View, 0)
 	optionsStr := ""
+	tablespaceJoinStr := ""
+	relkindStr := `c.relkind = 'v'::"char"`
 	if connectionPool.Version.AtLeast("6") {
-		optionsStr = "coalesce(' WITH (' || array_to_string(c.reloptions, ', ') || ')', '') AS options,"
+		optionsStr = `coalesce(' WITH (' || array_to_string(c.reloptions, ', ') || ')', '') AS options,
+	coalesce(quote_ident(t.spcname), '') AS tablespace,
+	c.relkind = 'm'::"char" AS ismaterialized,
+	c.relispopulated AS ispopulated,`
+		tablespaceJoinStr = "LEFT JOIN pg_tablespace t ON t.oid = c.reltablespace"
+		relkindStr = `c.relkind IN ('m'::"char", 'v'::"char")`
 	}
 	query := fmt.Sprintf(`
 SELECT
@@ -364,21 +442,21 @@ SELECT
 	pg_get_viewdef(c.oid) AS definition
 FROM pg_class c
 LEFT JOIN pg_namespace n ON n.oid = c.relnamespace
-WHERE c.relkind = 'v'::"char"
+%s
+WHERE %s
 AND %s
-AND %s;`, optionsStr, relationAndSchemaFilterClause(), ExtensionFilterClause("c"))
+AND %s;`, optionsStr, tablespaceJoinStr, relkindStr, relationAndSchemaFilterClause(connectionPool), ExtensionFilterClause("c"))
 	err := connectionPool.Select(&results, query)
 	gplog.FatalOnError(err)
-	return results
-}
 
-func LockTables(connectionPool *dbconn.DBConn, tables []Relation) {
-	gplog.Info("Acquiring ACCESS SHARE locks on tables")
-	progressBar := utils.NewProgressBar(len(tables), "Locks acquired: ", utils.PB_VERBOSE)
-	progressBar.Start()
-	for _, table := range tables {
-		connectionPool.MustExec(fmt.Sprintf("LOCK TABLE %s IN ACCESS SHARE MODE", table.FQN()))
-		progressBar.Increment()
+	var distributionPolicies map[uint32]string
+	for i := range results {
+		if results[i].IsMaterialized {
+			if distributionPolicies == nil {
+				distributionPolicies = GetDistributionPolicies(connectionPool)
+			}
+			results[i].DistPolicy = distributionPolicies[results[i].Oid]
+		}
 	}
-	progressBar.Finish()
+	return results
 }
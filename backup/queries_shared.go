@@ -163,7 +163,7 @@ ORDER BY name;
 		filterClause := fmt.Sprintf("%s\nAND c.oid IN (%s)", SchemaFilterClause("n"), strings.Join(oidList, ","))
 		query = fmt.Sprintf(tableQuery, filterClause)
 	} else {
-		tableQuery = fmt.Sprintf(tableQuery, relationAndSchemaFilterClause())
+		tableQuery = fmt.Sprintf(tableQuery, relationAndSchemaFilterClause(connectionPool))
 		query = fmt.Sprintf("%s\nUNION\n%s", tableQuery, nonTableQuery)
 	}
 	results := make([]Constraint, 0)
@@ -179,7 +179,7 @@ func SchemaFilterClause(namespace string) string {
 		schemaFilterClauseStr = fmt.Sprintf("\nAND %s.nspname IN (%s)", namespace, utils.SliceToQuotedString(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)))
 	}
 	if len(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA)) > 0 {
-		schemaFilterClauseStr = fmt.Sprintf("\nAND %s.nspname NOT IN (%s)", namespace, utils.SliceToQuotedString(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA)))
+		schemaFilterClauseStr += fmt.Sprintf("\nAND %s.nspname NOT IN (%s)", namespace, utils.SliceToQuotedString(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA)))
 	}
 	return fmt.Sprintf(`%s.nspname NOT LIKE 'pg_temp_%%' AND %s.nspname NOT LIKE 'pg_toast%%' AND %s.nspname NOT IN ('gp_toolkit', 'information_schema', 'pg_aoseg', 'pg_bitmapindex', 'pg_catalog') %s`, namespace, namespace, namespace, schemaFilterClauseStr)
 }
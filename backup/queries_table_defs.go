@@ -55,6 +55,7 @@ type TableDefinition struct {
 	IsUnlogged         bool
 	ForeignDef         ForeignTableDefinition
 	Inherits           []string
+	SizeEstimate       int64
 }
 
 /*
@@ -83,6 +84,7 @@ func ConstructDefinitionsForTables(connectionPool *dbconn.DBConn, tableRelations
 	unloggedTableMap := GetUnloggedTables(connectionPool)
 	foreignTableDefs := GetForeignTableDefinitions(connectionPool)
 	inheritanceMap := GetTableInheritance(connectionPool, tableRelations)
+	sizeEstimates := GetTableSizeEstimates(connectionPool)
 
 	gplog.Verbose("Constructing table definition map")
 	for _, tableRel := range tableRelations {
@@ -101,6 +103,7 @@ func ConstructDefinitionsForTables(connectionPool *dbconn.DBConn, tableRelations
 			IsUnlogged:         unloggedTableMap[oid],
 			ForeignDef:         foreignTableDefs[oid],
 			Inherits:           inheritanceMap[oid],
+			SizeEstimate:       sizeEstimates[oid],
 		}
 		if tableDef.Inherits == nil {
 			tableDef.Inherits = []string{}
@@ -216,7 +219,7 @@ LEFT JOIN pg_description d ON (d.objoid = a.attrelid AND d.classoid = 'pg_class'
 WHERE %s
 AND a.attnum > 0::pg_catalog.int2
 AND a.attisdropped = 'f'
-ORDER BY a.attrelid, a.attnum;`, relationAndSchemaFilterClause())
+ORDER BY a.attrelid, a.attnum;`, relationAndSchemaFilterClause(connectionPool))
 
 	masterQuery := fmt.Sprintf(`
 SELECT
@@ -249,7 +252,7 @@ LEFT JOIN pg_seclabel sec ON (sec.objoid = a.attrelid AND sec.classoid = 'pg_cla
 WHERE %s
 AND a.attnum > 0::pg_catalog.int2
 AND a.attisdropped = 'f'
-ORDER BY a.attrelid, a.attnum;`, relationAndSchemaFilterClause())
+ORDER BY a.attrelid, a.attnum;`, relationAndSchemaFilterClause(connectionPool))
 
 	var err error
 	if connectionPool.Version.Before("6") {
@@ -304,12 +307,12 @@ WHERE %s
 AND a.attnum > 0::pg_catalog.int2
 AND a.attisdropped = 'f'
 ORDER BY a.attrelid, a.attname;
-`, relationAndSchemaFilterClause())
+`, relationAndSchemaFilterClause(connectionPool))
 
 	results := make([]ColumnPrivilegesQueryStruct, 0)
 	err := connectionPool.Select(&results, query)
 	gplog.FatalOnError(err)
-	metadataMap = ConstructColumnPrivilegesMap(results)
+	metadataMap = ConstructColumnPrivilegesMap(connectionPool, results)
 
 	return metadataMap
 }
@@ -379,7 +382,7 @@ func GetPartitionDefinitions(connectionPool *dbconn.DBConn) map[uint32]string {
 	query := fmt.Sprintf(`SELECT p.parrelid AS oid, pg_get_partition_def(p.parrelid, true, true) AS value FROM pg_partition p
 	JOIN pg_class c ON p.parrelid = c.oid
 	JOIN pg_namespace n ON c.relnamespace = n.oid
-	WHERE %s`, relationAndSchemaFilterClause())
+	WHERE %s`, relationAndSchemaFilterClause(connectionPool))
 	return selectAsOidToStringMap(connectionPool, query)
 }
 
@@ -387,7 +390,7 @@ func GetPartitionTemplates(connectionPool *dbconn.DBConn) map[uint32]string {
 	query := fmt.Sprintf(`SELECT p.parrelid AS oid, pg_get_partition_template_def(p.parrelid, true, true) AS value FROM pg_partition p
 	JOIN pg_class c ON p.parrelid = c.oid
 	JOIN pg_namespace n ON c.relnamespace = n.oid
-	WHERE %s`, relationAndSchemaFilterClause())
+	WHERE %s`, relationAndSchemaFilterClause(connectionPool))
 	return selectAsOidToStringMap(connectionPool, query)
 }
 
@@ -418,6 +421,39 @@ func GetUnloggedTables(connectionPool *dbconn.DBConn) map[uint32]bool {
 	return resultMap
 }
 
+/*
+ * Table sizes are estimated from relpages, which is only as current as the
+ * last VACUUM or ANALYZE of the table, so that no queries need to be
+ * dispatched to the segments.  A partition root has no storage of its own, so
+ * its estimate is the sum of the estimates of all of its partitions.
+ */
+func GetTableSizeEstimates(connectionPool *dbconn.DBConn) map[uint32]int64 {
+	query := fmt.Sprintf(`
+SELECT
+	c.oid,
+	(c.relpages::bigint + coalesce(sum(pc.relpages), 0)) * current_setting('block_size')::bigint AS sizeestimate
+FROM pg_class c
+JOIN pg_namespace n ON c.relnamespace = n.oid
+LEFT JOIN pg_partition p ON p.parrelid = c.oid
+LEFT JOIN pg_partition_rule r ON r.paroid = p.oid
+LEFT JOIN pg_class pc ON pc.oid = r.parchildrelid
+WHERE c.relkind = 'r'
+AND %s
+GROUP BY c.oid, c.relpages`, SchemaFilterClause("n"))
+
+	var results []struct {
+		Oid          uint32
+		SizeEstimate int64
+	}
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	resultMap := make(map[uint32]int64, 0)
+	for _, result := range results {
+		resultMap[result.Oid] = result.SizeEstimate
+	}
+	return resultMap
+}
+
 type ForeignTableDefinition struct {
 	Oid     uint32 `db:"ftrelid"`
 	Options string `db:"ftoptions"`
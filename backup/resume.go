@@ -0,0 +1,221 @@
+package backup
+
+/*
+ * This file contains structs and functions related to resuming a backup that
+ * failed partway through backing up data.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * A record is appended to the completed tables file as soon as each table's
+ * data file is complete, along with the incremental metadata for the table as
+ * of the backup's snapshot, so that a resumed backup can tell whether the
+ * table has changed since its data file was written.
+ */
+type CompletedTableRecord struct {
+	DataEntry          utils.MasterDataEntry
+	DataFile           string
+	AOEntry            *utils.AOEntry   `json:",omitempty"`
+	HeapEntry          *utils.HeapEntry `json:",omitempty"`
+	FingerprintVersion int              `json:",omitempty"`
+}
+
+/*
+ * Completed tables are only tracked when each table has its own data file on
+ * disk and the backup has incremental metadata to compare against.
+ */
+func IsTrackingCompletedTables() bool {
+	return !MustGetFlagBool(utils.SINGLE_DATA_FILE) && MustGetFlagString(utils.PLUGIN_CONFIG) == "" && !MustGetFlagBool(utils.DATA_ONLY)
+}
+
+/*
+ * The completed tables, config, report, and TOC files of the failed backup are
+ * all rewritten by the resumed backup, so the latter three are removed here to
+ * avoid failing on the read-only copies written by the failed backup.
+ */
+func PrepareBackupDirectoryForResume() {
+	if iohelper.FileExistsAndIsReadable(globalFPInfo.GetBackupHistoryFilePath()) {
+		history, err := backup_history.NewHistory(globalFPInfo.GetBackupHistoryFilePath())
+		gplog.FatalOnError(err)
+		for _, backupConfig := range history.BackupConfigs {
+			if backupConfig.Timestamp == globalFPInfo.Timestamp {
+				gplog.Fatal(errors.Errorf("The backup with timestamp = %s completed successfully and cannot be resumed.", globalFPInfo.Timestamp), "")
+			}
+		}
+	}
+	if !iohelper.FileExistsAndIsReadable(globalFPInfo.GetCompletedTablesFilePath()) {
+		gplog.Fatal(errors.Errorf("Completed tables file %s not found.  The backup with timestamp = %s cannot be resumed.",
+			globalFPInfo.GetCompletedTablesFilePath(), globalFPInfo.Timestamp), "")
+	}
+	for _, filename := range []string{globalFPInfo.GetConfigFilePath(), globalFPInfo.GetBackupReportFilePath(), globalFPInfo.GetTOCFilePath()} {
+		if _, err := operating.System.Stat(filename); err == nil {
+			err = operating.System.Remove(filename)
+			gplog.FatalOnError(err)
+		}
+	}
+}
+
+/*
+ * Lines that cannot be parsed are skipped, as the last line may have been
+ * only partially written when the backup failed.  If a table appears more
+ * than once, the last record for it is used.
+ */
+func ReadCompletedTableRecords(filename string) map[string]CompletedTableRecord {
+	contents, err := operating.System.ReadFile(filename)
+	gplog.FatalOnError(err)
+	records := make(map[string]CompletedTableRecord, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record := CompletedTableRecord{}
+		err = json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			gplog.Verbose("Skipping unreadable line in completed tables file %s", filename)
+			continue
+		}
+		records[utils.MakeFQN(record.DataEntry.Schema, record.DataEntry.Name)] = record
+	}
+	return records
+}
+
+func GetTablesToResume(tables []Table, records map[string]CompletedTableRecord, currentTOC *utils.TOC) ([]Table, []CompletedTableRecord) {
+	tablesToBackUp := make([]Table, 0)
+	reusedRecords := make([]CompletedTableRecord, 0)
+	for _, table := range tables {
+		record, ok := records[table.FQN()]
+		if ok && !table.SkipDataBackup() && IsCompletedTableUnchanged(table, record, currentTOC) {
+			reusedRecords = append(reusedRecords, record)
+		} else {
+			tablesToBackUp = append(tablesToBackUp, table)
+		}
+	}
+	return tablesToBackUp, reusedRecords
+}
+
+/*
+ * As in an incremental backup, AO tables are compared on modcount and DDL
+ * timestamp, and heap tables can only be reused if their contents were
+ * checksummed with --track-heap-changes.
+ */
+func IsCompletedTableUnchanged(table Table, record CompletedTableRecord, currentTOC *utils.TOC) bool {
+	if record.DataEntry.Oid != table.Oid ||
+		record.DataEntry.AttributeString != ConstructTableAttributesList(table.ColumnDefs) ||
+		record.DataEntry.Predicate != GetTablePredicate(table) ||
+		strings.Join(record.DataEntry.MaskedColumns, "\n") != strings.Join(GetMaskedColumns(table), "\n") ||
+		record.DataFile != globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.GetPipeThroughProgram().Extension, false) {
+		return false
+	}
+	if MustGetFlagBool(utils.WITH_FINGERPRINTS) && record.DataEntry.Fingerprints == nil {
+		return false
+	}
+	if record.FingerprintVersion != currentTOC.FingerprintVersion && (record.DataEntry.Fingerprints != nil || record.HeapEntry != nil) {
+		return false
+	}
+	if currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[table.FQN()]; isAOTable {
+		return record.AOEntry != nil && *record.AOEntry == currentAOEntry
+	}
+	currentHeapEntry, hasCurrentHeapEntry := currentTOC.IncrementalMetadata.Heap[table.FQN()]
+	return hasCurrentHeapEntry && record.HeapEntry != nil &&
+		record.HeapEntry.LastDDLTimestamp == currentHeapEntry.LastDDLTimestamp &&
+		len(utils.CompareTableFingerprints(table.Schema, table.Name, record.HeapEntry.Fingerprints, currentHeapEntry.Fingerprints)) == 0
+}
+
+/*
+ * Any data file not being reused is either about to be overwritten or belongs
+ * to a table that is no longer in the backup set, and leaving the latter in
+ * place would cause the restore's file count check to fail.
+ */
+func RemoveIncompleteDataFiles(reusedRecords []CompletedTableRecord) {
+	extension := utils.GetPipeThroughProgram().Extension
+	remoteOutput := globalCluster.GenerateAndExecuteCommand("Removing incomplete data files from previous backup attempt", func(contentID int) string {
+		keepStr := ""
+		for _, record := range reusedRecords {
+			keepStr += fmt.Sprintf(" ! -name %s", fmt.Sprintf("gpbackup_%d_%s_%d%s", contentID, globalFPInfo.Timestamp, record.DataEntry.Oid, extension))
+		}
+		return fmt.Sprintf("find %s -maxdepth 1 -type f -name 'gpbackup_%d_%s_*'%s -delete", globalFPInfo.GetDirForContent(contentID), contentID, globalFPInfo.Timestamp, keepStr)
+	}, cluster.ON_SEGMENTS)
+	globalCluster.CheckClusterError(remoteOutput, "Unable to remove incomplete data files", func(contentID int) string {
+		return fmt.Sprintf("Unable to remove incomplete data files in %s on host %s", globalFPInfo.GetDirForContent(contentID), globalCluster.GetHostForContent(contentID))
+	})
+}
+
+func OpenCompletedTablesFile(filename string, reusedRecords []CompletedTableRecord) {
+	completedTablesFile = iohelper.MustOpenFileForWriting(filename)
+	for _, record := range reusedRecords {
+		err := writeCompletedTableRecord(record)
+		gplog.FatalOnError(err)
+	}
+}
+
+func RecordCompletedTable(table Table, rowsCopied int64, fingerprints []utils.SegmentFingerprint) error {
+	if completedTablesFile == nil || table.SkipDataBackup() {
+		return nil
+	}
+	record := CompletedTableRecord{
+		DataEntry: utils.MasterDataEntry{
+			Schema:          table.Schema,
+			Name:            table.Name,
+			Oid:             table.Oid,
+			AttributeString: ConstructTableAttributesList(table.ColumnDefs),
+			RowsCopied:      rowsCopied,
+			PartitionRoot:   table.PartitionLevelInfo.RootName,
+			Fingerprints:    fingerprints,
+			SizeEstimate:    table.SizeEstimate,
+			Predicate:       GetTablePredicate(table),
+			MaskedColumns:   GetMaskedColumns(table),
+		},
+		DataFile:           globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.GetPipeThroughProgram().Extension, false),
+		FingerprintVersion: globalTOC.FingerprintVersion,
+	}
+	if aoEntry, ok := globalTOC.IncrementalMetadata.AO[table.FQN()]; ok {
+		record.AOEntry = &aoEntry
+	}
+	if heapEntry, ok := globalTOC.IncrementalMetadata.Heap[table.FQN()]; ok {
+		record.HeapEntry = &heapEntry
+	}
+	return writeCompletedTableRecord(record)
+}
+
+func writeCompletedTableRecord(record CompletedTableRecord) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	completedTablesMutex.Lock()
+	defer completedTablesMutex.Unlock()
+	_, err = completedTablesFile.Write(append(recordBytes, '\n'))
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error recording completed data backup of table %s", utils.MakeFQN(record.DataEntry.Schema, record.DataEntry.Name)))
+	}
+	return nil
+}
+
+/*
+ * The completed tables file is only needed to resume a failed backup, so it
+ * is removed once the backup succeeds.
+ */
+func RemoveCompletedTablesFile() {
+	if completedTablesFile == nil {
+		return
+	}
+	_ = completedTablesFile.Close()
+	completedTablesFile = nil
+	err := operating.System.Remove(globalFPInfo.GetCompletedTablesFilePath())
+	if err != nil {
+		gplog.Warn("Unable to remove completed tables file %s", globalFPInfo.GetCompletedTablesFilePath())
+	}
+}
@@ -0,0 +1,151 @@
+package backup_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/resume tests", func() {
+	fpInfo := backup_filepath.FilePathInfo{Timestamp: "20170101010101"}
+	aoTable := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "ao"}}
+	heapTable := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "heap"}}
+	fingerprints := []utils.SegmentFingerprint{{ContentID: 0, NumRows: 1, Checksum: "123"}}
+	aoRecord := backup.CompletedTableRecord{
+		DataEntry: utils.MasterDataEntry{Schema: "public", Name: "ao", Oid: 1, RowsCopied: 10},
+		DataFile:  "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_1",
+		AOEntry:   &utils.AOEntry{Modcount: 5, LastDDLTimestamp: "00000"},
+	}
+	heapRecord := backup.CompletedTableRecord{
+		DataEntry: utils.MasterDataEntry{Schema: "public", Name: "heap", Oid: 2, RowsCopied: 1},
+		DataFile:  "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_2",
+		HeapEntry: &utils.HeapEntry{LastDDLTimestamp: "00000", Fingerprints: fingerprints},
+	}
+	var currentTOC *utils.TOC
+
+	BeforeEach(func() {
+		backup.SetFPInfo(fpInfo)
+		currentTOC = &utils.TOC{
+			IncrementalMetadata: utils.IncrementalEntries{
+				AO:   map[string]utils.AOEntry{"public.ao": {Modcount: 5, LastDDLTimestamp: "00000"}},
+				Heap: map[string]utils.HeapEntry{"public.heap": {LastDDLTimestamp: "00000", Fingerprints: fingerprints}},
+			},
+		}
+	})
+	Describe("ReadCompletedTableRecords", func() {
+		AfterEach(func() {
+			operating.InitializeSystemFunctions()
+		})
+		It("reads one record per table, using the last record and skipping partially written lines", func() {
+			operating.System.ReadFile = func(string) ([]byte, error) {
+				return []byte(`{"DataEntry":{"Schema":"public","Name":"ao","Oid":1,"RowsCopied":3}}
+{"DataEntry":{"Schema":"public","Name":"ao","Oid":1,"RowsCopied":10},"DataFile":"<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_1","AOEntry":{"Modcount":5,"LastDDLTimestamp":"00000"}}
+{"DataEntry":{"Schema":"public","Name":"hea`), nil
+			}
+
+			records := backup.ReadCompletedTableRecords("/tmp/completed_tables.jsonl")
+
+			Expect(records).To(Equal(map[string]backup.CompletedTableRecord{"public.ao": aoRecord}))
+		})
+	})
+	Describe("GetTablesToResume", func() {
+		records := map[string]backup.CompletedTableRecord{"public.ao": aoRecord, "public.heap": heapRecord}
+		It("reuses completed tables that have not changed", func() {
+			tables, reused := backup.GetTablesToResume([]backup.Table{aoTable, heapTable}, records, currentTOC)
+
+			Expect(tables).To(BeEmpty())
+			Expect(reused).To(Equal([]backup.CompletedTableRecord{aoRecord, heapRecord}))
+		})
+		It("backs up tables that were not completed", func() {
+			otherTable := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "other"}}
+
+			tables, reused := backup.GetTablesToResume([]backup.Table{aoTable, otherTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{otherTable}))
+			Expect(reused).To(Equal([]backup.CompletedTableRecord{aoRecord}))
+		})
+		It("backs up AO tables whose modcount has changed", func() {
+			currentTOC.IncrementalMetadata.AO["public.ao"] = utils.AOEntry{Modcount: 6, LastDDLTimestamp: "00000"}
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{aoTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{aoTable}))
+		})
+		It("backs up heap tables whose contents have changed", func() {
+			currentTOC.IncrementalMetadata.Heap["public.heap"] = utils.HeapEntry{LastDDLTimestamp: "00000", Fingerprints: []utils.SegmentFingerprint{{ContentID: 0, NumRows: 2, Checksum: "456"}}}
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{heapTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{heapTable}))
+		})
+		It("backs up heap tables whose contents were not checksummed", func() {
+			delete(currentTOC.IncrementalMetadata.Heap, "public.heap")
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{heapTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{heapTable}))
+		})
+		It("backs up tables that have been recreated with a different oid", func() {
+			recreatedTable := backup.Table{Relation: backup.Relation{Oid: 4, Schema: "public", Name: "ao"}}
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{recreatedTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{recreatedTable}))
+		})
+		It("backs up tables whose data file was written with a different compression setting", func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Extension: ".gz"})
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{aoTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{aoTable}))
+		})
+		It("backs up heap tables whose contents were checksummed in a different fingerprint format", func() {
+			currentTOC.FingerprintVersion = utils.FINGERPRINT_VERSION
+
+			tables, reused := backup.GetTablesToResume([]backup.Table{aoTable, heapTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{heapTable}))
+			Expect(reused).To(Equal([]backup.CompletedTableRecord{aoRecord}))
+		})
+		It("backs up tables with no fingerprint when --with-fingerprints is set", func() {
+			_ = cmdFlags.Set(utils.WITH_FINGERPRINTS, "true")
+
+			tables, _ := backup.GetTablesToResume([]backup.Table{aoTable}, records, currentTOC)
+
+			Expect(tables).To(Equal([]backup.Table{aoTable}))
+		})
+	})
+	Describe("RecordCompletedTable", func() {
+		AfterEach(func() {
+			backup.SetCompletedTablesFile(nil)
+		})
+		It("writes a record with the table's data entry and incremental metadata", func() {
+			backup.SetTOC(currentTOC)
+			backup.SetCompletedTablesFile(buffer)
+
+			err := backup.RecordCompletedTable(aoTable, 10, nil)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buffer.Contents())).To(Equal(`{"DataEntry":{"Schema":"public","Name":"ao","Oid":1,"AttributeString":"","RowsCopied":10,"PartitionRoot":"","Fingerprints":null,"SizeEstimate":0,"Predicate":"","MaskedColumns":null},"DataFile":"\u003cSEG_DATA_DIR\u003e/backups/20170101/20170101010101/gpbackup_\u003cSEGID\u003e_20170101010101_1","AOEntry":{"Modcount":5,"LastDDLTimestamp":"00000"}}
+`))
+		})
+		It("does nothing when completed tables are not being tracked", func() {
+			err := backup.RecordCompletedTable(aoTable, 10, nil)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("does not record external tables", func() {
+			backup.SetTOC(currentTOC)
+			backup.SetCompletedTablesFile(buffer)
+			extTable := backup.Table{Relation: backup.Relation{Oid: 5, Schema: "public", Name: "ext"}, TableDefinition: backup.TableDefinition{IsExternal: true}}
+
+			err := backup.RecordCompletedTable(extTable, 0, nil)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.Contents()).To(BeEmpty())
+		})
+	})
+})
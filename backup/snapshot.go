@@ -0,0 +1,122 @@
+package backup
+
+/*
+ * This file contains functions related to ensuring that data backed up on
+ * different connections reflects a single point in time.
+ */
+
+import (
+	"fmt"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+)
+
+const (
+	CONSISTENCY_SINGLE_CONNECTION = "Single snapshot on one connection"
+	CONSISTENCY_SYNCHRONIZED      = "Synchronized snapshot across all connections"
+	CONSISTENCY_TABLE_LOCKS       = "Per-connection snapshots taken under EXCLUSIVE table locks"
+	CONSISTENCY_PER_CONNECTION    = "Independent snapshot per connection"
+)
+
+/*
+ * Exporting a snapshot before GPDB 6.21.0 only exports the master's local
+ * snapshot, which does not give a consistent view of data on the segments.
+ */
+func SupportsSynchronizedSnapshots(connectionPool *dbconn.DBConn) bool {
+	return connectionPool.Version.AtLeast("6.21.0")
+}
+
+func GetDataConsistency(numConns int, supportsSynchronizedSnapshots bool, consistent bool) string {
+	if numConns == 1 {
+		return CONSISTENCY_SINGLE_CONNECTION
+	} else if supportsSynchronizedSnapshots {
+		return CONSISTENCY_SYNCHRONIZED
+	} else if consistent {
+		return CONSISTENCY_TABLE_LOCKS
+	}
+	return CONSISTENCY_PER_CONNECTION
+}
+
+/*
+ * Where synchronized snapshots are not supported, --consistent instead takes
+ * EXCLUSIVE locks on every table in the backup set so that no data can change
+ * once the locks are held.  No connection in the pool begins its transaction
+ * until then, as a REPEATABLE READ snapshot taken before the locks are held
+ * could miss changes committed while waiting for them, so the locks are held
+ * by a separate connection; see GetLockConnection.
+ */
+func BeginBackupTransactions() {
+	switch dataConsistency {
+	case CONSISTENCY_TABLE_LOCKS:
+		gplog.Warn("This version of Greenplum does not support synchronized snapshots, so --consistent will hold EXCLUSIVE locks " +
+			"on all tables in the backup set, blocking writes to them until the backup completes.")
+	case CONSISTENCY_PER_CONNECTION:
+		gplog.Warn("This version of Greenplum does not support synchronized snapshots, so tables backed up on different connections " +
+			"may reflect different points in time.  Use --consistent to block writes to tables in the backup set during the backup instead.")
+		beginRepeatableReadTransactions(0)
+	case CONSISTENCY_SYNCHRONIZED:
+		beginRepeatableReadTransactions(0)
+		snapshotID := dbconn.MustSelectString(connectionPool, "SELECT pg_export_snapshot() AS string")
+		gplog.Verbose("Synchronizing all connections to snapshot %s", snapshotID)
+		for connNum := 1; connNum < connectionPool.NumConns; connNum++ {
+			connectionPool.MustExec(fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'", snapshotID), connNum)
+		}
+	default:
+		beginRepeatableReadTransactions(0)
+	}
+}
+
+/*
+ * The table locks are normally taken on connection 0, inside the transaction
+ * that it backs up from.  When --consistent relies on table locks, they are
+ * instead held until the end of the backup by a transaction on a connection
+ * outside the pool, so that every connection in the pool can begin its
+ * transaction after the locks are acquired.  EXCLUSIVE locks do not conflict
+ * with the ACCESS SHARE locks taken by the pool's COPY commands.
+ */
+func GetLockConnection() *dbconn.DBConn {
+	if dataConsistency != CONSISTENCY_TABLE_LOCKS {
+		return connectionPool
+	}
+	if lockConnection == nil {
+		lockConnection = dbconn.NewDBConnFromEnvironment(connectionPool.DBName)
+		lockConnection.MustConnect(1)
+		lockConnection.MustExec("SET application_name TO 'gpbackup'")
+		lockConnection.MustBegin()
+	}
+	return lockConnection
+}
+
+/*
+ * This function is called once all tables have been locked, or in place of
+ * locking them when no backup files are written, and only does anything when
+ * --consistent is relying on table locks.
+ */
+func BeginTransactionsAfterLocks() {
+	if dataConsistency == CONSISTENCY_TABLE_LOCKS {
+		beginRepeatableReadTransactions(0)
+	}
+}
+
+func GetTableLockMode() string {
+	if dataConsistency == CONSISTENCY_TABLE_LOCKS {
+		return "EXCLUSIVE"
+	}
+	return "ACCESS SHARE"
+}
+
+/*
+ * GPDB 4.3 and 5 do not support REPEATABLE READ, but their SERIALIZABLE
+ * isolation level gives the same single snapshot for the whole transaction.
+ */
+func beginRepeatableReadTransactions(firstConn int) {
+	isolationLevel := "SERIALIZABLE"
+	if connectionPool.Version.AtLeast("6") {
+		isolationLevel = "REPEATABLE READ"
+	}
+	for connNum := firstConn; connNum < connectionPool.NumConns; connNum++ {
+		connectionPool.MustBegin(connNum)
+		connectionPool.MustExec(fmt.Sprintf("SET TRANSACTION ISOLATION LEVEL %s", isolationLevel), connNum)
+	}
+}
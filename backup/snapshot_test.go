@@ -0,0 +1,117 @@
+package backup_test
+
+import (
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/snapshot tests", func() {
+	Describe("GetDataConsistency", func() {
+		It("uses a single snapshot when there is only one connection", func() {
+			Expect(backup.GetDataConsistency(1, false, false)).To(Equal(backup.CONSISTENCY_SINGLE_CONNECTION))
+			Expect(backup.GetDataConsistency(1, true, true)).To(Equal(backup.CONSISTENCY_SINGLE_CONNECTION))
+		})
+		It("synchronizes snapshots when they are supported", func() {
+			Expect(backup.GetDataConsistency(4, true, false)).To(Equal(backup.CONSISTENCY_SYNCHRONIZED))
+			Expect(backup.GetDataConsistency(4, true, true)).To(Equal(backup.CONSISTENCY_SYNCHRONIZED))
+		})
+		It("relies on table locks when snapshots are not supported and --consistent is set", func() {
+			Expect(backup.GetDataConsistency(4, false, true)).To(Equal(backup.CONSISTENCY_TABLE_LOCKS))
+		})
+		It("uses independent snapshots when snapshots are not supported and --consistent is not set", func() {
+			Expect(backup.GetDataConsistency(4, false, false)).To(Equal(backup.CONSISTENCY_PER_CONNECTION))
+		})
+	})
+	Describe("GetTableLockMode", func() {
+		AfterEach(func() {
+			backup.SetDataConsistency("")
+		})
+		It("takes EXCLUSIVE locks when relying on table locks for consistency", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_TABLE_LOCKS)
+			Expect(backup.GetTableLockMode()).To(Equal("EXCLUSIVE"))
+		})
+		It("takes ACCESS SHARE locks otherwise", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			Expect(backup.GetTableLockMode()).To(Equal("ACCESS SHARE"))
+		})
+	})
+	Describe("BeginBackupTransactions", func() {
+		AfterEach(func() {
+			backup.SetDataConsistency("")
+		})
+		It("begins a repeatable read transaction for a single connection in GPDB 6", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			backup.SetDataConsistency(backup.CONSISTENCY_SINGLE_CONNECTION)
+			mock.ExpectBegin()
+			mock.ExpectExec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.BeginBackupTransactions()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("begins a serializable transaction for a single connection in GPDB 5", func() {
+			testhelper.SetDBVersion(connectionPool, "5.1.0")
+			backup.SetDataConsistency(backup.CONSISTENCY_SINGLE_CONNECTION)
+			mock.ExpectBegin()
+			mock.ExpectExec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.BeginBackupTransactions()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("begins a serializable transaction for a single connection in GPDB 4.3", func() {
+			testhelper.SetDBVersion(connectionPool, "4.3.0")
+			backup.SetDataConsistency(backup.CONSISTENCY_SINGLE_CONNECTION)
+			mock.ExpectBegin()
+			mock.ExpectExec("SET TRANSACTION ISOLATION LEVEL SERIALIZABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.BeginBackupTransactions()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("does not begin any transaction before the tables are locked when relying on table locks", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_TABLE_LOCKS)
+
+			backup.BeginBackupTransactions()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(connectionPool.Tx[0]).To(BeNil())
+		})
+	})
+	Describe("BeginTransactionsAfterLocks", func() {
+		AfterEach(func() {
+			backup.SetDataConsistency("")
+		})
+		It("begins a repeatable read transaction on every connection, including connection 0, when relying on table locks", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			backup.SetDataConsistency(backup.CONSISTENCY_TABLE_LOCKS)
+			mock.ExpectBegin()
+			mock.ExpectExec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ").WillReturnResult(sqlmock.NewResult(0, 0))
+
+			backup.BeginTransactionsAfterLocks()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(connectionPool.Tx[0]).ToNot(BeNil())
+		})
+		It("does nothing when the transactions began before the tables were locked", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SINGLE_CONNECTION)
+
+			backup.BeginTransactionsAfterLocks()
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
+	Describe("GetLockConnection", func() {
+		AfterEach(func() {
+			backup.SetDataConsistency("")
+		})
+		It("locks tables on the connection pool when not relying on table locks for consistency", func() {
+			backup.SetDataConsistency(backup.CONSISTENCY_SYNCHRONIZED)
+			Expect(backup.GetLockConnection()).To(BeIdenticalTo(connectionPool))
+		})
+	})
+})
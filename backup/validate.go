@@ -86,21 +86,69 @@ WHERE quote_ident(n.nspname) || '.' || quote_ident(c.relname) IN (%s)`, quotedTa
 
 func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	utils.CheckExclusiveFlags(flags, utils.DEBUG, utils.QUIET, utils.VERBOSE)
-	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.METADATA_ONLY, utils.INCREMENTAL)
-	utils.CheckExclusiveFlags(flags, utils.INCLUDE_SCHEMA, utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE)
-	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_SCHEMA, utils.INCLUDE_SCHEMA)
-	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_SCHEMA, utils.EXCLUDE_RELATION, utils.INCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_FILE)
-	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.LEAF_PARTITION_DATA)
+	utils.CheckExclusiveFlags(flags, utils.DBNAME, utils.ALL_DATABASES, utils.INCLUDE_DATABASE)
+	if !flags.Changed(utils.DBNAME) && !MustGetFlagBool(utils.ALL_DATABASES) && !flags.Changed(utils.INCLUDE_DATABASE) {
+		gplog.Fatal(errors.Errorf("One of --dbname, --all-databases, or --include-database must be specified"), "")
+	}
+	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.METADATA_ONLY, utils.INCREMENTAL, utils.DIFFERENTIAL)
+	utils.CheckFilterFlagCombinations(flags)
 	utils.CheckExclusiveFlags(flags, utils.JOBS, utils.METADATA_ONLY, utils.SINGLE_DATA_FILE)
 	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.LEAF_PARTITION_DATA)
+	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.WITH_FINGERPRINTS)
+	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.METADATA_ONLY, utils.TRACK_HEAP_CHANGES)
 	utils.CheckExclusiveFlags(flags, utils.NO_COMPRESSION, utils.COMPRESSION_LEVEL)
 	utils.CheckExclusiveFlags(flags, utils.PLUGIN_CONFIG, utils.BACKUP_DIR)
-	if MustGetFlagString(utils.FROM_TIMESTAMP) != "" && !MustGetFlagBool(utils.INCREMENTAL) {
-		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental"), "")
+	utils.CheckExclusiveFlags(flags, utils.COPY_RETRIES, utils.SINGLE_DATA_FILE)
+	utils.CheckExclusiveFlags(flags, utils.COPY_RETRIES, utils.PLUGIN_CONFIG)
+	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.INCREMENTAL, utils.DIFFERENTIAL, utils.TABLE_PREDICATE_FILE)
+	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.INCREMENTAL, utils.DIFFERENTIAL, utils.MASKING_RULES_FILE)
+	utils.CheckExclusiveFlags(flags, utils.MASKING_RULES_FILE, utils.WITH_STATS)
+	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.WITH_CATALOG_MODEL)
+	if MustGetFlagString(utils.CONSOLIDATE) != "" {
+		for _, flagName := range []string{utils.COMPRESSION_LEVEL, utils.DATA_ONLY, utils.DIFFERENTIAL, utils.EXCLUDE_RELATION,
+			utils.EXCLUDE_RELATION_FILE, utils.EXCLUDE_RELATION_PATTERN, utils.EXCLUDE_SCHEMA, utils.EXCLUDE_SCHEMA_PATTERN, utils.FROM_TIMESTAMP,
+			utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_SCHEMA, utils.INCLUDE_SCHEMA_PATTERN, utils.INCREMENTAL, utils.JOBS, utils.LEAF_PARTITION_DATA, utils.MASKING_RULES_FILE, utils.METADATA_FORMAT, utils.METADATA_ONLY, utils.NO_COMPRESSION,
+			utils.PLUGIN_CONFIG, utils.RESUME, utils.SINGLE_DATA_FILE, utils.TABLE_PREDICATE_FILE, utils.TRACK_HEAP_CHANGES, utils.WITH_CATALOG_MODEL, utils.WITH_FINGERPRINTS, utils.WITH_STATS} {
+			utils.CheckExclusiveFlags(flags, utils.CONSOLIDATE, flagName)
+		}
+	}
+	if MustGetFlagBool(utils.LIST_ONLY) {
+		for _, flagName := range []string{utils.CONSOLIDATE, utils.DIFFERENTIAL, utils.INCREMENTAL, utils.RESUME} {
+			utils.CheckExclusiveFlags(flags, utils.LIST_ONLY, flagName)
+		}
+	}
+	if MustGetFlagString(utils.DIFF_TIMESTAMP) != "" {
+		for _, flagName := range []string{utils.CONSOLIDATE, utils.DATA_ONLY, utils.DIFFERENTIAL, utils.INCREMENTAL, utils.LIST_ONLY, utils.PLUGIN_CONFIG, utils.RESUME} {
+			utils.CheckExclusiveFlags(flags, utils.DIFF_TIMESTAMP, flagName)
+		}
+	}
+	if MustGetFlagString(utils.DIFF_TO_TIMESTAMP) != "" && MustGetFlagString(utils.DIFF_TIMESTAMP) == "" {
+		gplog.Fatal(errors.Errorf("--diff-to-timestamp must be specified with --diff-timestamp"), "")
+	}
+	if IsBackupGroup() {
+		groupFlag := utils.INCLUDE_DATABASE
+		if MustGetFlagBool(utils.ALL_DATABASES) {
+			groupFlag = utils.ALL_DATABASES
+		}
+		for _, flagName := range []string{utils.BACKUP_GROUP, utils.CONSOLIDATE, utils.DIFF_TIMESTAMP, utils.DIFF_TO_TIMESTAMP, utils.FROM_TIMESTAMP,
+			utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_SCHEMA, utils.INCLUDE_SCHEMA_PATTERN, utils.RESUME} {
+			utils.CheckExclusiveFlags(flags, groupFlag, flagName)
+		}
+	}
+	if MustGetFlagString(utils.RESUME) != "" {
+		for _, flagName := range []string{utils.DATA_ONLY, utils.METADATA_ONLY, utils.PLUGIN_CONFIG, utils.SINGLE_DATA_FILE} {
+			utils.CheckExclusiveFlags(flags, utils.RESUME, flagName)
+		}
+	}
+	if MustGetFlagString(utils.FROM_TIMESTAMP) != "" && !MustGetFlagBool(utils.INCREMENTAL) && !MustGetFlagBool(utils.DIFFERENTIAL) {
+		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental or --differential"), "")
 	}
 	if MustGetFlagBool(utils.INCREMENTAL) && !MustGetFlagBool(utils.LEAF_PARTITION_DATA) {
 		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --incremental"), "")
 	}
+	if MustGetFlagBool(utils.DIFFERENTIAL) && !MustGetFlagBool(utils.LEAF_PARTITION_DATA) {
+		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --differential"), "")
+	}
 }
 
 func ValidateFlagValues() {
@@ -108,11 +156,41 @@ func ValidateFlagValues() {
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(utils.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
+	err = utils.ValidatePatternFlags(cmdFlags)
+	gplog.FatalOnError(err)
 	ValidateCompressionLevel(MustGetFlagInt(utils.COMPRESSION_LEVEL))
+	if MustGetFlagInt(utils.COPY_RETRIES) < 0 {
+		gplog.Fatal(errors.Errorf("--copy-retries must be greater than or equal to 0"), "")
+	}
+	if MustGetFlagInt(utils.LOCK_WAIT_TIMEOUT) < 0 {
+		gplog.Fatal(errors.Errorf("--lock-wait-timeout must be greater than or equal to 0"), "")
+	}
+	metadataFormat := MustGetFlagString(utils.METADATA_FORMAT)
+	if metadataFormat != utils.METADATA_FORMAT_FILE && metadataFormat != utils.METADATA_FORMAT_DIRECTORY {
+		gplog.Fatal(errors.Errorf("Metadata format %s is invalid.  Valid values are %s and %s.",
+			metadataFormat, utils.METADATA_FORMAT_FILE, utils.METADATA_FORMAT_DIRECTORY), "")
+	}
+	if metadataFormat == utils.METADATA_FORMAT_DIRECTORY && MustGetFlagString(utils.PLUGIN_CONFIG) != "" {
+		gplog.Fatal(errors.Errorf("--metadata-format %s cannot be used with --plugin-config", utils.METADATA_FORMAT_DIRECTORY), "")
+	}
 	if MustGetFlagString(utils.FROM_TIMESTAMP) != "" && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.FROM_TIMESTAMP)) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
 			MustGetFlagString(utils.FROM_TIMESTAMP)), "")
 	}
+	if MustGetFlagString(utils.CONSOLIDATE) != "" && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.CONSOLIDATE)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
+			MustGetFlagString(utils.CONSOLIDATE)), "")
+	}
+	for _, flagName := range []string{utils.BACKUP_GROUP, utils.DIFF_TIMESTAMP, utils.DIFF_TO_TIMESTAMP} {
+		if MustGetFlagString(flagName) != "" && !backup_filepath.IsValidTimestamp(MustGetFlagString(flagName)) {
+			gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
+				MustGetFlagString(flagName)), "")
+		}
+	}
+	if MustGetFlagString(utils.RESUME) != "" && !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.RESUME)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
+			MustGetFlagString(utils.RESUME)), "")
+	}
 }
 
 func ValidateCompressionLevel(compressionLevel int) {
@@ -129,10 +207,22 @@ func ValidateFromTimestamp(fromTimestamp string) {
 		pluginConfig.MustRestoreFile(fromTimestampFPInfo.GetConfigFilePath())
 	}
 	fromBackupConfig := backup_history.ReadConfigFile(fromTimestampFPInfo.GetConfigFilePath())
+	if fromBackupConfig.Subset {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s only contains a subset of the rows of some tables "+
+			"and cannot be used as the base of an incremental backup.", fromTimestampFPInfo.Timestamp), "")
+	}
+	if len(fromBackupConfig.MaskingRules) > 0 {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s contains masked data "+
+			"and cannot be used as the base of an incremental backup.", fromTimestampFPInfo.Timestamp), "")
+	}
 
 	if !MatchesIncrementalFlags(fromBackupConfig, &backupReport.BackupConfig) {
 		gplog.Fatal(errors.Errorf("The flags of the backup with timestamp = %s does not match "+
 			"that of the current one. Please refer to the report to view the flags supplied for the"+
 			"previous backup.", fromTimestampFPInfo.Timestamp), "")
 	}
+	if MustGetFlagBool(utils.DIFFERENTIAL) && !IsFullDataBackup(fromBackupConfig) {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s is not a full backup. "+
+			"Differential backups must be based on a full backup.", fromTimestampFPInfo.Timestamp), "")
+	}
 }
@@ -153,4 +153,31 @@ var _ = Describe("backup/validate tests", func() {
 			backup.ValidateCompressionLevel(compressLevel)
 		})
 	})
+	Describe("ValidateFlagValues", func() {
+		It("accepts the file and directory metadata formats", func() {
+			_ = cmdFlags.Set(utils.METADATA_FORMAT, utils.METADATA_FORMAT_DIRECTORY)
+			backup.ValidateFlagValues()
+		})
+		It("accepts the file metadata format with a plugin", func() {
+			_ = cmdFlags.Set(utils.METADATA_FORMAT, utils.METADATA_FORMAT_FILE)
+			_ = cmdFlags.Set(utils.PLUGIN_CONFIG, "/tmp/plugin_config")
+			backup.ValidateFlagValues()
+		})
+		It("panics if given the directory metadata format with a plugin", func() {
+			_ = cmdFlags.Set(utils.METADATA_FORMAT, utils.METADATA_FORMAT_DIRECTORY)
+			_ = cmdFlags.Set(utils.PLUGIN_CONFIG, "/tmp/plugin_config")
+			defer testhelper.ShouldPanicWithMessage("--metadata-format directory cannot be used with --plugin-config")
+			backup.ValidateFlagValues()
+		})
+		It("panics if given an invalid metadata format", func() {
+			_ = cmdFlags.Set(utils.METADATA_FORMAT, "tar")
+			defer testhelper.ShouldPanicWithMessage("Metadata format tar is invalid.  Valid values are file and directory.")
+			backup.ValidateFlagValues()
+		})
+		It("panics if given an invalid diff timestamp", func() {
+			_ = cmdFlags.Set(utils.DIFF_TO_TIMESTAMP, "2018")
+			defer testhelper.ShouldPanicWithMessage("Timestamp 2018 is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.")
+			backup.ValidateFlagValues()
+		})
+	})
 })
@@ -2,6 +2,7 @@ package backup
 
 import (
 	"fmt"
+	"path"
 	"reflect"
 	"strings"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/nightlyone/lockfile"
@@ -43,9 +45,10 @@ func InitializeConnectionPool() {
 	InitializeMetadataParams(connectionPool)
 	for connNum := 0; connNum < connectionPool.NumConns; connNum++ {
 		connectionPool.MustExec("SET application_name TO 'gpbackup'", connNum)
-		connectionPool.MustBegin(connNum)
 		SetSessionGUCs(connNum)
 	}
+	dataConsistency = GetDataConsistency(connectionPool.NumConns, SupportsSynchronizedSnapshots(connectionPool), MustGetFlagBool(utils.CONSISTENT))
+	BeginBackupTransactions()
 }
 
 func SetSessionGUCs(connNum int) {
@@ -63,8 +66,16 @@ func SetSessionGUCs(connNum int) {
 }
 
 func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plugin string, timestamp string) *backup_history.BackupConfig {
+	backupType := backup_history.FULL_BACKUP
+	if MustGetFlagBool(utils.INCREMENTAL) {
+		backupType = backup_history.INCREMENTAL_BACKUP
+	} else if MustGetFlagBool(utils.DIFFERENTIAL) {
+		backupType = backup_history.DIFFERENTIAL_BACKUP
+	}
 	backupConfig := backup_history.BackupConfig{
 		BackupDir:             MustGetFlagString(utils.BACKUP_DIR),
+		BackupGroup:           MustGetFlagString(utils.BACKUP_GROUP),
+		BackupType:            backupType,
 		BackupVersion:         backupVersion,
 		Compressed:            !MustGetFlagBool(utils.NO_COMPRESSION),
 		DatabaseName:          dbName,
@@ -78,13 +89,17 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 		IncludeSchemaFiltered: len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA)) > 0,
 		IncludeSchemas:        MustGetFlagStringSlice(utils.INCLUDE_SCHEMA),
 		IncludeTableFiltered:  len(MustGetFlagStringSlice(utils.INCLUDE_RELATION)) > 0,
-		Incremental:           MustGetFlagBool(utils.INCREMENTAL),
+		Incremental:           MustGetFlagBool(utils.INCREMENTAL) || MustGetFlagBool(utils.DIFFERENTIAL),
 		LeafPartitionData:     MustGetFlagBool(utils.LEAF_PARTITION_DATA),
+		MaskingRules:          GetMaskingRuleList(),
+		MetadataFormat:        MustGetFlagString(utils.METADATA_FORMAT),
 		MetadataOnly:          MustGetFlagBool(utils.METADATA_ONLY),
 		Plugin:                plugin,
 		SingleDataFile:        MustGetFlagBool(utils.SINGLE_DATA_FILE),
+		Subset:                MustGetFlagString(utils.TABLE_PREDICATE_FILE) != "",
 		Timestamp:             timestamp,
 		WithStatistics:        MustGetFlagBool(utils.WITH_STATS),
+		WithoutGlobals:        MustGetFlagBool(utils.WITHOUT_GLOBALS),
 	}
 
 	return &backupConfig
@@ -109,8 +124,12 @@ func InitializeBackupReport() {
 	}
 
 	backupReport = &utils.Report{
-		DatabaseSize: dbSize,
-		BackupConfig: *config,
+		DatabaseSize:    dbSize,
+		DataConsistency: dataConsistency,
+		BackupConfig:    *config,
+	}
+	for _, table := range GetTablePredicateTables(tablePredicates) {
+		backupReport.TablePredicates = append(backupReport.TablePredicates, fmt.Sprintf("%s: %s", table, tablePredicates[table]))
 	}
 	backupReport.ConstructBackupParamsString()
 }
@@ -126,6 +145,23 @@ func InitializeFilterLists() {
 		err := cmdFlags.Set(utils.INCLUDE_RELATION, strings.Join(includeRelations, ","))
 		gplog.FatalOnError(err)
 	}
+	if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA_PATTERN)) > 0 || len(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA_PATTERN)) > 0 {
+		schemaNames := GetSchemaNamesForPatternFiltering(connectionPool)
+		utils.ExpandPatternFlag(cmdFlags, utils.INCLUDE_SCHEMA_PATTERN, utils.INCLUDE_SCHEMA, schemaNames, "schemas", false)
+		utils.ExpandPatternFlag(cmdFlags, utils.EXCLUDE_SCHEMA_PATTERN, utils.EXCLUDE_SCHEMA, schemaNames, "schemas", true)
+	}
+	if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION_PATTERN)) > 0 || len(MustGetFlagStringSlice(utils.EXCLUDE_RELATION_PATTERN)) > 0 {
+		tableNames := GetTableNamesForPatternFiltering(connectionPool)
+		utils.ExpandPatternFlag(cmdFlags, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, tableNames, "tables", false)
+		utils.ExpandPatternFlag(cmdFlags, utils.EXCLUDE_RELATION_PATTERN, utils.EXCLUDE_RELATION, tableNames, "tables", true)
+	}
+}
+
+func InitializeTablePredicates() {
+	predicates, err := ReadTablePredicateFile(MustGetFlagString(utils.TABLE_PREDICATE_FILE))
+	gplog.FatalOnError(err)
+	ValidateTablePredicates(connectionPool, predicates)
+	tablePredicates = predicates
 }
 
 func CreateBackupLockFile(timestamp string) {
@@ -154,7 +190,10 @@ func CreateBackupDirectoriesOnAllHosts() {
 
 func RetrieveAndProcessTables() ([]Table, []Table) {
 	tableRelations := GetAllUserTableRelations(connectionPool)
-	LockTables(connectionPool, tableRelations)
+	if WritesBackupFiles() {
+		LockTables(GetLockConnection(), tableRelations, GetTableLockMode(), MustGetFlagInt(utils.LOCK_WAIT_TIMEOUT))
+	}
+	BeginTransactionsAfterLocks()
 
 	/*
 	 * We expand the includeRelations list to include parent and leaf partitions that may not have been
@@ -174,177 +213,34 @@ func RetrieveAndProcessTables() ([]Table, []Table) {
 	return metadataTables, dataTables
 }
 
-func RetrieveFunctions(sortables *[]Sortable, metadataMap MetadataMap, procLangs []ProceduralLanguage) ([]Function, MetadataMap) {
-	gplog.Verbose("Retrieving function information")
-	functions := GetFunctionsAllVersions(connectionPool)
-	objectCounts["Functions"] = len(functions)
-	functionMetadata := GetMetadataForObjectType(connectionPool, TYPE_FUNCTION)
-	langFuncs, otherFuncs := ExtractLanguageFunctions(functions, procLangs)
+func RetrieveFunctions(sortables *[]Sortable, metadataMap MetadataMap, objects PredataObjects) []Function {
+	objectCounts["Functions"] = len(objects.Functions)
+	langFuncs, otherFuncs := ExtractLanguageFunctions(objects.Functions, objects.ProcLangs)
 
 	*sortables = append(*sortables, convertToSortableSlice(otherFuncs)...)
-	addToMetadataMap(functionMetadata, metadataMap)
-
-	return langFuncs, functionMetadata
-}
-
-func RetrieveTypes(sortables *[]Sortable, metadataMap MetadataMap) ([]Type, MetadataMap) {
-	gplog.Verbose("Retrieving type information")
-	shells := GetShellTypes(connectionPool)
-	bases := GetBaseTypes(connectionPool)
-	types := append(shells, bases...)
-	composites := GetCompositeTypes(connectionPool)
-	types = append(types, composites...)
-	domains := GetDomainTypes(connectionPool)
-	types = append(types, domains...)
-	if connectionPool.Version.AtLeast("6") {
-		ranges := GetRangeTypes(connectionPool)
-		types = append(types, ranges...)
-	}
-	objectCounts["Types"] = len(types)
-	typeMetadata := GetMetadataForObjectType(connectionPool, TYPE_TYPE)
-
-	*sortables = append(*sortables, convertToSortableSlice(types)...)
-	addToMetadataMap(typeMetadata, metadataMap)
-
-	return types, typeMetadata
-}
-
-func RetrieveConstraints(tables ...Relation) ([]Constraint, MetadataMap) {
-	constraints := GetConstraints(connectionPool, tables...)
-	conMetadata := GetCommentsForObjectType(connectionPool, TYPE_CONSTRAINT)
-	return constraints, conMetadata
-}
-
-func RetrieveSequences() ([]Sequence, map[string]string) {
-	sequenceOwnerTables, sequenceOwnerColumns := GetSequenceColumnOwnerMap(connectionPool)
-	sequences := GetAllSequences(connectionPool, sequenceOwnerTables)
-	return sequences, sequenceOwnerColumns
-}
-
-func RetrieveProtocols(sortables *[]Sortable, metadataMap MetadataMap) []ExternalProtocol {
-	protocols := GetExternalProtocols(connectionPool)
-	objectCounts["Protocols"] = len(protocols)
-	protoMetadata := GetMetadataForObjectType(connectionPool, TYPE_PROTOCOL)
-
-	*sortables = append(*sortables, convertToSortableSlice(protocols)...)
-	addToMetadataMap(protoMetadata, metadataMap)
-
-	return protocols
-}
-
-func RetrieveViews(sortables *[]Sortable) {
-	views := GetViews(connectionPool)
-	objectCounts["Views"] = len(views)
-
-	*sortables = append(*sortables, convertToSortableSlice(views)...)
-}
-
-func RetrieveTSParsers(sortables *[]Sortable, metadataMap MetadataMap) {
-	parsers := GetTextSearchParsers(connectionPool)
-	objectCounts["Text Search Parsers"] = len(parsers)
-	parserMetadata := GetCommentsForObjectType(connectionPool, TYPE_TSPARSER)
-
-	*sortables = append(*sortables, convertToSortableSlice(parsers)...)
-	addToMetadataMap(parserMetadata, metadataMap)
-}
-
-func RetrieveTSTemplates(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving TEXT SEARCH TEMPLATE information")
-	templates := GetTextSearchTemplates(connectionPool)
-	objectCounts["Text Search Templates"] = len(templates)
-	templateMetadata := GetCommentsForObjectType(connectionPool, TYPE_TSTEMPLATE)
+	addToMetadataMap(objects.FunctionMetadata, metadataMap)
 
-	*sortables = append(*sortables, convertToSortableSlice(templates)...)
-	addToMetadataMap(templateMetadata, metadataMap)
+	return langFuncs
 }
 
-func RetrieveTSDictionaries(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving TEXT SEARCH DICTIONARY information")
-	dictionaries := GetTextSearchDictionaries(connectionPool)
-	objectCounts["Text Search Dictionaries"] = len(dictionaries)
-	dictionaryMetadata := GetMetadataForObjectType(connectionPool, TYPE_TSDICTIONARY)
+func RetrieveTypes(sortables *[]Sortable, metadataMap MetadataMap, objects PredataObjects) []Type {
+	types := append(objects.ShellTypes, objects.BaseTypes...)
+	types = append(types, objects.CompositeTypes...)
+	types = append(types, objects.DomainTypes...)
+	types = append(types, objects.RangeTypes...)
+	addSortableObjects(sortables, metadataMap, "Types", types, objects.TypeMetadata)
 
-	*sortables = append(*sortables, convertToSortableSlice(dictionaries)...)
-	addToMetadataMap(dictionaryMetadata, metadataMap)
+	return types
 }
 
-func RetrieveTSConfigurations(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving TEXT SEARCH CONFIGURATION information")
-	configurations := GetTextSearchConfigurations(connectionPool)
-	objectCounts["Text Search Configurations"] = len(configurations)
-	configurationMetadata := GetMetadataForObjectType(connectionPool, TYPE_TSCONFIGURATION)
-
-	*sortables = append(*sortables, convertToSortableSlice(configurations)...)
-	addToMetadataMap(configurationMetadata, metadataMap)
-}
-
-func RetrieveOperators(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving OPERATOR information")
-	operators := GetOperators(connectionPool)
-	objectCounts["Operators"] = len(operators)
-	operatorMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATOR)
-
-	*sortables = append(*sortables, convertToSortableSlice(operators)...)
-	addToMetadataMap(operatorMetadata, metadataMap)
-}
-
-func RetrieveOperatorClasses(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving OPERATOR CLASS information")
-	operatorClasses := GetOperatorClasses(connectionPool)
-	objectCounts["Operator Classes"] = len(operatorClasses)
-	operatorClassMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATORCLASS)
-
-	*sortables = append(*sortables, convertToSortableSlice(operatorClasses)...)
-	addToMetadataMap(operatorClassMetadata, metadataMap)
-}
-
-func RetrieveAggregates(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving AGGREGATE information")
-	aggregates := GetAggregates(connectionPool)
-	objectCounts["Aggregates"] = len(aggregates)
-	aggMetadata := GetMetadataForObjectType(connectionPool, TYPE_AGGREGATE)
-
-	*sortables = append(*sortables, convertToSortableSlice(aggregates)...)
-	addToMetadataMap(aggMetadata, metadataMap)
-}
-
-func RetrieveCasts(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Retrieving CAST information")
-	casts := GetCasts(connectionPool)
-	objectCounts["Casts"] = len(casts)
-	castMetadata := GetCommentsForObjectType(connectionPool, TYPE_CAST)
-
-	*sortables = append(*sortables, convertToSortableSlice(casts)...)
-	addToMetadataMap(castMetadata, metadataMap)
-}
-
-func RetrieveForeignDataWrappers(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Writing CREATE FOREIGN DATA WRAPPER statements to metadata file")
-	wrappers := GetForeignDataWrappers(connectionPool)
-	objectCounts["Foreign Data Wrappers"] = len(wrappers)
-	fdwMetadata := GetMetadataForObjectType(connectionPool, TYPE_FOREIGNDATAWRAPPER)
-
-	*sortables = append(*sortables, convertToSortableSlice(wrappers)...)
-	addToMetadataMap(fdwMetadata, metadataMap)
-}
-
-func RetrieveForeignServers(sortables *[]Sortable, metadataMap MetadataMap) {
-	gplog.Verbose("Writing CREATE SERVER statements to metadata file")
-	servers := GetForeignServers(connectionPool)
-	objectCounts["Foreign Servers"] = len(servers)
-	serverMetadata := GetMetadataForObjectType(connectionPool, TYPE_FOREIGNSERVER)
-
-	*sortables = append(*sortables, convertToSortableSlice(servers)...)
-	addToMetadataMap(serverMetadata, metadataMap)
-}
-
-func RetrieveUserMappings(sortables *[]Sortable) {
-	gplog.Verbose("Writing CREATE USER MAPPING statements to metadata file")
-	mappings := GetUserMappings(connectionPool)
-	objectCounts["User Mappings"] = len(mappings)
-	// No comments, owners, or ACLs on UserMappings so no need to get metadata
-
-	*sortables = append(*sortables, convertToSortableSlice(mappings)...)
+/*
+ * The count of each kind of object is taken before convertToSortableSlice
+ * filters out any objects that are not sorted by dependency.
+ */
+func addSortableObjects(sortables *[]Sortable, metadataMap MetadataMap, objectType string, objSlice interface{}, objMetadata MetadataMap) {
+	objectCounts[objectType] = reflect.ValueOf(objSlice).Len()
+	*sortables = append(*sortables, convertToSortableSlice(objSlice)...)
+	addToMetadataMap(objMetadata, metadataMap)
 }
 
 /*
@@ -363,6 +259,7 @@ func LogBackupInfo() {
 func BackupSessionGUCs(metadataFile *utils.FileWithByteCount) {
 	gucs := GetSessionGUCs(connectionPool)
 	PrintSessionGUCs(metadataFile, globalTOC, gucs)
+	catalogModel.AddObjects([]SessionGUCs{gucs}, nil)
 }
 
 /*
@@ -375,6 +272,7 @@ func BackupTablespaces(metadataFile *utils.FileWithByteCount) {
 	objectCounts["Tablespaces"] = len(tablespaces)
 	tablespaceMetadata := GetMetadataForObjectType(connectionPool, TYPE_TABLESPACE)
 	PrintCreateTablespaceStatements(metadataFile, globalTOC, tablespaces, tablespaceMetadata)
+	catalogModel.AddObjects(tablespaces, tablespaceMetadata)
 }
 
 func BackupCreateDatabase(metadataFile *utils.FileWithByteCount) {
@@ -383,6 +281,7 @@ func BackupCreateDatabase(metadataFile *utils.FileWithByteCount) {
 	db := GetDatabaseInfo(connectionPool)
 	dbMetadata := GetMetadataForObjectType(connectionPool, TYPE_DATABASE)
 	PrintCreateDatabaseStatement(metadataFile, globalTOC, defaultDB, db, dbMetadata)
+	catalogModel.AddObjects([]Database{db}, dbMetadata)
 }
 
 func BackupDatabaseGUCs(metadataFile *utils.FileWithByteCount) {
@@ -398,6 +297,7 @@ func BackupResourceQueues(metadataFile *utils.FileWithByteCount) {
 	objectCounts["Resource Queues"] = len(resQueues)
 	resQueueMetadata := GetCommentsForObjectType(connectionPool, TYPE_RESOURCEQUEUE)
 	PrintCreateResourceQueueStatements(metadataFile, globalTOC, resQueues, resQueueMetadata)
+	catalogModel.AddObjects(resQueues, resQueueMetadata)
 }
 
 func BackupResourceGroups(metadataFile *utils.FileWithByteCount) {
@@ -407,6 +307,7 @@ func BackupResourceGroups(metadataFile *utils.FileWithByteCount) {
 	resGroupMetadata := GetCommentsForObjectType(connectionPool, TYPE_RESOURCEGROUP)
 	PrintResetResourceGroupStatements(metadataFile, globalTOC)
 	PrintCreateResourceGroupStatements(metadataFile, globalTOC, resGroups, resGroupMetadata)
+	catalogModel.AddObjects(resGroups, resGroupMetadata)
 }
 
 func BackupRoles(metadataFile *utils.FileWithByteCount) {
@@ -415,39 +316,39 @@ func BackupRoles(metadataFile *utils.FileWithByteCount) {
 	objectCounts["Roles"] = len(roles)
 	roleMetadata := GetMetadataForObjectType(connectionPool, TYPE_ROLE)
 	PrintCreateRoleStatements(metadataFile, globalTOC, roles, roleMetadata)
+	catalogModel.AddObjects(roles, roleMetadata)
 }
 
 func BackupRoleGUCs(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing ROLE GUC statements to metadata file")
 	roleGUCs := GetRoleGUCs(connectionPool)
 	PrintRoleGUCStatements(metadataFile, globalTOC, roleGUCs)
+	catalogModel.AddRoleGUCs(roleGUCs)
 }
 
 func BackupRoleGrants(metadataFile *utils.FileWithByteCount) {
 	gplog.Verbose("Writing GRANT ROLE statements to metadata file")
 	roleMembers := GetRoleMembers(connectionPool)
 	PrintRoleMembershipStatements(metadataFile, globalTOC, roleMembers)
+	catalogModel.AddObjects(roleMembers, nil)
 }
 
 /*
  * Predata wrapper functions
  */
 
-func BackupSchemas(metadataFile *utils.FileWithByteCount) {
+func BackupSchemas(metadataFile *utils.FileWithByteCount, schemas []Schema, schemaMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE SCHEMA statements to metadata file")
-	schemas := GetAllUserSchemas(connectionPool)
 	objectCounts["Schemas"] = len(schemas)
-	schemaMetadata := GetMetadataForObjectType(connectionPool, TYPE_SCHEMA)
 	PrintCreateSchemaStatements(metadataFile, globalTOC, schemas, schemaMetadata)
 }
 
-func BackupProceduralLanguages(metadataFile *utils.FileWithByteCount, procLangs []ProceduralLanguage, langFuncs []Function, functionMetadata MetadataMap, funcInfoMap map[uint32]FunctionInfo) {
+func BackupProceduralLanguages(metadataFile *utils.FileWithByteCount, procLangs []ProceduralLanguage, langFuncs []Function, functionMetadata MetadataMap, funcInfoMap map[uint32]FunctionInfo, procLangMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE PROCEDURAL LANGUAGE statements to metadata file")
 	objectCounts["Procedural Languages"] = len(procLangs)
 	for _, langFunc := range langFuncs {
 		PrintCreateFunctionStatement(metadataFile, globalTOC, langFunc, functionMetadata[langFunc.GetUniqueID()])
 	}
-	procLangMetadata := GetMetadataForObjectType(connectionPool, TYPE_PROCLANGUAGE)
 	PrintCreateLanguageStatements(metadataFile, globalTOC, procLangs, funcInfoMap, procLangMetadata)
 }
 
@@ -456,8 +357,7 @@ func BackupShellTypes(metadataFile *utils.FileWithByteCount, types []Type) {
 	PrintCreateShellTypeStatements(metadataFile, globalTOC, types)
 }
 
-func BackupEnumTypes(metadataFile *utils.FileWithByteCount, typeMetadata MetadataMap) {
-	enums := GetEnumTypes(connectionPool)
+func BackupEnumTypes(metadataFile *utils.FileWithByteCount, enums []Type, typeMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE TYPE statements for enum types to metadata file")
 	objectCounts["Types"] += len(enums)
 	PrintCreateEnumTypeStatements(metadataFile, globalTOC, enums, typeMetadata)
@@ -513,7 +413,7 @@ func addToMetadataMap(newMetadata MetadataMap, metadataMap MetadataMap) {
 func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Table,
 	protocols []ExternalProtocol, filteredMetadata MetadataMap,
 	constraints []Constraint, sortables []Sortable, funcInfoMap map[uint32]FunctionInfo,
-	tableOnly bool) {
+	extPartInfo []PartitionInfo, partInfoMap map[uint32]PartitionInfo, tableOnly bool) {
 
 	gplog.Verbose("Writing CREATE statements for dependent objects to metadata file")
 
@@ -523,44 +423,37 @@ func BackupDependentObjects(metadataFile *utils.FileWithByteCount, tables []Tabl
 		AddProtocolDependenciesForGPDB4(relevantDeps, tables, protocols)
 	}
 	sortedSlice := TopologicalSort(sortables, relevantDeps)
+	dependencyKeys := AddDependenciesToTOC(globalTOC, sortedSlice, relevantDeps)
+	catalogModel.SetDependencies(dependencyKeys)
 
 	PrintDependentObjectStatements(metadataFile, globalTOC, sortedSlice, filteredMetadata, constraints, funcInfoMap)
-	extPartInfo, partInfoMap := GetExternalPartitionInfo(connectionPool)
 	if len(extPartInfo) > 0 {
 		gplog.Verbose("Writing EXCHANGE PARTITION statements to metadata file")
 		PrintExchangeExternalPartitionStatements(metadataFile, globalTOC, extPartInfo, partInfoMap, tables)
 	}
 }
 
-func BackupConversions(metadataFile *utils.FileWithByteCount) {
+func BackupConversions(metadataFile *utils.FileWithByteCount, conversions []Conversion, convMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE CONVERSION statements to metadata file")
-	conversions := GetConversions(connectionPool)
 	objectCounts["Conversions"] = len(conversions)
-	convMetadata := GetMetadataForObjectType(connectionPool, TYPE_CONVERSION)
 	PrintCreateConversionStatements(metadataFile, globalTOC, conversions, convMetadata)
 }
 
-func BackupOperatorFamilies(metadataFile *utils.FileWithByteCount) {
+func BackupOperatorFamilies(metadataFile *utils.FileWithByteCount, operatorFamilies []OperatorFamily, operatorFamilyMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE OPERATOR FAMILY statements to metadata file")
-	operatorFamilies := GetOperatorFamilies(connectionPool)
 	objectCounts["Operator Families"] = len(operatorFamilies)
-	operatorFamilyMetadata := GetMetadataForObjectType(connectionPool, TYPE_OPERATORFAMILY)
 	PrintCreateOperatorFamilyStatements(metadataFile, globalTOC, operatorFamilies, operatorFamilyMetadata)
 }
 
-func BackupCollations(metadataFile *utils.FileWithByteCount) {
+func BackupCollations(metadataFile *utils.FileWithByteCount, collations []Collation, collationMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE COLLATION statements to metadata file")
-	collations := GetCollations(connectionPool)
 	objectCounts["Collations"] = len(collations)
-	collationMetadata := GetMetadataForObjectType(connectionPool, TYPE_COLLATION)
 	PrintCreateCollationStatements(metadataFile, globalTOC, collations, collationMetadata)
 }
 
-func BackupExtensions(metadataFile *utils.FileWithByteCount) {
+func BackupExtensions(metadataFile *utils.FileWithByteCount, extensions []Extension, extensionMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE EXTENSION statements to metadata file")
-	extensions := GetExtensions(connectionPool)
 	objectCounts["Extensions"] = len(extensions)
-	extensionMetadata := GetCommentsForObjectType(connectionPool, TYPE_EXTENSION)
 	PrintCreateExtensionStatements(metadataFile, globalTOC, extensions, extensionMetadata)
 }
 
@@ -574,45 +467,51 @@ func BackupConstraints(metadataFile *utils.FileWithByteCount, constraints []Cons
  * Postdata wrapper functions
  */
 
-func BackupIndexes(metadataFile *utils.FileWithByteCount) {
+func BackupIndexes(metadataFile *utils.FileWithByteCount, indexes []IndexDefinition, indexMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE INDEX statements to metadata file")
-	indexes := GetIndexes(connectionPool)
 	objectCounts["Indexes"] = len(indexes)
-	indexMetadata := GetCommentsForObjectType(connectionPool, TYPE_INDEX)
 	PrintCreateIndexStatements(metadataFile, globalTOC, indexes, indexMetadata)
 }
 
-func BackupRules(metadataFile *utils.FileWithByteCount) {
+func BackupRules(metadataFile *utils.FileWithByteCount, rules []RuleDefinition, ruleMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE RULE statements to metadata file")
-	rules := GetRules(connectionPool)
 	objectCounts["Rules"] = len(rules)
-	ruleMetadata := GetCommentsForObjectType(connectionPool, TYPE_RULE)
 	PrintCreateRuleStatements(metadataFile, globalTOC, rules, ruleMetadata)
 }
 
-func BackupTriggers(metadataFile *utils.FileWithByteCount) {
+func BackupTriggers(metadataFile *utils.FileWithByteCount, triggers []TriggerDefinition, triggerMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE TRIGGER statements to metadata file")
-	triggers := GetTriggers(connectionPool)
 	objectCounts["Triggers"] = len(triggers)
-	triggerMetadata := GetCommentsForObjectType(connectionPool, TYPE_TRIGGER)
 	PrintCreateTriggerStatements(metadataFile, globalTOC, triggers, triggerMetadata)
 }
 
-func BackupEventTriggers(metadataFile *utils.FileWithByteCount) {
+func BackupEventTriggers(metadataFile *utils.FileWithByteCount, eventTriggers []EventTrigger, eventTriggerMetadata MetadataMap) {
 	gplog.Verbose("Writing CREATE EVENT TRIGGER statements to metadata file")
-	eventTriggers := GetEventTriggers(connectionPool)
 	objectCounts["Event Triggers"] = len(eventTriggers)
-	eventTriggerMetadata := GetMetadataForObjectType(connectionPool, TYPE_EVENTTRIGGER)
 	PrintCreateEventTriggerStatements(metadataFile, globalTOC, eventTriggers, eventTriggerMetadata)
 }
 
-func BackupDefaultPrivileges(metadataFile *utils.FileWithByteCount) {
+func BackupDefaultPrivileges(metadataFile *utils.FileWithByteCount, defaultPrivileges []DefaultPrivileges) {
 	gplog.Verbose("Writing ALTER DEFAULT PRIVILEGES statements to metadata file")
-	defaultPrivileges := GetDefaultPrivileges(connectionPool)
 	objectCounts["DEFAULT PRIVILEGES"] = len(defaultPrivileges)
 	PrintDefaultPrivilegesStatements(metadataFile, globalTOC, defaultPrivileges)
 }
 
+/*
+ * The metadata is always written to a single file first, as each statement's
+ * TOC entry is recorded by byte range, and is then split into one file per
+ * entry under the backup directory if --metadata-format is directory.
+ */
+func WriteMetadataDirectory(metadataFilename string) {
+	backupDir := path.Dir(metadataFilename)
+	gplog.Info("Writing metadata to %s", path.Join(backupDir, "metadata"))
+	metadataFile := iohelper.MustOpenFileForReading(metadataFilename)
+	globalTOC.WriteMetadataDirectory(metadataFile, backupDir)
+	_ = metadataFile.Close()
+	err := operating.System.Remove(metadataFilename)
+	gplog.FatalOnError(err)
+}
+
 /*
  * Data wrapper functions
  */
@@ -625,7 +524,14 @@ func BackupStatistics(statisticsFile *utils.FileWithByteCount, tables []Table) {
 	PrintStatisticsStatements(statisticsFile, globalTOC, tables, attStats, tupleStats)
 }
 
-func BackupIncrementalMetadata() {
+func BackupIncrementalMetadata(tables []Table) {
+	startTime := operating.System.Now()
+	defer func() {
+		backupReport.IncrementalMetadataDuration = operating.System.Now().Sub(startTime)
+	}()
 	aoTableEntries := GetAOIncrementalMetadata(connectionPool)
 	globalTOC.IncrementalMetadata.AO = aoTableEntries
+	if MustGetFlagBool(utils.TRACK_HEAP_CHANGES) {
+		globalTOC.IncrementalMetadata.Heap = GetHeapIncrementalMetadata(connectionPool, tables, aoTableEntries)
+	}
 }
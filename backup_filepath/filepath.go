@@ -97,6 +97,8 @@ func (backupFPInfo *FilePathInfo) GetTableBackupFilePathForCopyCommand(tableOid
 }
 
 var metadataFilenameMap = map[string]string{
+	"catalog model":     "catalog.json",
+	"completed tables":  "completed_tables.jsonl",
 	"config":            "config.yaml",
 	"metadata":          "metadata.sql",
 	"statistics":        "statistics.sql",
@@ -117,6 +119,10 @@ func (backupFPInfo *FilePathInfo) GetMetadataFilePath() string {
 	return backupFPInfo.GetBackupFilePath("metadata")
 }
 
+func (backupFPInfo *FilePathInfo) GetCatalogModelFilePath() string {
+	return backupFPInfo.GetBackupFilePath("catalog model")
+}
+
 func (backupFPInfo *FilePathInfo) GetStatisticsFilePath() string {
 	return backupFPInfo.GetBackupFilePath("statistics")
 }
@@ -133,6 +139,14 @@ func (backupFPInfo *FilePathInfo) GetRestoreReportFilePath(restoreTimestamp stri
 	return path.Join(backupFPInfo.GetDirForContent(-1), fmt.Sprintf("gprestore_%s_%s_report", backupFPInfo.Timestamp, restoreTimestamp))
 }
 
+func (backupFPInfo *FilePathInfo) GetRestoreErrorFilePath(restoreTimestamp string) string {
+	return path.Join(backupFPInfo.GetDirForContent(-1), fmt.Sprintf("gprestore_%s_%s_errors.jsonl", backupFPInfo.Timestamp, restoreTimestamp))
+}
+
+func (backupFPInfo *FilePathInfo) GetCompletedTablesFilePath() string {
+	return backupFPInfo.GetBackupFilePath("completed tables")
+}
+
 func (backupFPInfo *FilePathInfo) GetConfigFilePath() string {
 	return backupFPInfo.GetBackupFilePath("config")
 }
@@ -92,6 +92,24 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetBackupReportFilePath()).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_report"))
 		})
 	})
+	Describe("GetCatalogModelFilePath", func() {
+		It("returns catalog model file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetCatalogModelFilePath()).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_catalog.json"))
+		})
+	})
+	Describe("GetCompletedTablesFilePath", func() {
+		It("returns completed tables file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetCompletedTablesFilePath()).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_completed_tables.jsonl"))
+		})
+	})
+	Describe("GetRestoreErrorFilePath", func() {
+		It("returns restore error file path", func() {
+			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
+			Expect(fpInfo.GetRestoreErrorFilePath("20170101010102")).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gprestore_20170101010101_20170101010102_errors.jsonl"))
+		})
+	})
 	Describe("GetTableBackupFilePath", func() {
 		It("returns table file path", func() {
 			fpInfo := backup_filepath.NewFilePathInfo(c, "", "20170101010101", "gpseg")
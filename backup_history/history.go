@@ -13,6 +13,12 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	FULL_BACKUP         = "full"
+	INCREMENTAL_BACKUP  = "incremental"
+	DIFFERENTIAL_BACKUP = "differential"
+)
+
 type RestorePlanEntry struct {
 	Timestamp string
 	TableFQNs []string
@@ -20,6 +26,8 @@ type RestorePlanEntry struct {
 
 type BackupConfig struct {
 	BackupDir             string
+	BackupGroup           string `yaml:",omitempty"`
+	BackupType            string
 	BackupVersion         string
 	Compressed            bool
 	DatabaseName          string
@@ -36,12 +44,31 @@ type BackupConfig struct {
 	IncludeTableFiltered  bool
 	Incremental           bool
 	LeafPartitionData     bool
+	MaskingRules          []string `yaml:",omitempty"`
+	MetadataFormat        string   `yaml:",omitempty"`
 	MetadataOnly          bool
 	Plugin                string
 	RestorePlan           []RestorePlanEntry
 	SingleDataFile        bool
+	Subset                bool
 	Timestamp             string
 	WithStatistics        bool
+	WithoutGlobals        bool `yaml:",omitempty"`
+}
+
+/*
+ * Backups taken before the backup type was recorded are either full or
+ * incremental backups.  Differential backups also have Incremental set, as
+ * they depend on an earlier backup.
+ */
+func (config *BackupConfig) GetBackupType() string {
+	if config.BackupType != "" {
+		return config.BackupType
+	}
+	if config.Incremental {
+		return INCREMENTAL_BACKUP
+	}
+	return FULL_BACKUP
 }
 
 func ReadConfigFile(filename string) *BackupConfig {
@@ -80,6 +107,23 @@ func NewHistory(filename string) (*History, error) {
 	return history, nil
 }
 
+/*
+ * Returns the backups in the given backup group in the order in which they
+ * were taken, so that the backup containing the global objects is first.
+ */
+func (history *History) GetBackupGroup(groupID string) []BackupConfig {
+	members := make([]BackupConfig, 0)
+	for _, config := range history.BackupConfigs {
+		if config.BackupGroup == groupID && !config.Deleted {
+			members = append(members, config)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Timestamp < members[j].Timestamp
+	})
+	return members
+}
+
 func (history *History) AddBackupConfig(backupConfig *BackupConfig) {
 	history.BackupConfigs = append(history.BackupConfigs, *backupConfig)
 	sort.Slice(history.BackupConfigs, func(i, j int) bool {
@@ -148,6 +148,42 @@ var _ bool = Describe("backup/history tests", func() {
 			})
 		})
 	})
+	Describe("GetBackupType", func() {
+		It("returns the recorded backup type", func() {
+			config := backup_history.BackupConfig{BackupType: "differential", Incremental: true}
+			Expect(config.GetBackupType()).To(Equal(backup_history.DIFFERENTIAL_BACKUP))
+		})
+		It("returns incremental for an incremental backup without a recorded backup type", func() {
+			config := backup_history.BackupConfig{Incremental: true}
+			Expect(config.GetBackupType()).To(Equal(backup_history.INCREMENTAL_BACKUP))
+		})
+		It("returns full for a non-incremental backup without a recorded backup type", func() {
+			config := backup_history.BackupConfig{}
+			Expect(config.GetBackupType()).To(Equal(backup_history.FULL_BACKUP))
+		})
+	})
+	Describe("GetBackupGroup", func() {
+		It("returns the backups in the group in the order in which they were taken", func() {
+			testHistory := backup_history.History{
+				BackupConfigs: []backup_history.BackupConfig{
+					{DatabaseName: "db3", Timestamp: "20170101010103", BackupGroup: "20170101010101"},
+					{DatabaseName: "other", Timestamp: "20170101010102"},
+					{DatabaseName: "deleted", Timestamp: "20170101010102", BackupGroup: "20170101010101", Deleted: true},
+					{DatabaseName: "db1", Timestamp: "20170101010101", BackupGroup: "20170101010101"},
+				},
+			}
+
+			members := testHistory.GetBackupGroup("20170101010101")
+
+			Expect(members).To(HaveLen(2))
+			Expect(members[0].DatabaseName).To(Equal("db1"))
+			Expect(members[1].DatabaseName).To(Equal("db3"))
+		})
+		It("returns no backups for an unknown group", func() {
+			testHistory := backup_history.History{BackupConfigs: []backup_history.BackupConfig{testConfig1}}
+			Expect(testHistory.GetBackupGroup("20170101010101")).To(BeEmpty())
+		})
+	})
 	Describe("AddBackupConfig", func() {
 		It("adds the most recent history entry and keeps the list sorted", func() {
 			testHistory := backup_history.History{
@@ -411,6 +411,25 @@ var _ = Describe("backup end to end integration tests", func() {
 				os.Remove("/tmp/exclude-tables.txt")
 			})
 		})
+		Describe("Combined include and exclude filtering", func() {
+			It("runs gpbackup with include-schema and exclude-table backup flags", func() {
+				if useOldBackupVersion {
+					Skip("This test is not needed for old backup versions")
+				}
+				timestamp := gpbackup(gpbackupPath, backupHelperPath, "--include-schema", "schema2", "--exclude-table", "schema2.foo2")
+				gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb")
+
+				assertRelationsCreated(restoreConn, 16)
+				assertDataRestored(restoreConn, map[string]int{"schema2.returns": 6, "schema2.foo3": 100, "schema2.ao1": 1000, "schema2.ao2": 1000})
+			})
+			It("runs gprestore with include-schema and exclude-table restore flags", func() {
+				timestamp := gpbackup(gpbackupPath, backupHelperPath)
+				gprestore(gprestorePath, restoreHelperPath, timestamp, "--redirect-db", "restoredb", "--include-schema", "schema2", "--exclude-table", "schema2.foo2")
+
+				assertRelationsCreated(restoreConn, 16)
+				assertDataRestored(restoreConn, map[string]int{"schema2.returns": 6, "schema2.foo3": 100, "schema2.ao1": 1000, "schema2.ao2": 1000})
+			})
+		})
 		Describe("Restore exclude filtering", func() {
 			It("runs gpbackup and gprestore with exclude-schema restore flag", func() {
 				timestamp := gpbackup(gpbackupPath, backupHelperPath)
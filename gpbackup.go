@@ -19,8 +19,20 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			defer DoTeardown()
 			DoFlagValidation(cmd)
+			if IsBackupGroup() {
+				DoBackupGroup()
+				return
+			}
 			DoSetup()
-			DoBackup()
+			if MustGetFlagString(utils.CONSOLIDATE) != "" {
+				DoConsolidate()
+			} else if MustGetFlagBool(utils.LIST_ONLY) {
+				DoListOnly()
+			} else if MustGetFlagString(utils.DIFF_TIMESTAMP) != "" {
+				DoDiff()
+			} else {
+				DoBackup()
+			}
 		}}
 	rootCmd.SetArgs(utils.HandleSingleDashes(os.Args[1:]))
 	DoInit(rootCmd)
@@ -19,8 +19,14 @@ func main() {
 		Run: func(cmd *cobra.Command, args []string) {
 			defer DoTeardown()
 			DoValidation(cmd)
+			if MustGetFlagString(utils.BACKUP_GROUP) != "" {
+				DoRestoreGroup()
+				return
+			}
 			DoSetup()
-			DoRestore()
+			if !MustGetFlagBool(utils.LIST_RESTORE_POINTS) {
+				DoRestore()
+			}
 		}}
 	rootCmd.SetArgs(utils.HandleSingleDashes(os.Args[1:]))
 	DoInit(rootCmd)
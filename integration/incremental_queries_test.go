@@ -261,4 +261,42 @@ var _ = Describe("backup integration tests", func() {
 			})
 		})
 	})
+	Describe("GetHeapIncrementalMetadata", func() {
+		var heapTableFQN = "public.heap_foo"
+		var heapTable backup.Table
+		BeforeEach(func() {
+			backup.SetCluster(testCluster)
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("CREATE TABLE %s (i int) DISTRIBUTED BY (i)", heapTableFQN))
+			heapTable = backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_foo"}}
+		})
+		AfterEach(func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(dropTableSQL, heapTableFQN))
+		})
+		It("should have a last DDL timestamp and an empty checksum on every segment for a new table", func() {
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool, []backup.Table{heapTable}, map[string]utils.AOEntry{})
+
+			Expect(heapIncrementalMetadata[heapTableFQN].LastDDLTimestamp).To(Not(BeEmpty()))
+			Expect(heapIncrementalMetadata[heapTableFQN].Fingerprints).To(HaveLen(len(testCluster.ContentIDs) - 1))
+			for _, fingerprint := range heapIncrementalMetadata[heapTableFQN].Fingerprints {
+				Expect(fingerprint.NumRows).To(Equal(int64(0)))
+			}
+		})
+		It("should change the checksum after an insert", func() {
+			initialHeapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool, []backup.Table{heapTable}, map[string]utils.AOEntry{})
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(insertSQL, heapTableFQN))
+
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool, []backup.Table{heapTable}, map[string]utils.AOEntry{})
+
+			Expect(heapIncrementalMetadata[heapTableFQN].Fingerprints).To(Not(Equal(initialHeapIncrementalMetadata[heapTableFQN].Fingerprints)))
+		})
+		It("should not include AO tables", func() {
+			aoTable := backup.Table{Relation: backup.Relation{Schema: "public", Name: "ao_foo"}}
+			aoIncrementalMetadata := backup.GetAOIncrementalMetadata(connectionPool)
+
+			heapIncrementalMetadata := backup.GetHeapIncrementalMetadata(connectionPool, []backup.Table{heapTable, aoTable}, aoIncrementalMetadata)
+
+			Expect(heapIncrementalMetadata).To(HaveKey(heapTableFQN))
+			Expect(heapIncrementalMetadata).To(Not(HaveKey(aoTableFQN)))
+		})
+	})
 })
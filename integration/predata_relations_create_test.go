@@ -434,6 +434,26 @@ SET SUBPARTITION TEMPLATE ` + `
 			Expect(resultViews).To(HaveLen(1))
 			structmatcher.ExpectStructsToMatch(&view, &resultViews[0])
 		})
+		It("creates a materialized view with privileges, owner, security label, and comment", func() {
+			testutils.SkipIfBefore6(connectionPool)
+			view := backup.View{Oid: 1, Schema: "public", Name: "simplematview", Definition: " SELECT 1 AS a;",
+				IsMaterialized: true, DistPolicy: "DISTRIBUTED BY (a)"}
+			viewMetadata := testutils.DefaultMetadata("MATERIALIZED VIEW", true, true, true, includeSecurityLabels)
+
+			backup.PrintCreateViewStatement(backupfile, toc, view, viewMetadata)
+
+			testhelper.AssertQueryRuns(connectionPool, buffer.String())
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP MATERIALIZED VIEW public.simplematview")
+
+			resultViews := backup.GetViews(connectionPool)
+			resultMetadataMap := backup.GetMetadataForObjectType(connectionPool, backup.TYPE_RELATION)
+
+			view.Oid = testutils.OidFromObjectName(connectionPool, "public", "simplematview", backup.TYPE_RELATION)
+			Expect(resultViews).To(HaveLen(1))
+			resultMetadata := resultMetadataMap[view.GetUniqueID()]
+			structmatcher.ExpectStructsToMatch(&view, &resultViews[0])
+			structmatcher.ExpectStructsToMatch(&viewMetadata, &resultMetadata)
+		})
 	})
 	Describe("PrintCreateSequenceStatements", func() {
 		var (
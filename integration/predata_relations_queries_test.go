@@ -563,5 +563,18 @@ PARTITION BY LIST (gender)
 			Expect(results).To(HaveLen(1))
 			structmatcher.ExpectStructsToMatchExcluding(&view, &results[0], "Oid")
 		})
+		It("returns a slice for a materialized view", func() {
+			testutils.SkipIfBefore6(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, "CREATE MATERIALIZED VIEW public.simplematview WITH (fillfactor=10) AS SELECT 1 AS a DISTRIBUTED BY (a)")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP MATERIALIZED VIEW public.simplematview")
+
+			results := backup.GetViews(connectionPool)
+
+			matview := backup.View{Oid: 1, Schema: "public", Name: "simplematview", Definition: " SELECT 1 AS a;", Options: " WITH (fillfactor=10)",
+				IsMaterialized: true, IsPopulated: true, DistPolicy: "DISTRIBUTED BY (a)"}
+
+			Expect(results).To(HaveLen(1))
+			structmatcher.ExpectStructsToMatchExcluding(&matview, &results[0], "Oid")
+		})
 	})
 })
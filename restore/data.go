@@ -6,6 +6,7 @@ package restore
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -64,21 +65,40 @@ func restoreSingleTableData(fpInfo *backup_filepath.FilePathInfo, entry utils.Ma
 		return err
 	}
 	numRowsBackedUp := entry.RowsCopied
-	err = CheckRowsRestored(numRowsRestored, numRowsBackedUp, name)
+	err = CheckRowsRestored(numRowsRestored, numRowsBackedUp, name, entry.Predicate)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CheckRowsRestored(rowsRestored int64, rowsBackedUp int64, tableName string) error {
+func CheckRowsRestored(rowsRestored int64, rowsBackedUp int64, tableName string, predicate string) error {
 	if rowsRestored != rowsBackedUp {
-		rowsErrMsg := fmt.Sprintf("Expected to restore %d rows to table %s, but restored %d instead", rowsBackedUp, tableName, rowsRestored)
+		predicateStr := ""
+		if predicate != "" {
+			predicateStr = fmt.Sprintf(" (backed up with predicate %s)", predicate)
+		}
+		rowsErrMsg := fmt.Sprintf("Expected to restore %d rows to table %s%s, but restored %d instead", rowsBackedUp, tableName, predicateStr, rowsRestored)
 		return errors.New(rowsErrMsg)
 	}
 	return nil
 }
 
+/*
+ * Tables backed up with gpbackup --table-predicate-file only contain the rows
+ * that matched their predicate, so they are listed in the restore report.
+ */
+func GetTablePredicates(dataEntries []utils.MasterDataEntry) []string {
+	tablePredicates := make([]string, 0)
+	for _, entry := range dataEntries {
+		if entry.Predicate != "" {
+			tablePredicates = append(tablePredicates, fmt.Sprintf("%s: %s", utils.MakeFQN(entry.Schema, entry.Name), entry.Predicate))
+		}
+	}
+	sort.Strings(tablePredicates)
+	return tablePredicates
+}
+
 func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries []utils.MasterDataEntry,
 	gucStatements []utils.StatementWithType, dataProgressBar utils.ProgressBar) {
 	if len(dataEntries) == 0 {
@@ -125,6 +145,8 @@ func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries [
 				if err != nil {
 					if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
 						gplog.Verbose(err.Error())
+						copyTarget := utils.MakeFQN(entry.Schema, entry.Name) + entry.AttributeString
+						restoreErrors.AddRecord(utils.NewRestoreErrorRecord("data", "TABLE", entry.Schema, entry.Name, copyTarget, err))
 						atomic.AddInt32(&numErrors, 1)
 					} else {
 						fatalErr = err
@@ -135,7 +157,7 @@ func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries [
 			}
 		}(i)
 	}
-	for _, entry := range dataEntries {
+	for _, entry := range ScheduleDataEntriesForRestore(dataEntries, connectionPool.NumConns, backupConfig.SingleDataFile) {
 		tasks <- entry
 	}
 	close(tasks)
@@ -163,3 +185,107 @@ func restoreDataFromTimestamp(fpInfo backup_filepath.FilePathInfo, dataEntries [
 		gplog.Error("Encountered %d errors during table data restore; see log file %s for a list of table errors.", numErrors, gplog.GetLogFilePath())
 	}
 }
+
+/*
+ * As in gpbackup, the largest tables are restored first when there are
+ * multiple connections.  Data for a single data file backup must be restored
+ * in the order in which it appears in the file, as gpbackup_helper reads the
+ * file sequentially.
+ */
+func ScheduleDataEntriesForRestore(dataEntries []utils.MasterDataEntry, numConns int, singleDataFile bool) []utils.MasterDataEntry {
+	if numConns == 1 || singleDataFile || MustGetFlagBool(utils.PRESERVE_TABLE_ORDER) {
+		return dataEntries
+	}
+	scheduledEntries := make([]utils.MasterDataEntry, len(dataEntries))
+	copy(scheduledEntries, dataEntries)
+	sort.SliceStable(scheduledEntries, func(i int, j int) bool {
+		return scheduledEntries[i].SizeEstimate > scheduledEntries[j].SizeEstimate
+	})
+	return scheduledEntries
+}
+
+/*
+ * This function recomputes the fingerprint of each restored table that had a
+ * fingerprint recorded at backup time and compares the two, logging an error
+ * for each table whose data differs on any segment.
+ */
+func VerifyRestoredData(dataEntries []utils.MasterDataEntry) *utils.DataVerificationResult {
+	result := &utils.DataVerificationResult{Mismatches: []utils.FingerprintMismatch{}, Errors: []string{}}
+	entriesToVerify := make([]utils.MasterDataEntry, 0)
+	for _, entry := range dataEntries {
+		if entry.Fingerprints != nil {
+			entriesToVerify = append(entriesToVerify, entry)
+		}
+	}
+	if len(entriesToVerify) == 0 {
+		gplog.Warn("Backup does not contain data fingerprints; skipping data verification.  Use gpbackup --with-fingerprints to record them.")
+		return nil
+	}
+	if globalTOC.FingerprintVersion != utils.FINGERPRINT_VERSION {
+		gplog.Warn("Backup data fingerprints were recorded in an older format that this version of gprestore cannot compare against; skipping data verification")
+		return nil
+	}
+	if len(entriesToVerify) < len(dataEntries) {
+		gplog.Warn("%d tables do not have data fingerprints in the backup and will not be verified", len(dataEntries)-len(entriesToVerify))
+	}
+
+	gplog.Info("Verifying restored data")
+	var mutex sync.Mutex
+	var workerPool sync.WaitGroup
+	tasks := make(chan utils.MasterDataEntry, len(entriesToVerify))
+	for i := 0; i < connectionPool.NumConns; i++ {
+		workerPool.Add(1)
+		go func(whichConn int) {
+			defer workerPool.Done()
+			for entry := range tasks {
+				if wasTerminated {
+					return
+				}
+				name := utils.MakeFQN(entry.Schema, entry.Name)
+				gplog.Verbose("Verifying data for table %s", name)
+				fingerprints, err := utils.GetTableFingerprints(connectionPool, name, globalCluster.ContentIDs, whichConn)
+				mutex.Lock()
+				if err != nil {
+					gplog.Verbose("Unable to verify data for table %s: %s", name, err.Error())
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", name, err.Error()))
+				} else {
+					result.NumTablesVerified++
+					mismatches := utils.CompareTableFingerprints(entry.Schema, entry.Name, entry.Fingerprints, fingerprints)
+					for _, mismatch := range mismatches {
+						gplog.Verbose("Data for table %s differs on %s: expected %d rows with checksum %s, found %d rows with checksum %s",
+							name, mismatch.SegmentString(), mismatch.Expected.NumRows, mismatch.Expected.Checksum, mismatch.Actual.NumRows, mismatch.Actual.Checksum)
+					}
+					result.Mismatches = append(result.Mismatches, mismatches...)
+				}
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	for _, entry := range entriesToVerify {
+		tasks <- entry
+	}
+	close(tasks)
+	workerPool.Wait()
+
+	sort.Slice(result.Mismatches, func(i int, j int) bool {
+		first, second := result.Mismatches[i], result.Mismatches[j]
+		if first.Schema != second.Schema {
+			return first.Schema < second.Schema
+		} else if first.Name != second.Name {
+			return first.Name < second.Name
+		}
+		return first.ContentID < second.ContentID
+	})
+	sort.Strings(result.Errors)
+
+	if numMismatched := len(result.GetMismatchedTables()); numMismatched > 0 {
+		gplog.Error("Data verification found differences in %d of %d tables; see report file for a list of tables and segments that differ.", numMismatched, len(entriesToVerify))
+	}
+	if len(result.Errors) > 0 {
+		gplog.Error("Unable to verify data for %d tables; see log file %s for details.", len(result.Errors), gplog.GetLogFilePath())
+	}
+	if len(result.Mismatches) == 0 && len(result.Errors) == 0 {
+		gplog.Info("Data verification complete; all %d tables match", result.NumTablesVerified)
+	}
+	return result
+}
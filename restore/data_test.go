@@ -3,6 +3,7 @@ package restore_test
 import (
 	"regexp"
 
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/utils"
@@ -70,18 +71,67 @@ var _ = Describe("restore/data tests", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
+	Describe("ScheduleDataEntriesForRestore", func() {
+		small := utils.MasterDataEntry{Schema: "public", Name: "small", Oid: 1, SizeEstimate: 8192}
+		large := utils.MasterDataEntry{Schema: "public", Name: "large", Oid: 2, SizeEstimate: 81920}
+		noEstimate := utils.MasterDataEntry{Schema: "public", Name: "noestimate", Oid: 3}
+		dataEntries := []utils.MasterDataEntry{small, noEstimate, large}
+		It("schedules the largest tables first, keeping backup order for tables of equal size", func() {
+			Expect(restore.ScheduleDataEntriesForRestore(dataEntries, 2, false)).To(Equal([]utils.MasterDataEntry{large, small, noEstimate}))
+			Expect(dataEntries).To(Equal([]utils.MasterDataEntry{small, noEstimate, large}))
+		})
+		It("keeps backup order with a single connection", func() {
+			Expect(restore.ScheduleDataEntriesForRestore(dataEntries, 1, false)).To(Equal(dataEntries))
+		})
+		It("keeps backup order for a single data file backup", func() {
+			Expect(restore.ScheduleDataEntriesForRestore(dataEntries, 2, true)).To(Equal(dataEntries))
+		})
+		It("keeps backup order when --preserve-table-order is set", func() {
+			_ = cmdFlags.Set(utils.PRESERVE_TABLE_ORDER, "true")
+			Expect(restore.ScheduleDataEntriesForRestore(dataEntries, 2, false)).To(Equal(dataEntries))
+		})
+	})
 	Describe("CheckRowsRestored", func() {
 		var (
 			expectedRows int64 = 10
 			name               = "public.foo"
 		)
 		It("does nothing if the number of rows match ", func() {
-			err := restore.CheckRowsRestored(10, expectedRows, name)
+			err := restore.CheckRowsRestored(10, expectedRows, name, "")
 			Expect(err).ToNot(HaveOccurred())
 		})
 		It("returns an error if the numbers of rows do not match", func() {
-			err := restore.CheckRowsRestored(5, expectedRows, name)
+			err := restore.CheckRowsRestored(5, expectedRows, name, "")
 			Expect(err.Error()).To(Equal("Expected to restore 10 rows to table public.foo, but restored 5 instead"))
 		})
+		It("includes the predicate the table was backed up with in the error", func() {
+			err := restore.CheckRowsRestored(5, expectedRows, name, "i > 5")
+			Expect(err.Error()).To(Equal("Expected to restore 10 rows to table public.foo (backed up with predicate i > 5), but restored 5 instead"))
+		})
+	})
+	Describe("VerifyRestoredData", func() {
+		AfterEach(func() {
+			restore.SetTOC(nil)
+		})
+		It("skips verification of fingerprints recorded in a different format", func() {
+			restore.SetTOC(&utils.TOC{})
+			fingerprints := []utils.SegmentFingerprint{{ContentID: 0, NumRows: 1, Checksum: "123"}}
+
+			result := restore.VerifyRestoredData([]utils.MasterDataEntry{{Schema: "public", Name: "foo", Fingerprints: fingerprints}})
+
+			Expect(result).To(BeNil())
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			testhelper.ExpectRegexp(logfile, "Backup data fingerprints were recorded in an older format")
+		})
+	})
+	Describe("GetTablePredicates", func() {
+		It("lists the tables that were backed up with a predicate", func() {
+			dataEntries := []utils.MasterDataEntry{
+				{Schema: "public", Name: "foo", Predicate: "i > 5"},
+				{Schema: "public", Name: "bar"},
+				{Schema: "public", Name: "baz", Predicate: "j < 3"},
+			}
+			Expect(restore.GetTablePredicates(dataEntries)).To(Equal([]string{"public.baz: j < 3", "public.foo: i > 5"}))
+		})
 	})
 })
@@ -23,11 +23,14 @@ import (
 var (
 	backupConfig     *backup_history.BackupConfig
 	connectionPool   *dbconn.DBConn
+	dataVerification *utils.DataVerificationResult
 	globalCluster    *cluster.Cluster
 	globalFPInfo     backup_filepath.FilePathInfo
 	globalTOC        *utils.TOC
 	pluginConfig     *utils.PluginConfig
+	restoreErrors    *utils.RestoreErrors
 	restoreStartTime string
+	tablePredicates  []string
 	version          string
 	wasTerminated    bool
 
@@ -64,6 +67,10 @@ func SetCluster(cluster *cluster.Cluster) {
 	globalCluster = cluster
 }
 
+func SetDataVerification(result *utils.DataVerificationResult) {
+	dataVerification = result
+}
+
 func SetFPInfo(fpInfo backup_filepath.FilePathInfo) {
 	globalFPInfo = fpInfo
 }
@@ -72,6 +79,10 @@ func SetPluginConfig(config *utils.PluginConfig) {
 	pluginConfig = config
 }
 
+func SetRestoreErrors(errors *utils.RestoreErrors) {
+	restoreErrors = errors
+}
+
 func SetTOC(toc *utils.TOC) {
 	globalTOC = toc
 }
@@ -0,0 +1,67 @@
+package restore
+
+/*
+ * This file contains functions related to restoring every database in a
+ * backup group taken by gpbackup --all-databases or --include-database.
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Each backup in the group is restored by its own run of gprestore, in the
+ * order in which the backups were taken, so that the global objects in the
+ * first backup are restored before the databases that depend on them.
+ */
+func DoRestoreGroup() {
+	SetLoggerVerbosity()
+	groupID := MustGetFlagString(utils.BACKUP_GROUP)
+	members := GetBackupGroupMembers(groupID)
+	databases := make([]string, 0)
+	for _, member := range members {
+		databases = append(databases, member.DatabaseName)
+	}
+	gplog.Info("Restoring databases %s from backup group %s", strings.Join(databases, ", "), groupID)
+	for i, member := range members {
+		if wasTerminated {
+			return
+		}
+		gplog.Info("Restoring database %s (%d of %d) from backup with timestamp %s", member.DatabaseName, i+1, len(members), member.Timestamp)
+		err := utils.RunUtilityWithArgs(GetRestoreGroupMemberArgs(member.Timestamp))
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Restore of database %s failed: %v.  Backup group %s was only partially restored.", member.DatabaseName, err, groupID), "")
+		}
+	}
+	gplog.Info("Restore of backup group %s complete", groupID)
+}
+
+func GetBackupGroupMembers(groupID string) []backup_history.BackupConfig {
+	historyConn := dbconn.NewDBConnFromEnvironment("postgres")
+	historyConn.MustConnect(1)
+	segConfig := cluster.MustGetSegmentConfiguration(historyConn)
+	historyConn.Close()
+	fpInfo := backup_filepath.NewFilePathInfo(cluster.NewCluster(segConfig), "", "", "")
+
+	history, err := backup_history.NewHistory(fpInfo.GetBackupHistoryFilePath())
+	gplog.FatalOnError(err)
+	members := history.GetBackupGroup(groupID)
+	if len(members) == 0 {
+		gplog.Fatal(errors.Errorf("No backups were found in backup group %s", groupID), "")
+	}
+	return members
+}
+
+func GetRestoreGroupMemberArgs(timestamp string) []string {
+	args := utils.GetChangedFlagArgs(cmdFlags, utils.BACKUP_GROUP)
+	return append(args, fmt.Sprintf("--%s=%s", utils.TIMESTAMP, timestamp))
+}
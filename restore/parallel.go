@@ -6,6 +6,7 @@ package restore
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -14,7 +15,7 @@ import (
 	"github.com/greenplum-db/gpbackup/utils"
 )
 
-func executeStatementsForConn(statements chan utils.StatementWithType, fatalErr *error, numErrors *int32, progressBar utils.ProgressBar, whichConn int) {
+func executeStatementsForConn(statements chan utils.StatementWithType, section string, fatalErr *error, numErrors *int32, progressBar utils.ProgressBar, whichConn int) {
 	for statement := range statements {
 		if wasTerminated || *fatalErr != nil {
 			return
@@ -23,6 +24,7 @@ func executeStatementsForConn(statements chan utils.StatementWithType, fatalErr
 		if err != nil {
 			gplog.Verbose("Error encountered when executing statement: %s Error was: %s", strings.TrimSpace(statement.Statement), err.Error())
 			if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
+				restoreErrors.AddStatementError(section, statement, err)
 				atomic.AddInt32(numErrors, 1)
 			} else {
 				*fatalErr = err
@@ -36,7 +38,7 @@ func executeStatementsForConn(statements chan utils.StatementWithType, fatalErr
  * This function creates a worker pool of N goroutines to be able to execute up
  * to N statements in parallel.
  */
-func ExecuteStatements(statements []utils.StatementWithType, progressBar utils.ProgressBar, executeInParallel bool, whichConn ...int) {
+func ExecuteStatements(statements []utils.StatementWithType, section string, progressBar utils.ProgressBar, executeInParallel bool, whichConn ...int) {
 	var workerPool sync.WaitGroup
 	var fatalErr error
 	var numErrors int32
@@ -48,14 +50,14 @@ func ExecuteStatements(statements []utils.StatementWithType, progressBar utils.P
 
 	if !executeInParallel {
 		connNum := connectionPool.ValidateConnNum(whichConn...)
-		executeStatementsForConn(tasks, &fatalErr, &numErrors, progressBar, connNum)
+		executeStatementsForConn(tasks, section, &fatalErr, &numErrors, progressBar, connNum)
 	} else {
 		for i := 0; i < connectionPool.NumConns; i++ {
 			workerPool.Add(1)
 			go func(connNum int) {
 				defer workerPool.Done()
 				connNum = connectionPool.ValidateConnNum(connNum)
-				executeStatementsForConn(tasks, &fatalErr, &numErrors, progressBar, connNum)
+				executeStatementsForConn(tasks, section, &fatalErr, &numErrors, progressBar, connNum)
 			}(i)
 		}
 		workerPool.Wait()
@@ -67,10 +69,272 @@ func ExecuteStatements(statements []utils.StatementWithType, progressBar utils.P
 	}
 }
 
-func ExecuteStatementsAndCreateProgressBar(statements []utils.StatementWithType, objectsTitle string, showProgressBar int, executeInParallel bool, whichConn ...int) {
+/*
+ * A PredataNode holds the statements for a single object, which are executed
+ * in order on a single connection.  HardDependencies are the objects on which
+ * this object depends, taken from the TOC, while OrderDependencies are only
+ * used to preserve the order of statements for objects that were not sorted
+ * by dependency at backup time; those statements act as barriers, so that any
+ * statement before one in the TOC is executed before it and any statement after
+ * one is executed after it.
+ */
+type PredataNode struct {
+	Key               string
+	Statements        []utils.StatementWithType
+	HardDependencies  []int
+	OrderDependencies []int
+}
+
+/*
+ * Shell types are created before all sorted objects and share their TOC key
+ * with the base type that later fills them in, so they are identified by their
+ * statement and executed as barriers rather than as part of the base type's
+ * node, which must wait for the type's input and output functions.
+ */
+func isShellTypeStatement(statement utils.StatementWithType) bool {
+	return statement.ObjectType == "TYPE" && strings.TrimSpace(statement.Statement) == fmt.Sprintf("CREATE TYPE %s;", utils.MakeFQN(statement.Schema, statement.Name))
+}
+
+/*
+ * A statement is only added to the node for an earlier statement for the same
+ * object if all of that object's dependencies had been created when the node
+ * was, and otherwise gets a node of its own that depends on the earlier one.
+ * A dependency on an object that appears later in the TOC is added once the
+ * node for that object is created.
+ */
+func BuildPredataDependencyGraph(statements []utils.StatementWithType, dependencies map[string][]string) []PredataNode {
+	keysInRestore := make(map[string]bool, len(statements))
+	for _, statement := range statements {
+		keysInRestore[utils.GetDependencyKey(statement.ObjectType, statement.Schema, statement.Name)] = true
+	}
+	nodes := make([]PredataNode, 0)
+	hasUnresolvedDeps := make([]bool, 0)
+	lastNodeForKey := make(map[string]int, 0)
+	pendingNodesForKey := make(map[string][]int, 0)
+	lastBarrier := -1
+	nodesSinceBarrier := make([]int, 0)
+	for _, statement := range statements {
+		key := utils.GetDependencyKey(statement.ObjectType, statement.Schema, statement.Name)
+		objectDeps, isSorted := dependencies[key]
+		if isShellTypeStatement(statement) {
+			isSorted = false
+		}
+		lastNode, seenKey := lastNodeForKey[key]
+		if isSorted && seenKey && lastNode > lastBarrier && !hasUnresolvedDeps[lastNode] {
+			nodes[lastNode].Statements = append(nodes[lastNode].Statements, statement)
+			continue
+		}
+
+		nodeNum := len(nodes)
+		node := PredataNode{Key: key, Statements: []utils.StatementWithType{statement}, HardDependencies: []int{}, OrderDependencies: []int{}}
+		isUnresolved := false
+		if isSorted {
+			for _, dep := range objectDeps {
+				if depNode, ok := lastNodeForKey[dep]; ok {
+					node.HardDependencies = append(node.HardDependencies, depNode)
+				} else if keysInRestore[dep] {
+					pendingNodesForKey[dep] = append(pendingNodesForKey[dep], nodeNum)
+					isUnresolved = true
+				}
+				// Dependencies not in the restore set are ignored
+			}
+			if seenKey {
+				node.HardDependencies = append(node.HardDependencies, lastNode)
+			}
+			if lastBarrier >= 0 {
+				node.OrderDependencies = append(node.OrderDependencies, lastBarrier)
+			}
+		} else if len(nodesSinceBarrier) > 0 {
+			node.OrderDependencies = append(node.OrderDependencies, nodesSinceBarrier...)
+		} else if lastBarrier >= 0 {
+			node.OrderDependencies = append(node.OrderDependencies, lastBarrier)
+		}
+
+		/*
+		 * A node waiting on this one can only depend on it if no barrier lies
+		 * between them, as the barrier must run after the waiting node and before
+		 * this one.
+		 */
+		for _, pendingNode := range pendingNodesForKey[key] {
+			if isSorted && pendingNode > lastBarrier {
+				nodes[pendingNode].HardDependencies = append(nodes[pendingNode].HardDependencies, nodeNum)
+			} else {
+				gplog.Warn("%s depends on %s, which is restored after it", nodes[pendingNode].Key, key)
+			}
+		}
+		delete(pendingNodesForKey, key)
+
+		nodes = append(nodes, node)
+		hasUnresolvedDeps = append(hasUnresolvedDeps, isUnresolved)
+		lastNodeForKey[key] = nodeNum
+		if isSorted {
+			nodesSinceBarrier = append(nodesSinceBarrier, nodeNum)
+		} else {
+			lastBarrier = nodeNum
+			nodesSinceBarrier = make([]int, 0)
+		}
+	}
+	return nodes
+}
+
+/*
+ * A materialized view must be refreshed after the materialized views it
+ * selects from, including those it only reaches through regular views, so its
+ * dependencies are the materialized views reachable through the pre-data
+ * dependencies in the TOC without passing through another materialized view.
+ */
+func GetMaterializedViewDependencies(statements []utils.StatementWithType, predataDependencies map[string][]string) map[string][]string {
+	matviewKeys := make(map[string]bool, len(statements))
+	for _, statement := range statements {
+		matviewKeys[utils.GetDependencyKey(statement.ObjectType, statement.Schema, statement.Name)] = true
+	}
+	dependencies := make(map[string][]string, len(matviewKeys))
+	for key := range matviewKeys {
+		matviewDeps := make([]string, 0)
+		visited := map[string]bool{key: true}
+		toVisit := append([]string{}, predataDependencies[key]...)
+		for len(toVisit) > 0 {
+			dep := toVisit[0]
+			toVisit = toVisit[1:]
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if matviewKeys[dep] {
+				matviewDeps = append(matviewDeps, dep)
+			} else {
+				toVisit = append(toVisit, predataDependencies[dep]...)
+			}
+		}
+		sort.Strings(matviewDeps)
+		dependencies[key] = matviewDeps
+	}
+	return dependencies
+}
+
+/*
+ * This function executes the statements in a dependency graph on all of the
+ * connections in the pool, starting each object once all of the objects that it
+ * depends on have been created.  If the first statement for an object fails and
+ * --on-error-continue is set, the rest of its statements and all objects that
+ * depend on it are skipped.
+ */
+func ExecuteStatementsWithDependencies(nodes []PredataNode, section string, progressBar utils.ProgressBar) {
+	var workerPool sync.WaitGroup
+	var fatalErr error
+	var mutex sync.Mutex
+	var numErrors, numSkipped int32
+
+	numRemainingDeps := make([]int, len(nodes))
+	dependents := make([][]int, len(nodes))
+	hardDependents := make([][]int, len(nodes))
+	shouldSkip := make([]bool, len(nodes))
+	for i, node := range nodes {
+		for _, dep := range node.HardDependencies {
+			dependents[dep] = append(dependents[dep], i)
+			hardDependents[dep] = append(hardDependents[dep], i)
+			numRemainingDeps[i]++
+		}
+		for _, dep := range node.OrderDependencies {
+			dependents[dep] = append(dependents[dep], i)
+			numRemainingDeps[i]++
+		}
+	}
+
+	numCompleted := 0
+	isClosed := false
+	readyNodes := make(chan int, len(nodes))
+	for i := range nodes {
+		if numRemainingDeps[i] == 0 {
+			readyNodes <- i
+		}
+	}
+	if len(nodes) == 0 {
+		close(readyNodes)
+		isClosed = true
+	}
+
+	for i := 0; i < connectionPool.NumConns; i++ {
+		workerPool.Add(1)
+		go func(whichConn int) {
+			defer workerPool.Done()
+			for nodeNum := range readyNodes {
+				mutex.Lock()
+				isStopping := wasTerminated || fatalErr != nil
+				skipNode := shouldSkip[nodeNum]
+				mutex.Unlock()
+
+				objectFailed := isStopping || skipNode
+				if skipNode && !isStopping {
+					gplog.Verbose("Skipping %s because an object it depends on was not restored", nodes[nodeNum].Key)
+					atomic.AddInt32(&numSkipped, int32(len(nodes[nodeNum].Statements)))
+					for _, statement := range nodes[nodeNum].Statements {
+						restoreErrors.AddSkippedStatement(section, statement)
+						progressBar.Increment()
+					}
+				} else if !isStopping {
+					for j, statement := range nodes[nodeNum].Statements {
+						if objectFailed {
+							restoreErrors.AddSkippedStatement(section, statement)
+							atomic.AddInt32(&numSkipped, 1)
+							progressBar.Increment()
+							continue
+						}
+						_, err := connectionPool.Exec(statement.Statement, whichConn)
+						if err != nil {
+							gplog.Verbose("Error encountered when executing statement: %s Error was: %s", strings.TrimSpace(statement.Statement), err.Error())
+							if !MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
+								mutex.Lock()
+								fatalErr = err
+								mutex.Unlock()
+								objectFailed = true
+								break
+							}
+							restoreErrors.AddStatementError(section, statement, err)
+							atomic.AddInt32(&numErrors, 1)
+							objectFailed = j == 0
+						}
+						progressBar.Increment()
+					}
+				}
+
+				mutex.Lock()
+				numCompleted++
+				if objectFailed {
+					for _, dependent := range hardDependents[nodeNum] {
+						shouldSkip[dependent] = true
+					}
+				}
+				for _, dependent := range dependents[nodeNum] {
+					numRemainingDeps[dependent]--
+					if numRemainingDeps[dependent] == 0 && !isClosed {
+						readyNodes <- dependent
+					}
+				}
+				if !isClosed && (numCompleted == len(nodes) || fatalErr != nil || wasTerminated) {
+					close(readyNodes)
+					isClosed = true
+				}
+				mutex.Unlock()
+			}
+		}(i)
+	}
+	workerPool.Wait()
+
+	if fatalErr != nil {
+		gplog.Fatal(fatalErr, "")
+	} else if numErrors > 0 {
+		gplog.Error("Encountered %d errors during metadata restore; see log file %s for a list of failed statements.", numErrors, gplog.GetLogFilePath())
+	}
+	if numSkipped > 0 {
+		gplog.Error("Skipped %d statements for objects that depend on objects that failed to restore; see log file %s for a list of skipped objects.", numSkipped, gplog.GetLogFilePath())
+	}
+}
+
+func ExecuteStatementsAndCreateProgressBar(statements []utils.StatementWithType, section string, objectsTitle string, showProgressBar int, executeInParallel bool, whichConn ...int) {
 	progressBar := utils.NewProgressBar(len(statements), fmt.Sprintf("%s restored: ", objectsTitle), showProgressBar)
 	progressBar.Start()
-	ExecuteStatements(statements, progressBar, executeInParallel, whichConn...)
+	ExecuteStatements(statements, section, progressBar, executeInParallel, whichConn...)
 	progressBar.Finish()
 }
 
@@ -1,8 +1,11 @@
 package restore_test
 
 import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/restore"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -34,4 +37,212 @@ var _ = Describe("restore/parallel tests", func() {
 		})
 
 	})
+	Describe("BuildPredataDependencyGraph", func() {
+		function := utils.StatementWithType{Schema: "public", Name: "func()", ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.func() ..."}
+		functionComment := utils.StatementWithType{Schema: "public", Name: "func()", ObjectType: "FUNCTION", Statement: "COMMENT ON FUNCTION public.func() ..."}
+		table := utils.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "CREATE TABLE public.foo ..."}
+		view := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "CREATE VIEW public.bar ..."}
+		sequence := utils.StatementWithType{Schema: "public", Name: "seq", ObjectType: "SEQUENCE", Statement: "CREATE SEQUENCE public.seq ..."}
+		constraint := utils.StatementWithType{Schema: "public", Name: "foo_pkey", ObjectType: "CONSTRAINT", ReferenceObject: "public.foo", Statement: "ALTER TABLE public.foo ADD CONSTRAINT ..."}
+		dependencies := map[string][]string{
+			"FUNCTION public.func()": {},
+			"TABLE public.foo":       {},
+			"VIEW public.bar":        {"FUNCTION public.func()", "TABLE public.foo"},
+		}
+		It("creates one node per statement when there are no dependencies in the TOC", func() {
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table}, map[string][]string{})
+
+			Expect(nodes).To(HaveLen(2))
+			Expect(nodes[0].OrderDependencies).To(BeEmpty())
+			Expect(nodes[1].OrderDependencies).To(Equal([]int{0}))
+		})
+		It("groups the statements for an object into a single node", func() {
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, functionComment, table}, dependencies)
+
+			Expect(nodes).To(HaveLen(2))
+			Expect(nodes[0].Key).To(Equal("FUNCTION public.func()"))
+			Expect(nodes[0].Statements).To(Equal([]utils.StatementWithType{function, functionComment}))
+			Expect(nodes[1].Key).To(Equal("TABLE public.foo"))
+		})
+		It("adds dependencies on the objects each object depends on", func() {
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table, view}, dependencies)
+
+			Expect(nodes[0].HardDependencies).To(BeEmpty())
+			Expect(nodes[1].HardDependencies).To(BeEmpty())
+			Expect(nodes[2].HardDependencies).To(Equal([]int{0, 1}))
+		})
+		It("ignores dependencies on objects that are not being restored", func() {
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{table, view}, dependencies)
+
+			Expect(nodes[1].HardDependencies).To(Equal([]int{0}))
+		})
+		It("orders statements for unsorted objects after all previous statements and before all following statements", func() {
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{sequence, function, table, constraint, view}, dependencies)
+
+			Expect(nodes).To(HaveLen(5))
+			Expect(nodes[0].OrderDependencies).To(BeEmpty())
+			Expect(nodes[1].OrderDependencies).To(Equal([]int{0}))
+			Expect(nodes[2].OrderDependencies).To(Equal([]int{0}))
+			Expect(nodes[3].OrderDependencies).To(Equal([]int{1, 2}))
+			Expect(nodes[4].OrderDependencies).To(Equal([]int{3}))
+			Expect(nodes[4].HardDependencies).To(Equal([]int{1, 2}))
+		})
+		It("creates a separate node with its own dependencies for each overloaded operator", func() {
+			operator1 := utils.StatementWithType{Schema: "public", Name: "## (integer, integer)", ObjectType: "OPERATOR", Statement: "CREATE OPERATOR public.## ..."}
+			operator2 := utils.StatementWithType{Schema: "public", Name: "## (public.foo, public.foo)", ObjectType: "OPERATOR", Statement: "CREATE OPERATOR public.## ..."}
+			operatorDependencies := map[string][]string{
+				"FUNCTION public.func()":                      {},
+				"TABLE public.foo":                            {},
+				"OPERATOR public.## (integer, integer)":       {"FUNCTION public.func()"},
+				"OPERATOR public.## (public.foo, public.foo)": {"TABLE public.foo"},
+			}
+
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table, operator1, operator2}, operatorDependencies)
+
+			Expect(nodes).To(HaveLen(4))
+			Expect(nodes[2].HardDependencies).To(Equal([]int{0}))
+			Expect(nodes[3].HardDependencies).To(Equal([]int{1}))
+		})
+		It("creates the base type for a shell type in its own node after the type's functions", func() {
+			shellType := utils.StatementWithType{Schema: "public", Name: "mytype", ObjectType: "TYPE", Statement: "CREATE TYPE public.mytype;\n"}
+			inputFunction := utils.StatementWithType{Schema: "public", Name: "mytype_in(cstring)", ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.mytype_in(cstring) ..."}
+			baseType := utils.StatementWithType{Schema: "public", Name: "mytype", ObjectType: "TYPE", Statement: "\n\nCREATE TYPE public.mytype (\n\tINPUT = public.mytype_in, ..."}
+			typeDependencies := map[string][]string{
+				"FUNCTION public.mytype_in(cstring)": {},
+				"TYPE public.mytype":                 {"FUNCTION public.mytype_in(cstring)"},
+			}
+
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{shellType, inputFunction, baseType}, typeDependencies)
+
+			Expect(nodes).To(HaveLen(3))
+			Expect(nodes[0].Statements).To(Equal([]utils.StatementWithType{shellType}))
+			Expect(nodes[1].OrderDependencies).To(Equal([]int{0}))
+			Expect(nodes[2].Statements).To(Equal([]utils.StatementWithType{baseType}))
+			Expect(nodes[2].HardDependencies).To(Equal([]int{1, 0}))
+		})
+		It("adds dependencies on objects that appear later in the TOC", func() {
+			viewComment := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "COMMENT ON VIEW public.bar ..."}
+
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{view, function, table, viewComment}, dependencies)
+
+			Expect(nodes).To(HaveLen(4))
+			Expect(nodes[0].Statements).To(Equal([]utils.StatementWithType{view}))
+			Expect(nodes[0].HardDependencies).To(Equal([]int{1, 2}))
+			Expect(nodes[3].Statements).To(Equal([]utils.StatementWithType{viewComment}))
+			Expect(nodes[3].HardDependencies).To(Equal([]int{1, 2, 0}))
+		})
+	})
+	Describe("GetMaterializedViewDependencies", func() {
+		matviewOne := utils.StatementWithType{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW"}
+		matviewTwo := utils.StatementWithType{Schema: "public", Name: "mv2", ObjectType: "MATERIALIZED VIEW"}
+		matviewThree := utils.StatementWithType{Schema: "public", Name: "mv3", ObjectType: "MATERIALIZED VIEW"}
+		It("returns no dependencies for materialized views that only depend on other objects", func() {
+			predataDependencies := map[string][]string{
+				"TABLE public.foo":             {},
+				"MATERIALIZED VIEW public.mv1": {"TABLE public.foo"},
+			}
+			dependencies := restore.GetMaterializedViewDependencies([]utils.StatementWithType{matviewOne}, predataDependencies)
+			Expect(dependencies).To(Equal(map[string][]string{"MATERIALIZED VIEW public.mv1": {}}))
+		})
+		It("returns the materialized views that a materialized view depends on through regular views", func() {
+			predataDependencies := map[string][]string{
+				"TABLE public.foo":             {},
+				"MATERIALIZED VIEW public.mv1": {"TABLE public.foo"},
+				"VIEW public.bar":              {"MATERIALIZED VIEW public.mv1"},
+				"MATERIALIZED VIEW public.mv2": {"VIEW public.bar"},
+			}
+			dependencies := restore.GetMaterializedViewDependencies([]utils.StatementWithType{matviewOne, matviewTwo}, predataDependencies)
+			Expect(dependencies).To(Equal(map[string][]string{
+				"MATERIALIZED VIEW public.mv1": {},
+				"MATERIALIZED VIEW public.mv2": {"MATERIALIZED VIEW public.mv1"},
+			}))
+		})
+		It("does not return materialized views that are only reached through another materialized view", func() {
+			predataDependencies := map[string][]string{
+				"MATERIALIZED VIEW public.mv1": {},
+				"MATERIALIZED VIEW public.mv2": {"MATERIALIZED VIEW public.mv1"},
+				"MATERIALIZED VIEW public.mv3": {"MATERIALIZED VIEW public.mv2"},
+			}
+			dependencies := restore.GetMaterializedViewDependencies([]utils.StatementWithType{matviewOne, matviewTwo, matviewThree}, predataDependencies)
+			Expect(dependencies["MATERIALIZED VIEW public.mv3"]).To(Equal([]string{"MATERIALIZED VIEW public.mv2"}))
+		})
+	})
+	Describe("ExecuteStatementsWithDependencies", func() {
+		var ignoredProgressBar utils.ProgressBar
+		function := utils.StatementWithType{Schema: "public", Name: "func()", ObjectType: "FUNCTION", Statement: "CREATE FUNCTION public.func()"}
+		table := utils.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "CREATE TABLE public.foo"}
+		view := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "CREATE VIEW public.bar"}
+		viewComment := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "COMMENT ON VIEW public.bar"}
+		dependencies := map[string][]string{
+			"FUNCTION public.func()": {},
+			"TABLE public.foo":       {},
+			"VIEW public.bar":        {"TABLE public.foo"},
+		}
+		BeforeEach(func() {
+			ignoredProgressBar = utils.NewProgressBar(4, "", utils.PB_NONE)
+			ignoredProgressBar.Start()
+		})
+		AfterEach(func() {
+			ignoredProgressBar.Finish()
+		})
+		It("executes each statement after the statements for the objects it depends on", func() {
+			mock.ExpectExec("CREATE FUNCTION public.func()").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE TABLE public.foo").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE VIEW public.bar").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("COMMENT ON VIEW public.bar").WillReturnResult(sqlmock.NewResult(0, 0))
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table, view, viewComment}, dependencies)
+
+			restore.ExecuteStatementsWithDependencies(nodes, "predata", ignoredProgressBar)
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("skips objects that depend on an object that failed to restore when --on-error-continue is set", func() {
+			cmdFlags.Set(utils.ON_ERROR_CONTINUE, "true")
+			mock.ExpectExec("CREATE FUNCTION public.func()").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE TABLE public.foo").WillReturnError(errors.New("table error"))
+			restoreErrors := utils.NewRestoreErrors()
+			restore.SetRestoreErrors(restoreErrors)
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table, view, viewComment}, dependencies)
+
+			restore.ExecuteStatementsWithDependencies(nodes, "predata", ignoredProgressBar)
+
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+			Expect(restoreErrors.Records).To(HaveLen(3))
+			Expect(restoreErrors.Records[0].ErrorMessage).To(Equal("table error"))
+			for _, record := range restoreErrors.Records[1:] {
+				Expect(record.Name).To(Equal("bar"))
+				Expect(record.ErrorMessage).To(Equal("skipped due to failed dependency"))
+				Expect(record.Skipped).To(BeTrue())
+			}
+			Expect(restoreErrors.GetErrorCountsBySection()).To(Equal(map[string]int{"predata": 1}))
+			testhelper.ExpectRegexp(logfile, "Skipping VIEW public.bar because an object it depends on was not restored")
+			testhelper.ExpectRegexp(logfile, "Encountered 1 errors during metadata restore")
+			testhelper.ExpectRegexp(logfile, "Skipped 2 statements for objects that depend on objects that failed to restore")
+		})
+		It("records each failed statement when --on-error-continue is set", func() {
+			cmdFlags.Set(utils.ON_ERROR_CONTINUE, "true")
+			mock.ExpectExec("CREATE FUNCTION public.func()").WillReturnError(errors.New("function error"))
+			mock.ExpectExec("CREATE TABLE public.foo").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("CREATE VIEW public.bar").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("COMMENT ON VIEW public.bar").WillReturnError(errors.New("comment error"))
+			restoreErrors := utils.NewRestoreErrors()
+			restore.SetRestoreErrors(restoreErrors)
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table, view, viewComment}, dependencies)
+
+			restore.ExecuteStatementsWithDependencies(nodes, "predata", ignoredProgressBar)
+
+			Expect(restoreErrors.Records).To(HaveLen(2))
+			Expect(restoreErrors.Records[0].Name).To(Equal("func()"))
+			Expect(restoreErrors.Records[0].ErrorMessage).To(Equal("function error"))
+			Expect(restoreErrors.Records[1].Statement).To(Equal("COMMENT ON VIEW public.bar"))
+			Expect(restoreErrors.GetErrorCountsBySection()).To(Equal(map[string]int{"predata": 2}))
+		})
+		It("panics on the first error when --on-error-continue is not set", func() {
+			mock.ExpectExec("CREATE FUNCTION public.func()").WillReturnError(errors.New("function error"))
+			nodes := restore.BuildPredataDependencyGraph([]utils.StatementWithType{function, table}, dependencies)
+
+			defer testhelper.ShouldPanicWithMessage("function error")
+			restore.ExecuteStatementsWithDependencies(nodes, "predata", ignoredProgressBar)
+		})
+	})
 })
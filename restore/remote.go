@@ -8,6 +8,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
 )
@@ -29,24 +30,24 @@ func VerifyBackupDirectoriesExistOnAllHosts() {
 	}
 }
 
-func VerifyBackupFileCountOnSegments(fileCount int) {
-	remoteOutput := globalCluster.GenerateAndExecuteCommand("Verifying backup file count", func(contentID int) string {
-		return fmt.Sprintf("find %s -type f | wc -l", globalFPInfo.GetDirForContent(contentID))
+func VerifyBackupFileCountOnSegments(fpInfo backup_filepath.FilePathInfo, fileCount int) {
+	remoteOutput := globalCluster.GenerateAndExecuteCommand(fmt.Sprintf("Verifying backup file count for backup %s", fpInfo.Timestamp), func(contentID int) string {
+		return fmt.Sprintf("find %s -type f | wc -l", fpInfo.GetDirForContent(contentID))
 	}, cluster.ON_SEGMENTS)
-	globalCluster.CheckClusterError(remoteOutput, "Could not verify backup file count", func(contentID int) string {
-		return "Could not verify backup file count"
+	globalCluster.CheckClusterError(remoteOutput, fmt.Sprintf("Could not verify backup file count for backup %s", fpInfo.Timestamp), func(contentID int) string {
+		return fmt.Sprintf("Could not verify backup file count in %s", fpInfo.GetDirForContent(contentID))
 	})
 
 	numIncorrect := 0
 	for contentID := range remoteOutput.Stdouts {
 		numFound, _ := strconv.Atoi(strings.TrimSpace(remoteOutput.Stdouts[contentID]))
 		if numFound != fileCount {
-			gplog.Verbose("Expected to find %d file(s) on segment %d on host %s, but found %d instead.", fileCount, contentID, globalCluster.GetHostForContent(contentID), numFound)
+			gplog.Verbose("Expected to find %d file(s) for backup %s on segment %d on host %s, but found %d instead.", fileCount, fpInfo.Timestamp, contentID, globalCluster.GetHostForContent(contentID), numFound)
 			numIncorrect++
 		}
 	}
 	if numIncorrect > 0 {
-		cluster.LogFatalClusterError("Found incorrect number of backup files", cluster.ON_SEGMENTS, numIncorrect)
+		cluster.LogFatalClusterError(fmt.Sprintf("Found incorrect number of backup files for backup %s", fpInfo.Timestamp), cluster.ON_SEGMENTS, numIncorrect)
 	}
 }
 
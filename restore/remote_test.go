@@ -40,7 +40,7 @@ var _ = Describe("restore/remote tests", func() {
 			}
 			testCluster.Executor = testExecutor
 			restore.SetCluster(testCluster)
-			restore.VerifyBackupFileCountOnSegments(2)
+			restore.VerifyBackupFileCountOnSegments(testFPInfo, 2)
 			Expect((*testExecutor).NumExecutions).To(Equal(1))
 		})
 		It("panics if backup file counts do not match on all segments", func() {
@@ -52,8 +52,8 @@ var _ = Describe("restore/remote tests", func() {
 			}
 			testCluster.Executor = testExecutor
 			restore.SetCluster(testCluster)
-			defer testhelper.ShouldPanicWithMessage("Found incorrect number of backup files on 2 segments")
-			restore.VerifyBackupFileCountOnSegments(2)
+			defer testhelper.ShouldPanicWithMessage("Found incorrect number of backup files for backup 20170101010101 on 2 segments")
+			restore.VerifyBackupFileCountOnSegments(testFPInfo, 2)
 		})
 		It("panics if backup file counts do not match on some segments", func() {
 			testExecutor.ClusterOutput = &cluster.RemoteOutput{
@@ -63,8 +63,8 @@ var _ = Describe("restore/remote tests", func() {
 			}
 			testCluster.Executor = testExecutor
 			restore.SetCluster(testCluster)
-			defer testhelper.ShouldPanicWithMessage("Found incorrect number of backup files on 1 segment")
-			restore.VerifyBackupFileCountOnSegments(2)
+			defer testhelper.ShouldPanicWithMessage("Found incorrect number of backup files for backup 20170101010101 on 1 segment")
+			restore.VerifyBackupFileCountOnSegments(testFPInfo, 2)
 		})
 		It("panics if it cannot verify some backup file counts", func() {
 			testExecutor.ClusterOutput = &cluster.RemoteOutput{
@@ -75,8 +75,8 @@ var _ = Describe("restore/remote tests", func() {
 			}
 			testCluster.Executor = testExecutor
 			restore.SetCluster(testCluster)
-			defer testhelper.ShouldPanicWithMessage("Could not verify backup file count on 1 segment")
-			restore.VerifyBackupFileCountOnSegments(2)
+			defer testhelper.ShouldPanicWithMessage("Could not verify backup file count for backup 20170101010101 on 1 segment")
+			restore.VerifyBackupFileCountOnSegments(testFPInfo, 2)
 		})
 	})
 })
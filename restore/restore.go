@@ -23,11 +23,10 @@ import (
 func initializeFlags(cmd *cobra.Command) {
 	SetFlagDefaults(cmd.Flags())
 
-	_ = cmd.MarkFlagRequired(utils.TIMESTAMP)
-
 	cmdFlags = cmd.Flags()
 }
 func SetFlagDefaults(flagSet *pflag.FlagSet) {
+	flagSet.String(utils.BACKUP_GROUP, "", "Restore each database in the backup group with the specified timestamp, in the order in which they were backed up, instead of a single --timestamp")
 	flagSet.String(utils.BACKUP_DIR, "", "The absolute path of the directory in which the backup files to be restored are located")
 	flagSet.Bool(utils.CREATE_DB, false, "Create the database before metadata restore")
 	flagSet.Bool(utils.DATA_ONLY, false, "Only restore data, do not restore metadata")
@@ -35,20 +34,32 @@ func SetFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.StringSlice(utils.EXCLUDE_SCHEMA, []string{}, "Restore all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.EXCLUDE_RELATION, []string{}, "Restore all metadata except the specified relation(s). --exclude-table can be specified multiple times.")
 	flagSet.String(utils.EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will not be restored")
+	flagSet.StringSlice(utils.EXCLUDE_RELATION_PATTERN, []string{}, "Restore all metadata except the relation(s) whose fully-qualified names match the specified pattern(s). --exclude-table-pattern can be specified multiple times.")
+	flagSet.StringSlice(utils.EXCLUDE_SCHEMA_PATTERN, []string{}, "Restore all metadata except objects in the schema(s) whose names match the specified pattern(s). --exclude-schema-pattern can be specified multiple times.")
 	flagSet.Bool("help", false, "Help for gprestore")
 	flagSet.StringSlice(utils.INCLUDE_SCHEMA, []string{}, "Restore only the specified schema(s). --include-schema can be specified multiple times.")
 	flagSet.StringSlice(utils.INCLUDE_RELATION, []string{}, "Restore only the specified relation(s). --include-table can be specified multiple times.")
 	flagSet.String(utils.INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will be restored")
+	flagSet.StringSlice(utils.INCLUDE_RELATION_PATTERN, []string{}, "Restore only the relation(s) whose fully-qualified names match the specified pattern(s). --include-table-pattern can be specified multiple times.")
+	flagSet.StringSlice(utils.INCLUDE_SCHEMA_PATTERN, []string{}, "Restore only the schema(s) whose names match the specified pattern(s). --include-schema-pattern can be specified multiple times.")
 	flagSet.Bool(utils.METADATA_ONLY, false, "Only restore metadata, do not restore data")
-	flagSet.Int(utils.JOBS, 1, "Number of parallel connections to use when restoring table data and post-data")
+	flagSet.Bool(utils.LIST_RESTORE_POINTS, false, "List the backups in the incremental chain of the specified backup, any of which can be restored with --timestamp, and exit without restoring")
+	flagSet.Int(utils.JOBS, 1, "Number of parallel connections to use when restoring pre-data metadata, table data, and post-data, and when refreshing materialized views")
+	flagSet.Bool(utils.NO_REFRESH_MATVIEWS, false, "Do not refresh materialized views after restoring data, leaving them empty until they are refreshed manually")
+	flagSet.Bool(utils.NO_TABLESPACES, false, "Do not restore tablespaces, and create all tables and indexes in the default tablespace")
 	flagSet.Bool(utils.ON_ERROR_CONTINUE, false, "Log errors and continue restore, instead of exiting on first error")
+	flagSet.String(utils.PATTERN_SYNTAX, utils.PATTERN_SYNTAX_GLOB, "The syntax of the patterns passed to the --*-pattern flags, either glob or regex")
 	flagSet.String(utils.PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.Bool(utils.PRESERVE_TABLE_ORDER, false, "Restore table data in the order in which it was backed up, instead of starting with the largest tables when --jobs is greater than 1")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(utils.QUIET, false, "Suppress non-warning, non-error log messages")
 	flagSet.String(utils.REDIRECT_DB, "", "Restore to the specified database instead of the database that was backed up")
 	flagSet.Bool(utils.WITH_GLOBALS, false, "Restore global metadata")
+	flagSet.StringSlice(utils.TABLESPACE_LOCATION, []string{}, "Create the specified tablespace at a new location, in the format tablespace:/path.  Requires --with-globals.  --tablespace-location can be specified multiple times.")
+	flagSet.StringSlice(utils.TABLESPACE_MAP, []string{}, "Restore tables and indexes in the specified tablespace to a different tablespace, in the format old:new.  --tablespace-map can be specified multiple times.")
 	flagSet.String(utils.TIMESTAMP, "", "The timestamp to be restored, in the format YYYYMMDDHHMMSS")
 	flagSet.Bool(utils.VERBOSE, false, "Print verbose log messages")
+	flagSet.Bool(utils.VERIFY_DATA, false, "After restoring data, compare each table's per-segment row count and checksum against those recorded by gpbackup --with-fingerprints")
 	flagSet.Bool(utils.WITH_STATS, false, "Restore query plan statistics")
 }
 
@@ -71,15 +82,23 @@ func DoValidation(cmd *cobra.Command) {
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(utils.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
-	if !backup_filepath.IsValidTimestamp(MustGetFlagString(utils.TIMESTAMP)) {
-		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", MustGetFlagString(utils.TIMESTAMP)), "")
+	timestamp := MustGetFlagString(utils.TIMESTAMP)
+	if MustGetFlagString(utils.BACKUP_GROUP) != "" {
+		timestamp = MustGetFlagString(utils.BACKUP_GROUP)
+	}
+	if !backup_filepath.IsValidTimestamp(timestamp) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", timestamp), "")
 	}
+	err = utils.ValidatePatternFlags(cmdFlags)
+	gplog.FatalOnError(err)
+	ValidateTablespaceFlagValues()
 }
 
 // This function handles setup that must be done after parsing flags.
 func DoSetup() {
 	SetLoggerVerbosity()
 	restoreStartTime = utils.CurrentTimestamp()
+	restoreErrors = utils.NewRestoreErrors()
 	gplog.Info("Restore Key = %s", MustGetFlagString(utils.TIMESTAMP))
 
 	InitializeConnectionPool("postgres")
@@ -96,6 +115,11 @@ func DoSetup() {
 	}
 
 	BackupConfigurationValidation()
+	if MustGetFlagBool(utils.LIST_RESTORE_POINTS) {
+		ListRestorePoints()
+		return
+	}
+	ValidateRestorePlan()
 	metadataFilename := globalFPInfo.GetMetadataFilePath()
 	if !backupConfig.DataOnly {
 		gplog.Verbose("Metadata will be restored from %s", metadataFilename)
@@ -133,17 +157,10 @@ func DoRestore() {
 	isDataOnly := backupConfig.DataOnly || MustGetFlagBool(utils.DATA_ONLY)
 	isMetadataOnly := backupConfig.MetadataOnly || MustGetFlagBool(utils.METADATA_ONLY)
 	if !isDataOnly {
-		restorePredata(metadataFilename)
+		restorePredata(metadataFilename, gucStatements)
 	}
 
 	if !isMetadataOnly {
-		if MustGetFlagString(utils.PLUGIN_CONFIG) == "" {
-			backupFileCount := 2 // 1 for the actual data file, 1 for the segment TOC file
-			if !backupConfig.SingleDataFile {
-				backupFileCount = len(globalTOC.DataEntries)
-			}
-			VerifyBackupFileCountOnSegments(backupFileCount)
-		}
 		restoreData(GetBackupFPInfoListFromRestorePlan(), gucStatements)
 	}
 
@@ -151,6 +168,10 @@ func DoRestore() {
 		restorePostdata(metadataFilename)
 	}
 
+	if !isMetadataOnly && !MustGetFlagBool(utils.NO_REFRESH_MATVIEWS) {
+		refreshMaterializedViews(metadataFilename)
+	}
+
 	if MustGetFlagBool(utils.WITH_STATS) && backupConfig.WithStatistics {
 		restoreStatistics()
 	}
@@ -164,7 +185,8 @@ func createDatabase(metadataFilename string) {
 		quotedDBName := utils.QuoteIdent(connectionPool, MustGetFlagString(utils.REDIRECT_DB))
 		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
 	}
-	ExecuteRestoreMetadataStatements(statements, "", nil, utils.PB_NONE, false)
+	statements = SubstituteTablespaces(statements)
+	ExecuteRestoreMetadataStatements(statements, "global", "", nil, utils.PB_NONE, false)
 	gplog.Info("Database creation complete")
 }
 
@@ -174,17 +196,21 @@ func restoreGlobal(metadataFilename string) {
 		objectTypes = append(objectTypes, "DATABASE")
 	}
 	gplog.Info("Restoring global metadata")
+	if len(MustGetFlagStringSlice(utils.TABLESPACE_LOCATION)) > 0 && utils.ParseBackupDatabaseVersion(backupConfig.DatabaseVersion).Before("6") {
+		gplog.Warn("Tablespace locations cannot be overridden for tablespaces backed up from GPDB versions before 6; --tablespace-location will be ignored")
+	}
 	statements := GetRestoreMetadataStatements("global", metadataFilename, objectTypes, []string{}, false, false)
 	if MustGetFlagString(utils.REDIRECT_DB) != "" {
 		quotedDBName := utils.QuoteIdent(connectionPool, MustGetFlagString(utils.REDIRECT_DB))
 		statements = utils.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
 	}
 	statements = utils.RemoveActiveRole(connectionPool.User, statements)
-	ExecuteRestoreMetadataStatements(statements, "Global objects", nil, utils.PB_VERBOSE, false)
+	statements = SubstituteTablespaces(statements)
+	ExecuteRestoreMetadataStatements(statements, "global", "Global objects", nil, utils.PB_VERBOSE, false)
 	gplog.Info("Global database metadata restore complete")
 }
 
-func restorePredata(metadataFilename string) {
+func restorePredata(metadataFilename string, gucStatements []utils.StatementWithType) {
 	if wasTerminated {
 		return
 	}
@@ -192,12 +218,21 @@ func restorePredata(metadataFilename string) {
 
 	schemaStatements := GetRestoreMetadataStatements("predata", metadataFilename, []string{"SCHEMA"}, []string{}, true, false)
 	statements := GetRestoreMetadataStatements("predata", metadataFilename, []string{}, []string{"SCHEMA"}, true, true)
+	statements = SubstituteTablespaces(statements)
 
 	progressBar := utils.NewProgressBar(len(schemaStatements)+len(statements), "Pre-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
 
 	RestoreSchemas(schemaStatements, progressBar)
-	ExecuteRestoreMetadataStatements(statements, "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	if len(globalTOC.PredataDependencies) > 0 {
+		for i := 1; i < connectionPool.NumConns; i++ {
+			setGUCsForConnection(gucStatements, i)
+		}
+		nodes := BuildPredataDependencyGraph(statements, globalTOC.PredataDependencies)
+		ExecuteStatementsWithDependencies(nodes, "predata", progressBar)
+	} else {
+		ExecuteRestoreMetadataStatements(statements, "predata", "Pre-data objects", progressBar, utils.PB_VERBOSE, false)
+	}
 
 	progressBar.Finish()
 	if wasTerminated {
@@ -237,8 +272,23 @@ func restoreData(fpInfoList []backup_filepath.FilePathInfo, gucStatements []util
 	dataProgressBar.Finish()
 	if wasTerminated {
 		gplog.Info("Data restore incomplete")
-	} else {
-		gplog.Info("Data restore complete")
+		return
+	}
+	gplog.Info("Data restore complete")
+
+	allDataEntries := make([]utils.MasterDataEntry, 0, totalTables)
+	for _, dataEntries := range filteredDataEntries {
+		allDataEntries = append(allDataEntries, dataEntries...)
+	}
+	tablePredicates = GetTablePredicates(allDataEntries)
+	if len(tablePredicates) > 0 {
+		gplog.Warn("%d tables were backed up with a predicate and only contain the rows that matched it; see report file for details.", len(tablePredicates))
+	}
+	if len(backupConfig.MaskingRules) > 0 {
+		gplog.Warn("Backup was taken with masking rules for %d columns, so the restored data for those columns is masked; see the backup report file for details.", len(backupConfig.MaskingRules))
+	}
+	if MustGetFlagBool(utils.VERIFY_DATA) {
+		dataVerification = VerifyRestoredData(allDataEntries)
 	}
 }
 
@@ -248,11 +298,12 @@ func restorePostdata(metadataFilename string) {
 	}
 	gplog.Info("Restoring post-data metadata")
 	statements := GetRestoreMetadataStatements("postdata", metadataFilename, []string{}, []string{}, true, true)
+	statements = SubstituteTablespaces(statements)
 	firstBatch, secondBatch := BatchPostdataStatements(statements)
 	progressBar := utils.NewProgressBar(len(statements), "Post-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
-	ExecuteRestoreMetadataStatements(firstBatch, "", progressBar, utils.PB_VERBOSE, connectionPool.NumConns > 1)
-	ExecuteRestoreMetadataStatements(secondBatch, "", progressBar, utils.PB_VERBOSE, connectionPool.NumConns > 1)
+	ExecuteRestoreMetadataStatements(firstBatch, "postdata", "", progressBar, utils.PB_VERBOSE, connectionPool.NumConns > 1)
+	ExecuteRestoreMetadataStatements(secondBatch, "postdata", "", progressBar, utils.PB_VERBOSE, connectionPool.NumConns > 1)
 	progressBar.Finish()
 	if wasTerminated {
 		gplog.Info("Post-data metadata restore incomplete")
@@ -261,6 +312,40 @@ func restorePostdata(metadataFilename string) {
 	}
 }
 
+/*
+ * Materialized views are created WITH NO DATA in the pre-data section, so they
+ * are populated here, once the tables they select from have been restored and
+ * indexed.  Materialized views that were not populated in the source database
+ * are left empty.  With --jobs, materialized views that do not select from one
+ * another are refreshed in parallel.
+ */
+func refreshMaterializedViews(metadataFilename string) {
+	if wasTerminated {
+		return
+	}
+	statements := GetRestoreMetadataStatements("predata", metadataFilename, []string{"MATERIALIZED VIEW"}, []string{}, true, true)
+	refreshStatements := GetRefreshMaterializedViewStatements(statements, globalTOC.UnpopulatedMatviews)
+	if len(refreshStatements) == 0 {
+		return
+	}
+	gplog.Info("Refreshing materialized views")
+	progressBar := utils.NewProgressBar(len(refreshStatements), "Materialized views refreshed: ", utils.PB_VERBOSE)
+	progressBar.Start()
+	if connectionPool.NumConns > 1 && len(globalTOC.PredataDependencies) > 0 {
+		dependencies := GetMaterializedViewDependencies(refreshStatements, globalTOC.PredataDependencies)
+		nodes := BuildPredataDependencyGraph(refreshStatements, dependencies)
+		ExecuteStatementsWithDependencies(nodes, "postdata", progressBar)
+	} else {
+		ExecuteStatements(refreshStatements, "postdata", progressBar, false)
+	}
+	progressBar.Finish()
+	if wasTerminated {
+		gplog.Info("Materialized view refresh incomplete")
+	} else {
+		gplog.Info("Materialized view refresh complete")
+	}
+}
+
 func restoreStatistics() {
 	if wasTerminated {
 		return
@@ -268,7 +353,7 @@ func restoreStatistics() {
 	statisticsFilename := globalFPInfo.GetStatisticsFilePath()
 	gplog.Info("Restoring query planner statistics from %s", statisticsFilename)
 	statements := GetRestoreMetadataStatements("statistics", statisticsFilename, []string{}, []string{}, true, false)
-	ExecuteRestoreMetadataStatements(statements, "Table statistics", nil, utils.PB_VERBOSE, false)
+	ExecuteRestoreMetadataStatements(statements, "statistics", "Table statistics", nil, utils.PB_VERBOSE, false)
 	gplog.Info("Query planner statistics restore complete")
 }
 
@@ -309,14 +394,23 @@ func DoTeardown() {
 	}
 	errMsg := utils.ParseErrorMessage(errStr)
 
-	if globalFPInfo.Timestamp != "" {
+	if globalFPInfo.Timestamp != "" && !MustGetFlagBool(utils.LIST_RESTORE_POINTS) {
 		_, statErr := os.Stat(globalFPInfo.GetDirForContent(-1))
 		if statErr != nil { // Even if this isn't os.IsNotExist, don't try to write a report file in case of further errors
 			return
 		}
 
 		reportFilename := globalFPInfo.GetRestoreReportFilePath(restoreStartTime)
-		utils.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, errMsg)
+		utils.WriteRestoreReportFile(reportFilename, globalFPInfo.Timestamp, restoreStartTime, connectionPool, version, errMsg, restoreErrors, dataVerification, tablePredicates)
+		if restoreErrors != nil && len(restoreErrors.Records) > 0 {
+			errorFilename := globalFPInfo.GetRestoreErrorFilePath(restoreStartTime)
+			err := restoreErrors.WriteErrorFile(errorFilename)
+			if err != nil {
+				gplog.Error("Unable to write restore error file %s: %s", errorFilename, err.Error())
+			} else {
+				gplog.Info("Restore errors written to %s", errorFilename)
+			}
+		}
 		utils.EmailReport(globalCluster, globalFPInfo.Timestamp, reportFilename, "gprestore")
 		if pluginConfig != nil {
 			pluginConfig.CleanupPluginForRestore(globalCluster, globalFPInfo)
@@ -53,6 +53,7 @@ var cmdFlags *pflag.FlagSet
 var _ = BeforeEach(func() {
 	connectionPool, mock, stdout, stderr, logfile = testutils.SetupTestEnvironment()
 	restore.SetConnection(connectionPool)
+	restore.SetRestoreErrors(utils.NewRestoreErrors())
 	buffer = gbytes.NewBuffer()
 
 	cmdFlags = pflag.NewFlagSet("gprestore", pflag.ExitOnError)
@@ -61,6 +62,7 @@ var _ = BeforeEach(func() {
 	cmdFlags.Bool(utils.ON_ERROR_CONTINUE, false, "")
 	cmdFlags.Bool(utils.DATA_ONLY, false, "")
 	cmdFlags.String(utils.PLUGIN_CONFIG, "", "")
+	cmdFlags.Bool(utils.PRESERVE_TABLE_ORDER, false, "")
 	cmdFlags.StringSlice(utils.INCLUDE_RELATION, []string{}, "")
 	cmdFlags.StringSlice(utils.EXCLUDE_RELATION, []string{}, "")
 	cmdFlags.StringSlice(utils.INCLUDE_SCHEMA, []string{}, "")
@@ -7,6 +7,9 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
@@ -77,25 +80,21 @@ func getFilterSchemasInBackupSet(schemaList []string) []string {
 }
 
 func GenerateRestoreRelationList() []string {
-	includeRelations := MustGetFlagStringSlice(utils.INCLUDE_RELATION)
-	if len(includeRelations) > 0 {
-		return includeRelations
-	}
-
+	filter := utils.NewFilter(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA), MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA),
+		MustGetFlagStringSlice(utils.INCLUDE_RELATION), MustGetFlagStringSlice(utils.EXCLUDE_RELATION))
 	relationList := make([]string, 0)
-	includedSchemaSet := utils.NewIncludeSet(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA))
-	excludedSchemaSet := utils.NewExcludeSet(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA))
-	excludedRelationsSet := utils.NewExcludeSet(MustGetFlagStringSlice(utils.EXCLUDE_RELATION))
-
-	if len(globalTOC.DataEntries) == 0 {
-		return []string{}
+	if len(filter.IncludeRelations) > 0 {
+		for _, fqn := range filter.IncludeRelations {
+			if filter.MatchesRelation(utils.GetSchemaFromFQN(fqn), fqn) {
+				relationList = append(relationList, fqn)
+			}
+		}
+		return relationList
 	}
+
 	for _, entry := range globalTOC.DataEntries {
 		fqn := utils.MakeFQN(entry.Schema, entry.Name)
-
-		if includedSchemaSet.MatchesFilter(entry.Schema) &&
-			excludedSchemaSet.MatchesFilter(entry.Schema) &&
-			excludedRelationsSet.MatchesFilter(fqn) {
+		if filter.MatchesRelation(entry.Schema, fqn) {
 			relationList = append(relationList, fqn)
 		}
 	}
@@ -162,7 +161,7 @@ func getFilterRelationsInBackupSet(relationList []string) []string {
 		relationMap[relation] = true
 	}
 	for _, entry := range globalTOC.PredataEntries {
-		if entry.ObjectType != "TABLE" && entry.ObjectType != "SEQUENCE" && entry.ObjectType != "VIEW" {
+		if entry.ObjectType != "TABLE" && entry.ObjectType != "SEQUENCE" && entry.ObjectType != "VIEW" && entry.ObjectType != "MATERIALIZED VIEW" {
 			continue
 		}
 		fqn := utils.MakeFQN(entry.Schema, entry.Name)
@@ -243,9 +242,141 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.WITH_GLOBALS)
 	utils.CheckExclusiveFlags(flags, utils.DATA_ONLY, utils.CREATE_DB)
 	utils.CheckExclusiveFlags(flags, utils.DEBUG, utils.QUIET, utils.VERBOSE)
-	utils.CheckExclusiveFlags(flags, utils.INCLUDE_SCHEMA, utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE)
-	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_SCHEMA, utils.INCLUDE_SCHEMA)
-	utils.CheckExclusiveFlags(flags, utils.EXCLUDE_SCHEMA, utils.EXCLUDE_RELATION, utils.INCLUDE_RELATION, utils.EXCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_FILE)
+	utils.CheckFilterFlagCombinations(flags)
 	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.DATA_ONLY)
+	utils.CheckExclusiveFlags(flags, utils.METADATA_ONLY, utils.VERIFY_DATA)
 	utils.CheckExclusiveFlags(flags, utils.PLUGIN_CONFIG, utils.BACKUP_DIR)
+	utils.CheckExclusiveFlags(flags, utils.NO_TABLESPACES, utils.TABLESPACE_MAP)
+	utils.CheckExclusiveFlags(flags, utils.NO_TABLESPACES, utils.TABLESPACE_LOCATION)
+	if flags.Changed(utils.TABLESPACE_LOCATION) && !flags.Changed(utils.WITH_GLOBALS) {
+		gplog.Fatal(errors.Errorf("--tablespace-location must be specified with --with-globals"), "")
+	}
+	utils.CheckExclusiveFlags(flags, utils.TIMESTAMP, utils.BACKUP_GROUP)
+	if !flags.Changed(utils.TIMESTAMP) && !flags.Changed(utils.BACKUP_GROUP) {
+		gplog.Fatal(errors.Errorf("Either --timestamp or --backup-group must be specified"), "")
+	}
+	if flags.Changed(utils.BACKUP_GROUP) {
+		for _, flagName := range []string{utils.INCLUDE_RELATION, utils.INCLUDE_RELATION_FILE, utils.INCLUDE_RELATION_PATTERN,
+			utils.INCLUDE_SCHEMA, utils.INCLUDE_SCHEMA_PATTERN, utils.REDIRECT_DB} {
+			utils.CheckExclusiveFlags(flags, utils.BACKUP_GROUP, flagName)
+		}
+	}
+}
+
+func ValidateTablespaceFlagValues() {
+	_, err := utils.ParseColonSeparatedFlagValues(utils.TABLESPACE_MAP, MustGetFlagStringSlice(utils.TABLESPACE_MAP))
+	gplog.FatalOnError(err)
+	locationMap, err := utils.ParseColonSeparatedFlagValues(utils.TABLESPACE_LOCATION, MustGetFlagStringSlice(utils.TABLESPACE_LOCATION))
+	gplog.FatalOnError(err)
+	for tablespace, location := range locationMap {
+		err = utils.ValidateFullPath(location)
+		if err != nil {
+			gplog.Fatal(errors.Errorf("Location %s for tablespace %s is not an absolute path.", location, tablespace), "")
+		}
+	}
+}
+
+/*
+ * Every backup in the restore plan must still exist and must have been taken
+ * with the same options as the backup being restored, so that a broken
+ * incremental chain is reported before any data is loaded.
+ */
+func ValidateRestorePlan() {
+	fpInfoList := GetBackupFPInfoListFromRestorePlan()
+	isMetadataOnly := backupConfig.MetadataOnly || MustGetFlagBool(utils.METADATA_ONLY)
+	if isMetadataOnly || len(fpInfoList) == 0 {
+		return
+	}
+	gplog.Verbose("Validating restore plan for backup %s", globalFPInfo.Timestamp)
+	for i, fpInfo := range fpInfoList {
+		entryConfig, err := ReadBackupConfigForRestorePlanEntry(fpInfo)
+		if err == nil {
+			err = ValidateRestorePlanEntry(backupConfig, entryConfig, i)
+		}
+		gplog.FatalOnError(err)
+	}
+	if MustGetFlagString(utils.PLUGIN_CONFIG) == "" {
+		for _, fpInfo := range fpInfoList {
+			backupFileCount := 2 // 1 for the actual data file, 1 for the segment TOC file
+			if !backupConfig.SingleDataFile {
+				backupFileCount = len(utils.NewTOC(fpInfo.GetTOCFilePath()).DataEntries)
+			}
+			VerifyBackupFileCountOnSegments(fpInfo, backupFileCount)
+		}
+	}
+}
+
+func ReadBackupConfigForRestorePlanEntry(fpInfo backup_filepath.FilePathInfo) (*backup_history.BackupConfig, error) {
+	if fpInfo.Timestamp == globalFPInfo.Timestamp {
+		return backupConfig, nil
+	}
+	for _, filename := range []string{fpInfo.GetConfigFilePath(), fpInfo.GetTOCFilePath()} {
+		if !iohelper.FileExistsAndIsReadable(filename) {
+			return nil, errors.Errorf("Backup %s in the restore plan for backup %s is missing or incomplete: cannot access %s", fpInfo.Timestamp, globalFPInfo.Timestamp, filename)
+		}
+	}
+	return backup_history.ReadConfigFile(fpInfo.GetConfigFilePath()), nil
+}
+
+/*
+ * The restore plan stored with each earlier backup in the chain must match the
+ * corresponding part of the restore plan being used, or else the earlier backup
+ * belongs to a different chain (e.g. it was replaced by a new backup with the
+ * same base).
+ */
+func ValidateRestorePlanEntry(currentConfig *backup_history.BackupConfig, entryConfig *backup_history.BackupConfig, index int) error {
+	entryTimestamp := currentConfig.RestorePlan[index].Timestamp
+	if entryConfig.Timestamp != entryTimestamp {
+		return errors.Errorf("Backup %s in the restore plan for backup %s has a config file for backup %s", entryTimestamp, currentConfig.Timestamp, entryConfig.Timestamp)
+	}
+	if entryConfig.Compressed != currentConfig.Compressed {
+		return errors.Errorf("Backup %s in the restore plan for backup %s %s, but backup %s %s", entryTimestamp, currentConfig.Timestamp,
+			describeCompression(entryConfig.Compressed), currentConfig.Timestamp, describeCompression(currentConfig.Compressed))
+	}
+	if entryConfig.Plugin != currentConfig.Plugin {
+		return errors.Errorf("Backup %s in the restore plan for backup %s %s, but backup %s %s", entryTimestamp, currentConfig.Timestamp,
+			describePlugin(entryConfig.Plugin), currentConfig.Timestamp, describePlugin(currentConfig.Plugin))
+	}
+	if entryConfig.SingleDataFile != currentConfig.SingleDataFile {
+		return errors.Errorf("Backup %s in the restore plan for backup %s %s, but backup %s %s", entryTimestamp, currentConfig.Timestamp,
+			describeSingleDataFile(entryConfig.SingleDataFile), currentConfig.Timestamp, describeSingleDataFile(currentConfig.SingleDataFile))
+	}
+
+	expectedTimestamps := make([]string, 0)
+	for _, entry := range currentConfig.RestorePlan[:index+1] {
+		expectedTimestamps = append(expectedTimestamps, entry.Timestamp)
+	}
+	entryTimestamps := []string{entryConfig.Timestamp}
+	if entryConfig.RestorePlan != nil {
+		entryTimestamps = make([]string, 0)
+		for _, entry := range entryConfig.RestorePlan {
+			entryTimestamps = append(entryTimestamps, entry.Timestamp)
+		}
+	}
+	if strings.Join(entryTimestamps, ",") != strings.Join(expectedTimestamps, ",") {
+		return errors.Errorf("Backup %s in the restore plan for backup %s is not part of the same incremental chain: its restore plan contains backups %s, expected %s",
+			entryTimestamp, currentConfig.Timestamp, strings.Join(entryTimestamps, ", "), strings.Join(expectedTimestamps, ", "))
+	}
+	return nil
+}
+
+func describeCompression(compressed bool) string {
+	if compressed {
+		return "is compressed"
+	}
+	return "is not compressed"
+}
+
+func describePlugin(plugin string) string {
+	if plugin == "" {
+		return "was taken without a plugin"
+	}
+	return fmt.Sprintf("was taken with plugin %s", plugin)
+}
+
+func describeSingleDataFile(singleDataFile bool) string {
+	if singleDataFile {
+		return "uses a single data file per segment"
+	}
+	return "uses one data file per table"
 }
@@ -29,13 +29,13 @@ var _ = Describe("restore/validate tests", func() {
 		BeforeEach(func() {
 			toc, backupfile = testutils.InitializeTestTOC(buffer, "predata")
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "table1", Oid: 1, AttributeString: "(i)"})
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "table2", Oid: 2, AttributeString: "(j)"})
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 			restore.SetTOC(toc)
 		})
 		It("passes when schema exists in normal backup", func() {
@@ -71,10 +71,10 @@ var _ = Describe("restore/validate tests", func() {
 	Describe("GenerateRestoreRelationList", func() {
 		BeforeEach(func() {
 			toc, _ = testutils.InitializeTestTOC(buffer, "metadata")
-			toc.AddMasterDataEntry("s1", "table1", 1, "(j)", 0, "")
-			toc.AddMasterDataEntry("s1", "table2", 2, "(j)", 0, "")
-			toc.AddMasterDataEntry("s2", "table1", 3, "(j)", 0, "")
-			toc.AddMasterDataEntry("s2", "table2", 4, "(j)", 0, "")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "s1", Name: "table1", Oid: 1, AttributeString: "(j)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "s1", Name: "table2", Oid: 2, AttributeString: "(j)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "s2", Name: "table1", Oid: 3, AttributeString: "(j)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "s2", Name: "table2", Oid: 4, AttributeString: "(j)"})
 			restore.SetTOC(toc)
 			cmdFlags.Set(utils.INCLUDE_RELATION, "")
 			cmdFlags.Set(utils.EXCLUDE_RELATION, "")
@@ -127,6 +127,15 @@ var _ = Describe("restore/validate tests", func() {
 
 			resultRelations := restore.GenerateRestoreRelationList()
 
+			Expect(resultRelations).To(ConsistOf(expectedRelations))
+		})
+		It("filters on include relations with exclude schema", func() {
+			cmdFlags.Set(utils.INCLUDE_RELATION, "s1.table1,s2.table2")
+			cmdFlags.Set(utils.EXCLUDE_SCHEMA, "s1")
+			expectedRelations := []string{"s2.table2"}
+
+			resultRelations := restore.GenerateRestoreRelationList()
+
 			Expect(resultRelations).To(ConsistOf(expectedRelations))
 		})
 	})
@@ -192,15 +201,15 @@ var _ = Describe("restore/validate tests", func() {
 		var backupfile *utils.FileWithByteCount
 		BeforeEach(func() {
 			toc, backupfile = testutils.InitializeTestTOC(buffer, "predata")
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "table1", Oid: 1, AttributeString: "(i)"})
 
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
-			toc.AddMasterDataEntry("schema2", "table2", 2, "(j)", 0, "")
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "table2", Oid: 2, AttributeString: "(j)"})
 
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "somesequence", "SEQUENCE", "", 0, 0}, 0, backupfile.ByteCount)
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "someview", "VIEW", "", 0, 0}, 0, backupfile.ByteCount)
-			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "somefunction", "FUNCTION", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "somesequence", "SEQUENCE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "someview", "VIEW", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema1", "somefunction", "FUNCTION", "", 0, 0, ""}, 0, backupfile.ByteCount)
 
 			restore.SetTOC(toc)
 		})
@@ -289,4 +298,44 @@ var _ = Describe("restore/validate tests", func() {
 			restore.ValidateDatabaseExistence("testdb", false, false)
 		})
 	})
+	Describe("ValidateRestorePlanEntry", func() {
+		var currentConfig, fullConfig, incrementalConfig *backup_history.BackupConfig
+		BeforeEach(func() {
+			fullPlan := []backup_history.RestorePlanEntry{{Timestamp: "20170101010101", TableFQNs: []string{"public.foo", "public.bar"}}}
+			incrementalPlan := append(fullPlan, backup_history.RestorePlanEntry{Timestamp: "20170102010101", TableFQNs: []string{"public.foo"}})
+			currentPlan := append(incrementalPlan, backup_history.RestorePlanEntry{Timestamp: "20170103010101", TableFQNs: []string{"public.bar"}})
+			fullConfig = &backup_history.BackupConfig{Timestamp: "20170101010101", Compressed: true, RestorePlan: fullPlan}
+			incrementalConfig = &backup_history.BackupConfig{Timestamp: "20170102010101", Compressed: true, Incremental: true, RestorePlan: incrementalPlan}
+			currentConfig = &backup_history.BackupConfig{Timestamp: "20170103010101", Compressed: true, Incremental: true, RestorePlan: currentPlan}
+		})
+		It("passes for backups that are part of the same chain", func() {
+			Expect(restore.ValidateRestorePlanEntry(currentConfig, fullConfig, 0)).To(Succeed())
+			Expect(restore.ValidateRestorePlanEntry(currentConfig, incrementalConfig, 1)).To(Succeed())
+			Expect(restore.ValidateRestorePlanEntry(currentConfig, currentConfig, 2)).To(Succeed())
+		})
+		It("passes for a full backup taken before restore plans were recorded", func() {
+			fullConfig.RestorePlan = nil
+			Expect(restore.ValidateRestorePlanEntry(currentConfig, fullConfig, 0)).To(Succeed())
+		})
+		It("returns an error for a backup with a different compression setting", func() {
+			incrementalConfig.Compressed = false
+			err := restore.ValidateRestorePlanEntry(currentConfig, incrementalConfig, 1)
+			Expect(err).To(MatchError("Backup 20170102010101 in the restore plan for backup 20170103010101 is not compressed, but backup 20170103010101 is compressed"))
+		})
+		It("returns an error for a backup with a different plugin", func() {
+			currentConfig.Plugin = "/usr/local/bin/gpbackup_s3_plugin"
+			err := restore.ValidateRestorePlanEntry(currentConfig, fullConfig, 0)
+			Expect(err).To(MatchError("Backup 20170101010101 in the restore plan for backup 20170103010101 was taken without a plugin, but backup 20170103010101 was taken with plugin /usr/local/bin/gpbackup_s3_plugin"))
+		})
+		It("returns an error for a backup with a different data file layout", func() {
+			fullConfig.SingleDataFile = true
+			err := restore.ValidateRestorePlanEntry(currentConfig, fullConfig, 0)
+			Expect(err).To(MatchError("Backup 20170101010101 in the restore plan for backup 20170103010101 uses a single data file per segment, but backup 20170103010101 uses one data file per table"))
+		})
+		It("returns an error for a backup from a different chain", func() {
+			incrementalConfig.RestorePlan[0].Timestamp = "20161231010101"
+			err := restore.ValidateRestorePlanEntry(currentConfig, incrementalConfig, 1)
+			Expect(err).To(MatchError("Backup 20170102010101 in the restore plan for backup 20170103010101 is not part of the same incremental chain: its restore plan contains backups 20161231010101, 20170102010101, expected 20170101010101, 20170102010101"))
+		})
+	})
 })
@@ -2,6 +2,8 @@ package restore
 
 import (
 	"fmt"
+	"io"
+	"path"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
@@ -116,6 +118,7 @@ func BackupConfigurationValidation() {
 	tocFilename := globalFPInfo.GetTOCFilePath()
 	globalTOC = utils.NewTOC(tocFilename)
 	globalTOC.InitializeMetadataEntryMap()
+	ExpandFilterPatterns(globalTOC)
 
 	// Legacy backups prior to the incremental feature would have no restoreplan yaml element
 	if isLegacyBackup := backupConfig.RestorePlan == nil; isLegacyBackup {
@@ -127,6 +130,66 @@ func BackupConfigurationValidation() {
 	validateFilterListsInBackupSet()
 }
 
+/*
+ * Patterns are matched against the schemas and relations in the backup's table
+ * of contents, in the same form as the names checked by
+ * validateFilterListsInBackupSet, so that the expanded lists always pass that
+ * validation.
+ */
+func ExpandFilterPatterns(toc *utils.TOC) {
+	if len(MustGetFlagStringSlice(utils.INCLUDE_SCHEMA_PATTERN)) > 0 || len(MustGetFlagStringSlice(utils.EXCLUDE_SCHEMA_PATTERN)) > 0 {
+		schemaNames := GetSchemaNamesInBackup(toc)
+		utils.ExpandPatternFlag(cmdFlags, utils.INCLUDE_SCHEMA_PATTERN, utils.INCLUDE_SCHEMA, schemaNames, "schemas", false)
+		utils.ExpandPatternFlag(cmdFlags, utils.EXCLUDE_SCHEMA_PATTERN, utils.EXCLUDE_SCHEMA, schemaNames, "schemas", true)
+	}
+	if len(MustGetFlagStringSlice(utils.INCLUDE_RELATION_PATTERN)) > 0 || len(MustGetFlagStringSlice(utils.EXCLUDE_RELATION_PATTERN)) > 0 {
+		relationNames := GetRelationNamesInBackup(toc)
+		utils.ExpandPatternFlag(cmdFlags, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, relationNames, "relations", false)
+		utils.ExpandPatternFlag(cmdFlags, utils.EXCLUDE_RELATION_PATTERN, utils.EXCLUDE_RELATION, relationNames, "relations", true)
+	}
+}
+
+func GetSchemaNamesInBackup(toc *utils.TOC) []string {
+	schemaSet := make(map[string]bool, 0)
+	schemaNames := make([]string, 0)
+	addSchema := func(schema string) {
+		if schema != "" && !schemaSet[schema] {
+			schemaSet[schema] = true
+			schemaNames = append(schemaNames, schema)
+		}
+	}
+	if !backupConfig.DataOnly {
+		for _, entry := range toc.PredataEntries {
+			addSchema(entry.Schema)
+		}
+	} else {
+		for _, entry := range toc.DataEntries {
+			addSchema(entry.Schema)
+		}
+	}
+	return schemaNames
+}
+
+func GetRelationNamesInBackup(toc *utils.TOC) []string {
+	relationSet := make(map[string]bool, 0)
+	relationNames := make([]string, 0)
+	addRelation := func(fqn string) {
+		if !relationSet[fqn] {
+			relationSet[fqn] = true
+			relationNames = append(relationNames, fqn)
+		}
+	}
+	for _, entry := range toc.PredataEntries {
+		if entry.ObjectType == "TABLE" || entry.ObjectType == "SEQUENCE" || entry.ObjectType == "VIEW" || entry.ObjectType == "MATERIALIZED VIEW" {
+			addRelation(utils.MakeFQN(entry.Schema, entry.Name))
+		}
+	}
+	for _, entry := range toc.DataEntries {
+		addRelation(utils.MakeFQN(entry.Schema, entry.Name))
+	}
+	return relationNames
+}
+
 func SetRestorePlanForLegacyBackup(toc *utils.TOC, backupTimestamp string, backupConfig *backup_history.BackupConfig) {
 	tableFQNs := make([]string, 0, len(toc.DataEntries))
 	for _, entry := range toc.DataEntries {
@@ -166,6 +229,9 @@ func RecoverMetadataFilesUsingPlugin() {
 	}
 
 	for _, fpInfo := range fpInfoList {
+		if fpInfo.Timestamp != globalFPInfo.Timestamp {
+			pluginConfig.MustRestoreFile(fpInfo.GetConfigFilePath())
+		}
 		pluginConfig.MustRestoreFile(fpInfo.GetTOCFilePath())
 		if backupConfig.SingleDataFile {
 			pluginConfig.RestoreSegmentTOCs(globalCluster, fpInfo)
@@ -178,7 +244,10 @@ func RecoverMetadataFilesUsingPlugin() {
  */
 
 func GetRestoreMetadataStatements(section string, filename string, includeObjectTypes []string, excludeObjectTypes []string, filterSchemas bool, filterRelations bool) []utils.StatementWithType {
-	metadataFile := iohelper.MustOpenFileForReading(filename)
+	var metadataFile io.ReaderAt
+	if !globalTOC.HasMetadataEntryPaths(section) {
+		metadataFile = iohelper.MustOpenFileForReading(filename)
+	}
 	var statements []utils.StatementWithType
 	var inSchemas, exSchemas, inRelations, exRelations []string
 	if len(includeObjectTypes) > 0 || len(excludeObjectTypes) > 0 || filterSchemas || filterRelations {
@@ -197,16 +266,57 @@ func GetRestoreMetadataStatements(section string, filename string, includeObject
 			}
 		}
 	}
-	statements = globalTOC.GetSQLStatementForObjectTypes(section, metadataFile, includeObjectTypes, excludeObjectTypes, inSchemas, exSchemas, inRelations, exRelations)
+	statements = globalTOC.GetSQLStatementForObjectTypes(section, metadataFile, path.Dir(filename), includeObjectTypes, excludeObjectTypes, inSchemas, exSchemas, inRelations, exRelations)
 	return statements
 }
 
-func ExecuteRestoreMetadataStatements(statements []utils.StatementWithType, objectsTitle string, progressBar utils.ProgressBar, showProgressBar int, executeInParallel bool) {
+/*
+ * The pre-data statements for a materialized view include its comment, owner,
+ * and privileges as well as its definition, so only one REFRESH statement is
+ * generated per materialized view, in the order in which they were created.
+ * Materialized views that were not populated at backup time are skipped.
+ */
+func GetRefreshMaterializedViewStatements(statements []utils.StatementWithType, unpopulatedMatviews []string) []utils.StatementWithType {
+	refreshStatements := make([]utils.StatementWithType, 0)
+	matviewSet := make(map[string]bool, 0)
+	for _, fqn := range unpopulatedMatviews {
+		matviewSet[fqn] = true
+	}
+	for _, statement := range statements {
+		fqn := utils.MakeFQN(statement.Schema, statement.Name)
+		if matviewSet[fqn] {
+			continue
+		}
+		matviewSet[fqn] = true
+		refreshStatements = append(refreshStatements, utils.StatementWithType{Schema: statement.Schema, Name: statement.Name,
+			ObjectType: statement.ObjectType, Statement: fmt.Sprintf("REFRESH MATERIALIZED VIEW %s;", fqn)})
+	}
+	return refreshStatements
+}
+
+func ExecuteRestoreMetadataStatements(statements []utils.StatementWithType, section string, objectsTitle string, progressBar utils.ProgressBar, showProgressBar int, executeInParallel bool) {
 	if progressBar == nil {
-		ExecuteStatementsAndCreateProgressBar(statements, objectsTitle, showProgressBar, executeInParallel)
+		ExecuteStatementsAndCreateProgressBar(statements, section, objectsTitle, showProgressBar, executeInParallel)
 	} else {
-		ExecuteStatements(statements, progressBar, executeInParallel)
+		ExecuteStatements(statements, section, progressBar, executeInParallel)
+	}
+}
+
+/*
+ * Tablespace flag values are validated in DoValidation, so we can safely
+ * ignore parsing errors here.
+ */
+func SubstituteTablespaces(statements []utils.StatementWithType) []utils.StatementWithType {
+	if MustGetFlagBool(utils.NO_TABLESPACES) {
+		return utils.RemoveTablespacesFromStatements(statements)
 	}
+	tablespaceMap, _ := utils.ParseColonSeparatedFlagValues(utils.TABLESPACE_MAP, MustGetFlagStringSlice(utils.TABLESPACE_MAP))
+	for oldName, newName := range tablespaceMap {
+		tablespaceMap[oldName] = utils.QuoteIdent(connectionPool, newName)
+	}
+	statements = utils.SubstituteTablespacesInStatements(statements, tablespaceMap)
+	locationMap, _ := utils.ParseColonSeparatedFlagValues(utils.TABLESPACE_LOCATION, MustGetFlagStringSlice(utils.TABLESPACE_LOCATION))
+	return utils.SubstituteTablespaceLocationsInStatements(statements, locationMap)
 }
 
 func GetBackupFPInfoListFromRestorePlan() []backup_filepath.FilePathInfo {
@@ -231,7 +341,7 @@ func setGUCsForConnection(gucStatements []utils.StatementWithType, whichConn int
 		objectTypes := []string{"SESSION GUCS"}
 		gucStatements = GetRestoreMetadataStatements("global", globalFPInfo.GetMetadataFilePath(), objectTypes, []string{}, false, false)
 	}
-	ExecuteStatementsAndCreateProgressBar(gucStatements, "", utils.PB_NONE, false, whichConn)
+	ExecuteStatementsAndCreateProgressBar(gucStatements, "global", "", utils.PB_NONE, false, whichConn)
 	return gucStatements
 }
 
@@ -247,6 +357,7 @@ func RestoreSchemas(schemaStatements []utils.StatementWithType, progressBar util
 				errMsg := fmt.Sprintf("Error encountered while creating schema %s", schema.Name)
 				if MustGetFlagBool(utils.ON_ERROR_CONTINUE) {
 					gplog.Verbose(fmt.Sprintf("%s: %s", errMsg, err.Error()))
+					restoreErrors.AddStatementError("predata", schema, err)
 					numErrors++
 				} else {
 					gplog.Fatal(err, errMsg)
@@ -259,3 +370,39 @@ func RestoreSchemas(schemaStatements []utils.StatementWithType, progressBar util
 		gplog.Error("Encountered %d errors during schema restore; see log file %s for a list of errors.", numErrors, gplog.GetLogFilePath())
 	}
 }
+
+/*
+ * Any backup in an incremental chain can be restored by passing its timestamp
+ * to --timestamp, in which case the restore plan stored with that backup is
+ * used, so each backup in the restore plan is a restore point.
+ */
+func ListRestorePoints() {
+	gplog.Info("Restore points for backup %s:", globalFPInfo.Timestamp)
+	gplog.Info("%-16s%-13s%-18s%-17s%s", "Timestamp", "Type", "Tables Backed Up", "Tables Restored", "Status")
+	chainErrors := make([]error, 0)
+	for i, fpInfo := range GetBackupFPInfoListFromRestorePlan() {
+		backupType, tablesBackedUp, tablesRestored, status := "-", "-", "-", "OK"
+		entryConfig, err := ReadBackupConfigForRestorePlanEntry(fpInfo)
+		if err == nil {
+			backupType = entryConfig.GetBackupType()
+			backupType = strings.ToUpper(backupType[:1]) + backupType[1:]
+			if entryConfig.RestorePlan != nil {
+				numTables := 0
+				for _, entry := range entryConfig.RestorePlan {
+					numTables += len(entry.TableFQNs)
+				}
+				tablesBackedUp = fmt.Sprintf("%d", len(entryConfig.RestorePlan[len(entryConfig.RestorePlan)-1].TableFQNs))
+				tablesRestored = fmt.Sprintf("%d", numTables)
+			}
+			err = ValidateRestorePlanEntry(backupConfig, entryConfig, i)
+		}
+		if err != nil {
+			status = "Invalid"
+			chainErrors = append(chainErrors, err)
+		}
+		gplog.Info("%-16s%-13s%-18s%-17s%s", fpInfo.Timestamp, backupType, tablesBackedUp, tablesRestored, status)
+	}
+	for _, err := range chainErrors {
+		gplog.Error(err.Error())
+	}
+}
@@ -90,6 +90,89 @@ var _ = Describe("wrapper tests", func() {
 			restore.RestoreSchemas(schemaArray, ignoredProgressBar)
 		})
 	})
+	Describe("GetSchemaNamesInBackup and GetRelationNamesInBackup", func() {
+		toc := utils.TOC{
+			PredataEntries: []utils.MetadataEntry{
+				{Schema: "schema1", Name: "schema1", ObjectType: "SCHEMA"},
+				{Schema: "schema1", Name: "table1", ObjectType: "TABLE"},
+				{Schema: "schema1", Name: "seq1", ObjectType: "SEQUENCE"},
+				{Schema: "schema1", Name: "func1", ObjectType: "FUNCTION"},
+				{Schema: "schema2", Name: "view2", ObjectType: "VIEW"},
+				{Schema: "schema2", Name: "matview2", ObjectType: "MATERIALIZED VIEW"},
+			},
+			DataEntries: []utils.MasterDataEntry{
+				{Schema: "schema1", Name: "table1"},
+				{Schema: "schema3", Name: "table3"},
+			},
+		}
+		AfterEach(func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{})
+		})
+		It("returns each schema containing metadata once", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{})
+			Expect(restore.GetSchemaNamesInBackup(&toc)).To(Equal([]string{"schema1", "schema2"}))
+		})
+		It("returns each schema containing data once for a data-only backup", func() {
+			restore.SetBackupConfig(&backup_history.BackupConfig{DataOnly: true})
+			Expect(restore.GetSchemaNamesInBackup(&toc)).To(Equal([]string{"schema1", "schema3"}))
+		})
+		It("returns each relation with metadata or data once", func() {
+			Expect(restore.GetRelationNamesInBackup(&toc)).To(Equal([]string{"schema1.table1", "schema1.seq1", "schema2.view2", "schema2.matview2", "schema3.table3"}))
+		})
+	})
+	Describe("GetRefreshMaterializedViewStatements", func() {
+		It("returns one REFRESH statement for each materialized view, in order", func() {
+			statements := []utils.StatementWithType{
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv1 AS SELECT 1\nWITH NO DATA;"},
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "ALTER MATERIALIZED VIEW public.mv1 OWNER TO testrole;"},
+				{Schema: "public", Name: "mv2", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv2 AS SELECT 1\nWITH NO DATA;"},
+			}
+			refreshStatements := restore.GetRefreshMaterializedViewStatements(statements, []string{})
+			Expect(refreshStatements).To(Equal([]utils.StatementWithType{
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "REFRESH MATERIALIZED VIEW public.mv1;"},
+				{Schema: "public", Name: "mv2", ObjectType: "MATERIALIZED VIEW", Statement: "REFRESH MATERIALIZED VIEW public.mv2;"},
+			}))
+		})
+		It("does not refresh materialized views that were not populated at backup time", func() {
+			statements := []utils.StatementWithType{
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv1 AS SELECT 1\nWITH NO DATA;"},
+				{Schema: "public", Name: "mv2", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv2 AS SELECT 1\nWITH NO DATA;"},
+			}
+			refreshStatements := restore.GetRefreshMaterializedViewStatements(statements, []string{"public.mv1"})
+			Expect(refreshStatements).To(Equal([]utils.StatementWithType{
+				{Schema: "public", Name: "mv2", ObjectType: "MATERIALIZED VIEW", Statement: "REFRESH MATERIALIZED VIEW public.mv2;"},
+			}))
+		})
+	})
+	Describe("SubstituteTablespaces", func() {
+		matview := utils.StatementWithType{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv1 TABLESPACE test_tablespace AS SELECT 1\nWITH NO DATA;"}
+		BeforeEach(func() {
+			cmdFlags.Bool(utils.NO_TABLESPACES, false, "")
+			cmdFlags.StringSlice(utils.TABLESPACE_MAP, []string{}, "")
+			cmdFlags.StringSlice(utils.TABLESPACE_LOCATION, []string{}, "")
+		})
+		It("removes the tablespace of a materialized view with --no-tablespaces", func() {
+			_ = cmdFlags.Set(utils.NO_TABLESPACES, "true")
+			statements := restore.SubstituteTablespaces([]utils.StatementWithType{matview})
+			Expect(statements).To(Equal([]utils.StatementWithType{
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv1 AS SELECT 1\nWITH NO DATA;"},
+			}))
+		})
+		It("substitutes the tablespace of a materialized view with --tablespace-map", func() {
+			_ = cmdFlags.Set(utils.TABLESPACE_MAP, "test_tablespace:new_tablespace")
+			mock.ExpectQuery("SELECT quote_ident").WillReturnRows(sqlmock.NewRows([]string{"quote_ident"}).AddRow("new_tablespace"))
+			statements := restore.SubstituteTablespaces([]utils.StatementWithType{matview})
+			Expect(statements).To(Equal([]utils.StatementWithType{
+				{Schema: "public", Name: "mv1", ObjectType: "MATERIALIZED VIEW", Statement: "CREATE MATERIALIZED VIEW public.mv1 TABLESPACE new_tablespace AS SELECT 1\nWITH NO DATA;"},
+			}))
+		})
+		It("quotes the new tablespace names in --tablespace-map", func() {
+			_ = cmdFlags.Set(utils.TABLESPACE_MAP, "test_tablespace:New Tablespace")
+			mock.ExpectQuery(`SELECT quote_ident\('New Tablespace'\)`).WillReturnRows(sqlmock.NewRows([]string{"quote_ident"}).AddRow(`"New Tablespace"`))
+			statements := restore.SubstituteTablespaces([]utils.StatementWithType{matview})
+			Expect(statements[0].Statement).To(Equal("CREATE MATERIALIZED VIEW public.mv1 TABLESPACE \"New Tablespace\" AS SELECT 1\nWITH NO DATA;"))
+		})
+	})
 	Describe("SetRestorePlanForLegacyBackup", func() {
 		legacyBackupConfig := backup_history.BackupConfig{}
 		legacyBackupConfig.RestorePlan = nil
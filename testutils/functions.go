@@ -147,6 +147,7 @@ var objNameToClassID = map[string]uint32{
 	"FUNCTION":                  1255,
 	"INDEX":                     2610,
 	"LANGUAGE":                  2612,
+	"MATERIALIZED VIEW":         1259,
 	"OPERATOR CLASS":            2616,
 	"OPERATOR FAMILY":           2753,
 	"OPERATOR":                  2617,
@@ -176,13 +177,13 @@ func ClassIDFromObjectName(objName string) uint32 {
 func DefaultACLForType(grantee string, objType string) backup.ACL {
 	return backup.ACL{
 		Grantee:    grantee,
-		Select:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
-		Insert:     objType == "PROTOCOL" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
-		Update:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
-		Delete:     objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
-		Truncate:   objType == "TABLE" || objType == "VIEW",
-		References: objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
-		Trigger:    objType == "TABLE" || objType == "VIEW" || objType == "FOREIGN TABLE",
+		Select:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
+		Insert:     objType == "PROTOCOL" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
+		Update:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
+		Delete:     objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
+		Truncate:   objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		References: objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
+		Trigger:    objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW" || objType == "FOREIGN TABLE",
 		Usage:      objType == "LANGUAGE" || objType == "SCHEMA" || objType == "SEQUENCE" || objType == "FOREIGN DATA WRAPPER" || objType == "FOREIGN SERVER",
 		Execute:    objType == "FUNCTION",
 		Create:     objType == "DATABASE" || objType == "SCHEMA" || objType == "TABLESPACE",
@@ -194,13 +195,13 @@ func DefaultACLForType(grantee string, objType string) backup.ACL {
 func DefaultACLForTypeWithGrant(grantee string, objType string) backup.ACL {
 	return backup.ACL{
 		Grantee:             grantee,
-		SelectWithGrant:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW",
-		InsertWithGrant:     objType == "PROTOCOL" || objType == "TABLE" || objType == "VIEW",
-		UpdateWithGrant:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW",
-		DeleteWithGrant:     objType == "TABLE" || objType == "VIEW",
-		TruncateWithGrant:   objType == "TABLE" || objType == "VIEW",
-		ReferencesWithGrant: objType == "TABLE" || objType == "VIEW",
-		TriggerWithGrant:    objType == "TABLE" || objType == "VIEW",
+		SelectWithGrant:     objType == "PROTOCOL" || objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		InsertWithGrant:     objType == "PROTOCOL" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		UpdateWithGrant:     objType == "SEQUENCE" || objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		DeleteWithGrant:     objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		TruncateWithGrant:   objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		ReferencesWithGrant: objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
+		TriggerWithGrant:    objType == "TABLE" || objType == "VIEW" || objType == "MATERIALIZED VIEW",
 		UsageWithGrant:      objType == "LANGUAGE" || objType == "SCHEMA" || objType == "SEQUENCE" || objType == "FOREIGN DATA WRAPPER" || objType == "FOREIGN SERVER",
 		ExecuteWithGrant:    objType == "FUNCTION",
 		CreateWithGrant:     objType == "DATABASE" || objType == "SCHEMA" || objType == "TABLESPACE",
@@ -0,0 +1,225 @@
+package utils
+
+/*
+ * This file contains structs and functions related to comparing the metadata
+ * statements of two backups, or of a backup and a live database.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DIFF_ADDED   = "Added"
+	DIFF_CHANGED = "Changed"
+	DIFF_REMOVED = "Removed"
+
+	diffContextLines = 3
+)
+
+type ObjectDiff struct {
+	Section         string
+	ObjectType      string
+	Schema          string
+	Name            string
+	ReferenceObject string
+	Change          string
+	Diff            string
+}
+
+func (objectDiff ObjectDiff) String() string {
+	name := objectDiff.Name
+	if objectDiff.Schema != "" {
+		name = MakeFQN(objectDiff.Schema, objectDiff.Name)
+	}
+	if objectDiff.ReferenceObject != "" {
+		name = fmt.Sprintf("%s on %s", name, objectDiff.ReferenceObject)
+	}
+	return fmt.Sprintf("%s %s %s", objectDiff.Change, objectDiff.ObjectType, name)
+}
+
+/*
+ * The Name of an overloadable object's TOC entry carries its argument
+ * signature, as in "foo(integer)" for a function or "+ (integer, integer)"
+ * for an operator, and operator classes and families carry their index method,
+ * so each overload is compared separately.
+ */
+type diffKey struct {
+	ObjectType      string
+	Schema          string
+	Name            string
+	ReferenceObject string
+}
+
+/*
+ * An object may have several TOC entries, such as its CREATE statement
+ * followed by its owner and privileges, so the statements of all entries that
+ * share a key are compared together.
+ */
+func groupStatementsByObject(statements []StatementWithType) map[diffKey]string {
+	groupedStatements := make(map[diffKey][]string, 0)
+	for _, statement := range statements {
+		key := diffKey{statement.ObjectType, statement.Schema, statement.Name, statement.ReferenceObject}
+		groupedStatements[key] = append(groupedStatements[key], strings.TrimSpace(statement.Statement))
+	}
+	objects := make(map[diffKey]string, len(groupedStatements))
+	for key, objectStatements := range groupedStatements {
+		objects[key] = strings.Join(objectStatements, "\n")
+	}
+	return objects
+}
+
+/*
+ * Objects are matched by object type, schema, name, and reference object
+ * within a section, and the diffs are sorted by those fields so that the
+ * output does not depend on the order in which the objects were backed up.
+ */
+func DiffMetadataStatements(section string, left []StatementWithType, right []StatementWithType, leftLabel string, rightLabel string) []ObjectDiff {
+	leftObjects := groupStatementsByObject(left)
+	rightObjects := groupStatementsByObject(right)
+	diffs := make([]ObjectDiff, 0)
+	for key, leftStatement := range leftObjects {
+		rightStatement, ok := rightObjects[key]
+		if !ok {
+			diffs = append(diffs, ObjectDiff{section, key.ObjectType, key.Schema, key.Name, key.ReferenceObject, DIFF_REMOVED, UnifiedDiff(leftLabel, rightLabel, leftStatement, "")})
+		} else if leftStatement != rightStatement {
+			diffs = append(diffs, ObjectDiff{section, key.ObjectType, key.Schema, key.Name, key.ReferenceObject, DIFF_CHANGED, UnifiedDiff(leftLabel, rightLabel, leftStatement, rightStatement)})
+		}
+	}
+	for key, rightStatement := range rightObjects {
+		if _, ok := leftObjects[key]; !ok {
+			diffs = append(diffs, ObjectDiff{section, key.ObjectType, key.Schema, key.Name, key.ReferenceObject, DIFF_ADDED, UnifiedDiff(leftLabel, rightLabel, "", rightStatement)})
+		}
+	}
+	sort.Slice(diffs, func(i int, j int) bool {
+		if diffs[i].ObjectType != diffs[j].ObjectType {
+			return diffs[i].ObjectType < diffs[j].ObjectType
+		}
+		if diffs[i].Schema != diffs[j].Schema {
+			return diffs[i].Schema < diffs[j].Schema
+		}
+		if diffs[i].Name != diffs[j].Name {
+			return diffs[i].Name < diffs[j].Name
+		}
+		return diffs[i].ReferenceObject < diffs[j].ReferenceObject
+	})
+	return diffs
+}
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+/*
+ * The lines of each statement are matched using their longest common
+ * subsequence, which is fast enough for statements of the size found in
+ * metadata files.
+ */
+func diffLines(left []string, right []string) []diffLine {
+	lcsLengths := make([][]int, len(left)+1)
+	for i := range lcsLengths {
+		lcsLengths[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lcsLengths[i][j] = lcsLengths[i+1][j+1] + 1
+			} else if lcsLengths[i+1][j] >= lcsLengths[i][j+1] {
+				lcsLengths[i][j] = lcsLengths[i+1][j]
+			} else {
+				lcsLengths[i][j] = lcsLengths[i][j+1]
+			}
+		}
+	}
+	lines := make([]diffLine, 0, len(left)+len(right))
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		if left[i] == right[j] {
+			lines = append(lines, diffLine{' ', left[i]})
+			i++
+			j++
+		} else if lcsLengths[i+1][j] >= lcsLengths[i][j+1] {
+			lines = append(lines, diffLine{'-', left[i]})
+			i++
+		} else {
+			lines = append(lines, diffLine{'+', right[j]})
+			j++
+		}
+	}
+	for ; i < len(left); i++ {
+		lines = append(lines, diffLine{'-', left[i]})
+	}
+	for ; j < len(right); j++ {
+		lines = append(lines, diffLine{'+', right[j]})
+	}
+	return lines
+}
+
+func formatHunkRange(start int, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func UnifiedDiff(leftLabel string, rightLabel string, left string, right string) string {
+	lines := diffLines(splitDiffLines(left), splitDiffLines(right))
+	diff := fmt.Sprintf("--- %s\n+++ %s\n", leftLabel, rightLabel)
+	for hunkStart := 0; hunkStart < len(lines); {
+		if lines[hunkStart].op == ' ' {
+			hunkStart++
+			continue
+		}
+		// Extend the hunk until the next change is too far away to share context
+		hunkEnd := hunkStart
+		for next := hunkStart; next < len(lines) && next-hunkEnd <= 2*diffContextLines; next++ {
+			if lines[next].op != ' ' {
+				hunkEnd = next
+			}
+		}
+		first := hunkStart - diffContextLines
+		if first < 0 {
+			first = 0
+		}
+		last := hunkEnd + diffContextLines
+		if last > len(lines)-1 {
+			last = len(lines) - 1
+		}
+		leftStart, rightStart := 1, 1
+		for _, line := range lines[:first] {
+			if line.op != '+' {
+				leftStart++
+			}
+			if line.op != '-' {
+				rightStart++
+			}
+		}
+		leftCount, rightCount := 0, 0
+		hunk := ""
+		for _, line := range lines[first : last+1] {
+			if line.op != '+' {
+				leftCount++
+			}
+			if line.op != '-' {
+				rightCount++
+			}
+			hunk += fmt.Sprintf("%c%s\n", line.op, line.text)
+		}
+		diff += fmt.Sprintf("@@ -%s +%s @@\n%s", formatHunkRange(leftStart, leftCount), formatHunkRange(rightStart, rightCount), hunk)
+		hunkStart = last + 1
+	}
+	return diff
+}
@@ -0,0 +1,105 @@
+package utils_test
+
+import (
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/diff tests", func() {
+	Describe("UnifiedDiff", func() {
+		It("returns only the headers for identical text", func() {
+			Expect(utils.UnifiedDiff("a", "b", "line1\nline2", "line1\nline2")).To(Equal("--- a\n+++ b\n"))
+		})
+		It("shows a changed line with surrounding context", func() {
+			left := "1\n2\n3\n4\n5\n6\n7\n8\n9"
+			right := "1\n2\n3\n4\nfive\n6\n7\n8\n9"
+			Expect(utils.UnifiedDiff("a", "b", left, right)).To(Equal(`--- a
++++ b
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`))
+		})
+		It("splits changes that are far apart into separate hunks", func() {
+			left := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10"
+			right := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten"
+			Expect(utils.UnifiedDiff("a", "b", left, right)).To(Equal(`--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -7,4 +7,4 @@
+ 7
+ 8
+ 9
+-10
++ten
+`))
+		})
+		It("shows added text against empty text", func() {
+			Expect(utils.UnifiedDiff("a", "b", "", "CREATE SCHEMA foo;")).To(Equal("--- a\n+++ b\n@@ -0,0 +1 @@\n+CREATE SCHEMA foo;\n"))
+		})
+	})
+	Describe("DiffMetadataStatements", func() {
+		It("reports added, removed, and changed objects sorted by type, schema, and name", func() {
+			left := []utils.StatementWithType{
+				{ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "\n\nCREATE TABLE public.foo (i int);\n"},
+				{ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "\n\nALTER TABLE public.foo OWNER TO testrole;\n"},
+				{ObjectType: "TABLE", Schema: "public", Name: "bar", Statement: "\n\nCREATE TABLE public.bar (i int);\n"},
+				{ObjectType: "SCHEMA", Schema: "", Name: "public", Statement: "\n\nCREATE SCHEMA public;\n"},
+			}
+			right := []utils.StatementWithType{
+				{ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "\n\nCREATE TABLE public.foo (i int);\n"},
+				{ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "\n\nALTER TABLE public.foo OWNER TO otherrole;\n"},
+				{ObjectType: "SCHEMA", Schema: "", Name: "public", Statement: "\n\nCREATE SCHEMA public;\n"},
+				{ObjectType: "INDEX", Schema: "public", Name: "foo_idx", ReferenceObject: "public.foo", Statement: "\n\nCREATE INDEX foo_idx ON public.foo USING btree (i);\n"},
+			}
+
+			diffs := utils.DiffMetadataStatements("predata", left, right, "backup 1", "backup 2")
+
+			Expect(diffs).To(HaveLen(3))
+			Expect(diffs[0].String()).To(Equal("Added INDEX public.foo_idx on public.foo"))
+			Expect(diffs[0].Diff).To(Equal("--- backup 1\n+++ backup 2\n@@ -0,0 +1 @@\n+CREATE INDEX foo_idx ON public.foo USING btree (i);\n"))
+			Expect(diffs[1].String()).To(Equal("Removed TABLE public.bar"))
+			Expect(diffs[1].Diff).To(Equal("--- backup 1\n+++ backup 2\n@@ -1 +0,0 @@\n-CREATE TABLE public.bar (i int);\n"))
+			Expect(diffs[2].String()).To(Equal("Changed TABLE public.foo"))
+			Expect(diffs[2].Section).To(Equal("predata"))
+			Expect(diffs[2].Diff).To(Equal(`--- backup 1
++++ backup 2
+@@ -1,2 +1,2 @@
+ CREATE TABLE public.foo (i int);
+-ALTER TABLE public.foo OWNER TO testrole;
++ALTER TABLE public.foo OWNER TO otherrole;
+`))
+		})
+		It("compares each overload of a function or operator separately", func() {
+			left := []utils.StatementWithType{
+				{ObjectType: "FUNCTION", Schema: "public", Name: "foo(integer)", Statement: "\n\nCREATE FUNCTION public.foo(integer) RETURNS integer AS $$SELECT 1$$ LANGUAGE sql;\n"},
+				{ObjectType: "FUNCTION", Schema: "public", Name: "foo(text)", Statement: "\n\nCREATE FUNCTION public.foo(text) RETURNS integer AS $$SELECT 2$$ LANGUAGE sql;\n"},
+				{ObjectType: "OPERATOR", Schema: "public", Name: "## (integer, integer)", Statement: "\n\nCREATE OPERATOR public.## (PROCEDURE = public.intfoo, LEFTARG = integer, RIGHTARG = integer);\n"},
+				{ObjectType: "OPERATOR", Schema: "public", Name: "## (text, text)", Statement: "\n\nCREATE OPERATOR public.## (PROCEDURE = public.textfoo, LEFTARG = text, RIGHTARG = text);\n"},
+			}
+			right := []utils.StatementWithType{left[1], left[0], left[3]}
+
+			diffs := utils.DiffMetadataStatements("predata", left, right, "backup 1", "backup 2")
+
+			Expect(diffs).To(HaveLen(1))
+			Expect(diffs[0].String()).To(Equal("Removed OPERATOR public.## (integer, integer)"))
+		})
+		It("reports no differences for identical statements", func() {
+			statements := []utils.StatementWithType{{ObjectType: "SCHEMA", Name: "public", Statement: "\n\nCREATE SCHEMA public;\n"}}
+			Expect(utils.DiffMetadataStatements("predata", statements, statements, "a", "b")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,97 @@
+package utils
+
+/*
+ * This file contains structs and functions for combining the schema and
+ * relation filters passed to gpbackup and gprestore.
+ */
+
+import (
+	"github.com/greenplum-db/gpbackup/backup_history"
+)
+
+/*
+ * A Filter combines the --include-schema, --exclude-schema, --include-table,
+ * and --exclude-table lists.  An object is included if it matches the include
+ * lists, if any were given, and does not match either exclude list, so that an
+ * exclusion always takes precedence over an inclusion; for example, including
+ * a schema and excluding two of its tables includes every other object in the
+ * schema.
+ *
+ * Objects that are not relations and do not belong to a relation, such as
+ * functions and types, are only included when no relations are included by
+ * name, as a backup of specific tables only contains those tables and the
+ * objects they depend on.
+ */
+type Filter struct {
+	IncludeSchemas   []string
+	ExcludeSchemas   []string
+	IncludeRelations []string
+	ExcludeRelations []string
+
+	includeSchemaSet   *FilterSet
+	excludeSchemaSet   *FilterSet
+	includeRelationSet *FilterSet
+	excludeRelationSet *FilterSet
+}
+
+func NewFilter(includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) Filter {
+	return Filter{
+		IncludeSchemas:     includeSchemas,
+		ExcludeSchemas:     excludeSchemas,
+		IncludeRelations:   includeRelations,
+		ExcludeRelations:   excludeRelations,
+		includeSchemaSet:   NewIncludeSet(includeSchemas),
+		excludeSchemaSet:   NewExcludeSet(excludeSchemas),
+		includeRelationSet: NewIncludeSet(includeRelations),
+		excludeRelationSet: NewExcludeSet(excludeRelations),
+	}
+}
+
+func NewFilterFromBackupConfig(config *backup_history.BackupConfig) Filter {
+	return NewFilter(config.IncludeSchemas, config.ExcludeSchemas, config.IncludeRelations, config.ExcludeRelations)
+}
+
+func (f Filter) MatchesSchema(schema string) bool {
+	return f.includeSchemaSet.MatchesFilter(schema) && f.excludeSchemaSet.MatchesFilter(schema)
+}
+
+// relationFQN is the relation itself for a table, view, or sequence, or the relation it belongs to for an index, trigger, etc.
+func (f Filter) MatchesRelation(schema string, relationFQN string) bool {
+	return f.MatchesSchema(schema) && f.includeRelationSet.MatchesFilter(relationFQN) && f.excludeRelationSet.MatchesFilter(relationFQN)
+}
+
+func (f Filter) MatchesNonRelation(schema string) bool {
+	return f.MatchesSchema(schema) && len(f.IncludeRelations) == 0
+}
+
+/*
+ * Two filters are equal if they include the same objects.  The lists are
+ * compared as sets, ignoring exclusions that the include lists already make
+ * redundant, so that e.g. a backup taken with --include-schema and an
+ * --exclude-schema naming a different schema matches one taken without the
+ * --exclude-schema.
+ */
+func (f Filter) Equals(other Filter) bool {
+	fSchemas, fRelations := f.effectiveExclusions()
+	otherSchemas, otherRelations := other.effectiveExclusions()
+	return NewSet(f.IncludeSchemas).Equals(NewSet(other.IncludeSchemas)) &&
+		NewSet(f.IncludeRelations).Equals(NewSet(other.IncludeRelations)) &&
+		NewSet(fSchemas).Equals(NewSet(otherSchemas)) &&
+		NewSet(fRelations).Equals(NewSet(otherRelations))
+}
+
+func (f Filter) effectiveExclusions() ([]string, []string) {
+	excludeSchemas := make([]string, 0)
+	for _, schema := range f.ExcludeSchemas {
+		if f.includeSchemaSet.MatchesFilter(schema) {
+			excludeSchemas = append(excludeSchemas, schema)
+		}
+	}
+	excludeRelations := make([]string, 0)
+	for _, relation := range f.ExcludeRelations {
+		if f.includeRelationSet.MatchesFilter(relation) {
+			excludeRelations = append(excludeRelations, relation)
+		}
+	}
+	return excludeSchemas, excludeRelations
+}
@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/filter tests", func() {
+	Describe("MatchesRelation", func() {
+		It("includes every relation when there are no filters", func() {
+			filter := utils.NewFilter([]string{}, []string{}, []string{}, []string{})
+			Expect(filter.MatchesRelation("public", "public.foo")).To(BeTrue())
+		})
+		It("includes relations in an included schema except for excluded relations", func() {
+			filter := utils.NewFilter([]string{"public"}, []string{}, []string{}, []string{"public.audit"})
+			Expect(filter.MatchesRelation("public", "public.foo")).To(BeTrue())
+			Expect(filter.MatchesRelation("public", "public.audit")).To(BeFalse())
+			Expect(filter.MatchesRelation("other", "other.foo")).To(BeFalse())
+		})
+		It("excludes an included relation that is also excluded", func() {
+			filter := utils.NewFilter([]string{}, []string{}, []string{"public.foo", "public.bar"}, []string{"public.bar"})
+			Expect(filter.MatchesRelation("public", "public.foo")).To(BeTrue())
+			Expect(filter.MatchesRelation("public", "public.bar")).To(BeFalse())
+		})
+		It("excludes an included relation in an excluded schema", func() {
+			filter := utils.NewFilter([]string{}, []string{"public"}, []string{"public.foo", "other.foo"}, []string{})
+			Expect(filter.MatchesRelation("public", "public.foo")).To(BeFalse())
+			Expect(filter.MatchesRelation("other", "other.foo")).To(BeTrue())
+		})
+	})
+	Describe("MatchesNonRelation", func() {
+		It("includes objects in included schemas that are not excluded", func() {
+			filter := utils.NewFilter([]string{"public", "other"}, []string{"other"}, []string{}, []string{"public.foo"})
+			Expect(filter.MatchesNonRelation("public")).To(BeTrue())
+			Expect(filter.MatchesNonRelation("other")).To(BeFalse())
+		})
+		It("does not include objects when relations are included", func() {
+			filter := utils.NewFilter([]string{}, []string{}, []string{"public.foo"}, []string{})
+			Expect(filter.MatchesNonRelation("public")).To(BeFalse())
+		})
+	})
+	Describe("Equals", func() {
+		It("compares the filter lists as sets", func() {
+			filter1 := utils.NewFilter([]string{"s1", "s2"}, []string{}, []string{}, []string{"s1.foo", "s2.bar"})
+			filter2 := utils.NewFilter([]string{"s2", "s1"}, nil, nil, []string{"s2.bar", "s1.foo"})
+			Expect(filter1.Equals(filter2)).To(BeTrue())
+		})
+		It("ignores exclusions made redundant by the include lists", func() {
+			filter1 := utils.NewFilter([]string{"s1"}, []string{"s2"}, []string{}, []string{})
+			filter2 := utils.NewFilter([]string{"s1"}, []string{}, []string{}, []string{})
+			Expect(filter1.Equals(filter2)).To(BeTrue())
+
+			filter1 = utils.NewFilter([]string{}, []string{}, []string{"s1.foo"}, []string{"s1.bar"})
+			filter2 = utils.NewFilter([]string{}, []string{}, []string{"s1.foo"}, []string{})
+			Expect(filter1.Equals(filter2)).To(BeTrue())
+		})
+		It("does not match filters that include different objects", func() {
+			filter1 := utils.NewFilter([]string{"s1"}, []string{}, []string{}, []string{"s1.foo"})
+			filter2 := utils.NewFilter([]string{"s1"}, []string{}, []string{}, []string{})
+			Expect(filter1.Equals(filter2)).To(BeFalse())
+		})
+	})
+})
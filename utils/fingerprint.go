@@ -0,0 +1,207 @@
+package utils
+
+/*
+ * This file contains structs and functions related to computing and comparing
+ * per-segment fingerprints of table data, which are used to verify that the
+ * data in a restored table matches the data that was backed up.
+ */
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+)
+
+/*
+ * The format of the checksums in the fingerprints, which is recorded in the
+ * TOC so that fingerprints computed with different checksums are never
+ * compared.  Version 1 sums the first 64 bits of the md5 hash of each row;
+ * fingerprints recorded without a version summed the 32-bit hashtext of each
+ * row instead.
+ */
+const FINGERPRINT_VERSION = 1
+
+type SegmentFingerprint struct {
+	ContentID int
+	NumRows   int64
+	Checksum  string
+}
+
+type FingerprintMismatch struct {
+	Schema    string
+	Name      string
+	ContentID int
+	Expected  SegmentFingerprint
+	Actual    SegmentFingerprint
+}
+
+type DataVerificationResult struct {
+	NumTablesVerified int
+	Mismatches        []FingerprintMismatch
+	Errors            []string
+}
+
+/*
+ * The checksum is the sum of the first 64 bits of the md5 hashes of the text
+ * representations of all rows on a segment, so it does not depend on the order
+ * in which the rows are stored.  The hash bytes are read with get_byte, which
+ * unlike a cast to bit(64) is available in all supported GPDB versions, and
+ * summed as numeric so that the sum cannot overflow.  Segments with no rows
+ * are not returned by the query, so they are filled in with an empty
+ * fingerprint to allow comparing against a cluster with a different data
+ * distribution.
+ */
+func GetTableFingerprints(connectionPool *dbconn.DBConn, tableFQN string, contentIDs []int, whichConn int) ([]SegmentFingerprint, error) {
+	rowHash := "get_byte(rowhash, 0)::numeric"
+	for i := 1; i < 8; i++ {
+		rowHash = fmt.Sprintf("(%s) * 256 + get_byte(rowhash, %d)", rowHash, i)
+	}
+	query := fmt.Sprintf(`
+SELECT
+	gp_segment_id AS contentid,
+	count(*) AS numrows,
+	coalesce(sum(%s), 0)::text AS checksum
+FROM (
+	SELECT
+		t.gp_segment_id,
+		decode(md5(textin(record_out(t.*))), 'hex') AS rowhash
+	FROM %s t
+) rowhashes
+GROUP BY gp_segment_id
+ORDER BY gp_segment_id;`, rowHash, tableFQN)
+	results := make([]SegmentFingerprint, 0)
+	err := connectionPool.Select(&results, query, whichConn)
+	if err != nil {
+		return nil, err
+	}
+	fingerprintMap := make(map[int]SegmentFingerprint, len(results))
+	for _, fingerprint := range results {
+		fingerprintMap[fingerprint.ContentID] = fingerprint
+	}
+	fingerprints := make([]SegmentFingerprint, 0)
+	for _, contentID := range contentIDs {
+		if contentID < 0 {
+			continue
+		}
+		fingerprint, ok := fingerprintMap[contentID]
+		if !ok {
+			fingerprint = SegmentFingerprint{ContentID: contentID, NumRows: 0, Checksum: "0"}
+		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints, nil
+}
+
+/*
+ * As each checksum is a sum of per-row hashes, the data is the same if the row
+ * counts and checksums summed over all segments match, even if rows were moved
+ * between segments by restoring to a cluster with a different number of
+ * segments or a different hash distribution.  Differences are reported for
+ * each segment when both sets of fingerprints cover the same segments, and
+ * otherwise for the cluster as a whole, with a content ID of -1.
+ */
+func CompareTableFingerprints(schema string, name string, expected []SegmentFingerprint, actual []SegmentFingerprint) []FingerprintMismatch {
+	mismatches := make([]FingerprintMismatch, 0)
+	expectedTotal, actualTotal := sumFingerprints(expected), sumFingerprints(actual)
+	if expectedTotal == actualTotal {
+		return mismatches
+	}
+
+	expectedMap := make(map[int]SegmentFingerprint, len(expected))
+	actualMap := make(map[int]SegmentFingerprint, len(actual))
+	contentIDs := make([]int, 0)
+	for _, fingerprint := range expected {
+		expectedMap[fingerprint.ContentID] = fingerprint
+		contentIDs = append(contentIDs, fingerprint.ContentID)
+	}
+	for _, fingerprint := range actual {
+		actualMap[fingerprint.ContentID] = fingerprint
+	}
+	if len(expectedMap) != len(actualMap) {
+		return append(mismatches, FingerprintMismatch{Schema: schema, Name: name, ContentID: -1, Expected: expectedTotal, Actual: actualTotal})
+	}
+	for contentID := range actualMap {
+		if _, ok := expectedMap[contentID]; !ok {
+			return append(mismatches, FingerprintMismatch{Schema: schema, Name: name, ContentID: -1, Expected: expectedTotal, Actual: actualTotal})
+		}
+	}
+	sort.Ints(contentIDs)
+
+	for _, contentID := range contentIDs {
+		expectedFingerprint, actualFingerprint := expectedMap[contentID], actualMap[contentID]
+		if expectedFingerprint != actualFingerprint {
+			mismatches = append(mismatches, FingerprintMismatch{Schema: schema, Name: name, ContentID: contentID, Expected: expectedFingerprint, Actual: actualFingerprint})
+		}
+	}
+	return mismatches
+}
+
+/*
+ * The checksums are summed as arbitrary-precision integers, as the sum of
+ * 64-bit hashes over all segments may not fit in an int64.
+ */
+func sumFingerprints(fingerprints []SegmentFingerprint) SegmentFingerprint {
+	total := SegmentFingerprint{ContentID: -1}
+	checksum := big.NewInt(0)
+	for _, fingerprint := range fingerprints {
+		total.NumRows += fingerprint.NumRows
+		segChecksum, ok := new(big.Int).SetString(fingerprint.Checksum, 10)
+		if !ok {
+			// An unparseable checksum can only match an identical one
+			total.Checksum = fmt.Sprintf("invalid checksum %q on segment %d", fingerprint.Checksum, fingerprint.ContentID)
+			return total
+		}
+		checksum.Add(checksum, segChecksum)
+	}
+	total.Checksum = checksum.String()
+	return total
+}
+
+func (mismatch FingerprintMismatch) SegmentString() string {
+	if mismatch.ContentID == -1 {
+		return "all segments"
+	}
+	return fmt.Sprintf("segment %d", mismatch.ContentID)
+}
+
+func (result *DataVerificationResult) GetMismatchedTables() []string {
+	tableSet := make(map[string]bool, 0)
+	tables := make([]string, 0)
+	for _, mismatch := range result.Mismatches {
+		fqn := MakeFQN(mismatch.Schema, mismatch.Name)
+		if !tableSet[fqn] {
+			tableSet[fqn] = true
+			tables = append(tables, fqn)
+		}
+	}
+	return tables
+}
+
+func PrintDataVerificationResult(reportFile io.WriteCloser, result *DataVerificationResult) {
+	verificationStatus := "Success"
+	if len(result.Mismatches) > 0 || len(result.Errors) > 0 {
+		verificationStatus = "Failure"
+	}
+	verificationStr := fmt.Sprintf("\n\nData Verification: %s\n", verificationStatus)
+	verificationStr += fmt.Sprintf("%-29s%d\n", "Tables verified", result.NumTablesVerified)
+	verificationStr += fmt.Sprintf("%-29s%d\n", "Tables with differences", len(result.GetMismatchedTables()))
+	verificationStr += fmt.Sprintf("%-29s%d\n", "Tables not verified", len(result.Errors))
+	if len(result.Mismatches) > 0 {
+		verificationStr += "\nDifferences by Table and Segment:\n"
+		for _, mismatch := range result.Mismatches {
+			verificationStr += fmt.Sprintf("%s %s: expected %d rows (checksum %s), restored %d rows (checksum %s)\n",
+				MakeFQN(mismatch.Schema, mismatch.Name), mismatch.SegmentString(), mismatch.Expected.NumRows, mismatch.Expected.Checksum,
+				mismatch.Actual.NumRows, mismatch.Actual.Checksum)
+		}
+	}
+	if len(result.Errors) > 0 {
+		verificationStr += "\nTables Not Verified:\n"
+		for _, errStr := range result.Errors {
+			verificationStr += fmt.Sprintf("%s\n", errStr)
+		}
+	}
+	MustPrintf(reportFile, "%s", verificationStr)
+}
@@ -0,0 +1,86 @@
+package utils_test
+
+import (
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+
+	"github.com/greenplum-db/gpbackup/utils"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/fingerprint tests", func() {
+	Describe("GetTableFingerprints", func() {
+		It("returns a fingerprint for each segment, filling in segments with no rows", func() {
+			rows := sqlmock.NewRows([]string{"contentid", "numrows", "checksum"}).
+				AddRow(0, 3, "12345").
+				AddRow(2, 1, "678")
+			mock.ExpectQuery(`SELECT (.*)md5\(textin\(record_out\(t.\*\)\)\)(.*) FROM public.foo t \) rowhashes GROUP BY gp_segment_id`).WillReturnRows(rows)
+
+			fingerprints, err := utils.GetTableFingerprints(connectionPool, "public.foo", []int{-1, 0, 1, 2}, 0)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fingerprints).To(Equal([]utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 3, Checksum: "12345"},
+				{ContentID: 1, NumRows: 0, Checksum: "0"},
+				{ContentID: 2, NumRows: 1, Checksum: "678"},
+			}))
+		})
+	})
+	Describe("CompareTableFingerprints", func() {
+		expected := []utils.SegmentFingerprint{
+			{ContentID: 0, NumRows: 3, Checksum: "12345"},
+			{ContentID: 1, NumRows: 0, Checksum: "0"},
+		}
+		It("returns no mismatches when the fingerprints are the same", func() {
+			actual := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 3, Checksum: "12345"},
+				{ContentID: 1, NumRows: 0, Checksum: "0"},
+			}
+			Expect(utils.CompareTableFingerprints("public", "foo", expected, actual)).To(BeEmpty())
+		})
+		It("returns a mismatch for each segment that differs", func() {
+			actual := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 3, Checksum: "54321"},
+				{ContentID: 1, NumRows: 1, Checksum: "999"},
+			}
+			mismatches := utils.CompareTableFingerprints("public", "foo", expected, actual)
+			Expect(mismatches).To(Equal([]utils.FingerprintMismatch{
+				{Schema: "public", Name: "foo", ContentID: 0, Expected: expected[0], Actual: actual[0]},
+				{Schema: "public", Name: "foo", ContentID: 1, Expected: expected[1], Actual: actual[1]},
+			}))
+		})
+		It("returns no mismatches when rows were moved between segments", func() {
+			actual := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 1, Checksum: "12000"},
+				{ContentID: 1, NumRows: 2, Checksum: "345"},
+			}
+			Expect(utils.CompareTableFingerprints("public", "foo", expected, actual)).To(BeEmpty())
+		})
+		It("compares the totals for all segments when the fingerprints are for different numbers of segments", func() {
+			largeExpected := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 3, Checksum: "18446744073709551615"},
+				{ContentID: 1, NumRows: 1, Checksum: "18446744073709551615"},
+			}
+			actual := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 1, Checksum: "18446744073709551615"},
+				{ContentID: 1, NumRows: 1, Checksum: "18446744073709551615"},
+				{ContentID: 2, NumRows: 2, Checksum: "0"},
+			}
+			Expect(utils.CompareTableFingerprints("public", "foo", largeExpected, actual)).To(BeEmpty())
+		})
+		It("returns a single mismatch for all segments when the fingerprints are for different numbers of segments", func() {
+			actual := []utils.SegmentFingerprint{
+				{ContentID: 0, NumRows: 1, Checksum: "12000"},
+				{ContentID: 1, NumRows: 1, Checksum: "300"},
+				{ContentID: 2, NumRows: 2, Checksum: "42"},
+			}
+			mismatches := utils.CompareTableFingerprints("public", "foo", expected, actual)
+			Expect(mismatches).To(Equal([]utils.FingerprintMismatch{
+				{Schema: "public", Name: "foo", ContentID: -1,
+					Expected: utils.SegmentFingerprint{ContentID: -1, NumRows: 3, Checksum: "12345"},
+					Actual:   utils.SegmentFingerprint{ContentID: -1, NumRows: 4, Checksum: "12342"}},
+			}))
+			Expect(mismatches[0].SegmentString()).To(Equal("all segments"))
+		})
+	})
+})
@@ -5,6 +5,8 @@ package utils
  */
 
 import (
+	"bytes"
+	"encoding/csv"
 	"regexp"
 	"strings"
 
@@ -14,33 +16,64 @@ import (
 )
 
 const (
-	BACKUP_DIR            = "backup-dir"
-	COMPRESSION_LEVEL     = "compression-level"
-	DATA_ONLY             = "data-only"
-	DBNAME                = "dbname"
-	DEBUG                 = "debug"
-	EXCLUDE_RELATION      = "exclude-table"
-	EXCLUDE_RELATION_FILE = "exclude-table-file"
-	EXCLUDE_SCHEMA        = "exclude-schema"
-	FROM_TIMESTAMP        = "from-timestamp"
-	INCLUDE_RELATION      = "include-table"
-	INCLUDE_RELATION_FILE = "include-table-file"
-	INCLUDE_SCHEMA        = "include-schema"
-	INCREMENTAL           = "incremental"
-	JOBS                  = "jobs"
-	LEAF_PARTITION_DATA   = "leaf-partition-data"
-	METADATA_ONLY         = "metadata-only"
-	NO_COMPRESSION        = "no-compression"
-	PLUGIN_CONFIG         = "plugin-config"
-	QUIET                 = "quiet"
-	SINGLE_DATA_FILE      = "single-data-file"
-	VERBOSE               = "verbose"
-	WITH_STATS            = "with-stats"
-	CREATE_DB             = "create-db"
-	ON_ERROR_CONTINUE     = "on-error-continue"
-	REDIRECT_DB           = "redirect-db"
-	TIMESTAMP             = "timestamp"
-	WITH_GLOBALS          = "with-globals"
+	ALL_DATABASES            = "all-databases"
+	BACKUP_DIR               = "backup-dir"
+	BACKUP_GROUP             = "backup-group"
+	COMPRESSION_LEVEL        = "compression-level"
+	CONSISTENT               = "consistent"
+	CONSOLIDATE              = "consolidate"
+	COPY_RETRIES             = "copy-retries"
+	DATA_ONLY                = "data-only"
+	DBNAME                   = "dbname"
+	DEBUG                    = "debug"
+	DIFF_TIMESTAMP           = "diff-timestamp"
+	DIFF_TO_TIMESTAMP        = "diff-to-timestamp"
+	DIFFERENTIAL             = "differential"
+	EXCLUDE_RELATION         = "exclude-table"
+	EXCLUDE_RELATION_FILE    = "exclude-table-file"
+	EXCLUDE_RELATION_PATTERN = "exclude-table-pattern"
+	EXCLUDE_SCHEMA           = "exclude-schema"
+	EXCLUDE_SCHEMA_PATTERN   = "exclude-schema-pattern"
+	FROM_TIMESTAMP           = "from-timestamp"
+	INCLUDE_DATABASE         = "include-database"
+	INCLUDE_RELATION         = "include-table"
+	INCLUDE_RELATION_FILE    = "include-table-file"
+	INCLUDE_RELATION_PATTERN = "include-table-pattern"
+	INCLUDE_SCHEMA           = "include-schema"
+	INCLUDE_SCHEMA_PATTERN   = "include-schema-pattern"
+	INCREMENTAL              = "incremental"
+	JOBS                     = "jobs"
+	LEAF_PARTITION_DATA      = "leaf-partition-data"
+	LIST_ONLY                = "list-only"
+	LOCK_WAIT_TIMEOUT        = "lock-wait-timeout"
+	MASKING_RULES_FILE       = "masking-rules-file"
+	METADATA_FORMAT          = "metadata-format"
+	METADATA_ONLY            = "metadata-only"
+	NO_COMPRESSION           = "no-compression"
+	PATTERN_SYNTAX           = "pattern-syntax"
+	PLUGIN_CONFIG            = "plugin-config"
+	PRESERVE_TABLE_ORDER     = "preserve-table-order"
+	QUIET                    = "quiet"
+	RESUME                   = "resume"
+	SINGLE_DATA_FILE         = "single-data-file"
+	TABLE_PREDICATE_FILE     = "table-predicate-file"
+	TRACK_HEAP_CHANGES       = "track-heap-changes"
+	VERBOSE                  = "verbose"
+	WITH_CATALOG_MODEL       = "with-catalog-model"
+	WITH_FINGERPRINTS        = "with-fingerprints"
+	WITH_STATS               = "with-stats"
+	WITHOUT_GLOBALS          = "without-globals"
+	CREATE_DB                = "create-db"
+	LIST_RESTORE_POINTS      = "list-restore-points"
+	NO_REFRESH_MATVIEWS      = "no-refresh-materialized-views"
+	NO_TABLESPACES           = "no-tablespaces"
+	ON_ERROR_CONTINUE        = "on-error-continue"
+	REDIRECT_DB              = "redirect-db"
+	TABLESPACE_LOCATION      = "tablespace-location"
+	TABLESPACE_MAP           = "tablespace-map"
+	TIMESTAMP                = "timestamp"
+	VERIFY_DATA              = "verify-data"
+	WITH_GLOBALS             = "with-globals"
 )
 
 /*
@@ -60,10 +93,42 @@ func CheckExclusiveFlags(flags *pflag.FlagSet, flagNames ...string) {
 	}
 }
 
+/*
+ * Include and exclude filters may be combined, as described in filter.go, but
+ * only one kind of include filter may be used, as a backup of specific tables
+ * does not contain the other objects in their schemas, and each filter may
+ * only be passed in one form.
+ */
+func CheckFilterFlagCombinations(flags *pflag.FlagSet) {
+	CheckExclusiveFlags(flags, INCLUDE_SCHEMA, INCLUDE_SCHEMA_PATTERN, INCLUDE_RELATION, INCLUDE_RELATION_FILE, INCLUDE_RELATION_PATTERN)
+	CheckExclusiveFlags(flags, EXCLUDE_SCHEMA, EXCLUDE_SCHEMA_PATTERN)
+	CheckExclusiveFlags(flags, EXCLUDE_RELATION, EXCLUDE_RELATION_FILE, EXCLUDE_RELATION_PATTERN)
+}
+
 /*
  * Functions for validating flag values
  */
 
+/*
+ * Parse a list of flag values in the form "key:value" into a map.  Only the
+ * first colon is treated as a separator, so values (e.g. filesystem paths)
+ * may themselves contain colons.
+ */
+func ParseColonSeparatedFlagValues(flagName string, values []string) (map[string]string, error) {
+	valueMap := make(map[string]string, len(values))
+	for _, value := range values {
+		pair := strings.SplitN(value, ":", 2)
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			return nil, errors.Errorf("Invalid value %s for --%s.  Values must be in the format key:value.", value, flagName)
+		}
+		if _, ok := valueMap[pair[0]]; ok {
+			return nil, errors.Errorf("%s is specified more than once for --%s", pair[0], flagName)
+		}
+		valueMap[pair[0]] = pair[1]
+	}
+	return valueMap, nil
+}
+
 /*
  * Convert arguments that contain a single dash to double dashes for backward
  * compatibility.
@@ -78,6 +143,19 @@ func HandleSingleDashes(args []string) []string {
 	return newArgs
 }
 
+/*
+ * String slice flag values are parsed as CSV, so a value containing a comma or
+ * a double quote must be quoted as a CSV field to be parsed back into a single
+ * value when it is passed to the flag again.
+ */
+func QuoteCSVField(value string) string {
+	buffer := bytes.NewBuffer(nil)
+	writer := csv.NewWriter(buffer)
+	_ = writer.Write([]string{value})
+	writer.Flush()
+	return strings.TrimSuffix(buffer.String(), "\n")
+}
+
 func MustGetFlagString(cmdFlags *pflag.FlagSet, flagName string) string {
 	value, err := cmdFlags.GetString(flagName)
 	gplog.FatalOnError(err)
@@ -101,3 +179,9 @@ func MustGetFlagStringSlice(cmdFlags *pflag.FlagSet, flagName string) []string {
 	gplog.FatalOnError(err)
 	return value
 }
+
+func MustGetFlagStringArray(cmdFlags *pflag.FlagSet, flagName string) []string {
+	value, err := cmdFlags.GetStringArray(flagName)
+	gplog.FatalOnError(err)
+	return value
+}
@@ -39,6 +39,26 @@ var _ = Describe("utils/flag tests", func() {
 				utils.CheckExclusiveFlags(flagSet, "stringFlag", "boolFlag")
 			})
 		})
+		Context("ParseColonSeparatedFlagValues", func() {
+			It("parses values into a map", func() {
+				result, err := utils.ParseColonSeparatedFlagValues("someFlag", []string{"foo:bar", "baz:/some/path"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(map[string]string{"foo": "bar", "baz": "/some/path"}))
+			})
+			It("only splits values on the first colon", func() {
+				result, err := utils.ParseColonSeparatedFlagValues("someFlag", []string{"foo:/some:path"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(map[string]string{"foo": "/some:path"}))
+			})
+			It("returns an error if a value has no colon", func() {
+				_, err := utils.ParseColonSeparatedFlagValues("someFlag", []string{"foo"})
+				Expect(err).To(MatchError("Invalid value foo for --someFlag.  Values must be in the format key:value."))
+			})
+			It("returns an error if a key is specified more than once", func() {
+				_, err := utils.ParseColonSeparatedFlagValues("someFlag", []string{"foo:bar", "foo:baz"})
+				Expect(err).To(MatchError("foo is specified more than once for --someFlag"))
+			})
+		})
 		Context("HandleSingleDashes", func() {
 			It("replaces single dash at beginning of command", func() {
 				result := utils.HandleSingleDashes([]string{"-some_flag", "some_argument"})
@@ -0,0 +1,77 @@
+package utils
+
+/*
+ * This file contains functions related to running gpbackup or gprestore once
+ * for each database in a backup group.
+ */
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+/*
+ * Returns the arguments needed to pass the flags set on the command line, other
+ * than skipFlags, on to another run of the same utility.  Slice and array
+ * flags are passed once per value.  Slice flag values are parsed as CSV, so
+ * each slice value is written as a CSV field, quoted if it contains a comma or
+ * a quote, to be parsed back into the same value; array flag values are not
+ * parsed, so they are passed as they are.
+ */
+func GetChangedFlagArgs(flags *pflag.FlagSet, skipFlags ...string) []string {
+	flagSet := NewExcludeSet(skipFlags)
+	args := make([]string, 0)
+	flags.Visit(func(flag *pflag.Flag) {
+		if !flagSet.MatchesFilter(flag.Name) {
+			return
+		}
+		switch flag.Value.Type() {
+		case "stringSlice":
+			values, _ := flags.GetStringSlice(flag.Name)
+			for _, value := range values {
+				args = append(args, fmt.Sprintf("--%s=%s", flag.Name, QuoteCSVField(value)))
+			}
+			return
+		case "stringArray":
+			values, _ := flags.GetStringArray(flag.Name)
+			for _, value := range values {
+				args = append(args, fmt.Sprintf("--%s=%s", flag.Name, value))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+	return args
+}
+
+/*
+ * Backups are identified by a timestamp with a resolution of one second, so
+ * a run that finishes and the next run that starts within the same second
+ * would be given the same timestamp.  This waits until the current timestamp
+ * is later than the given one.
+ */
+func WaitForNextTimestamp(timestamp string) {
+	for CurrentTimestamp() <= timestamp {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+/*
+ * Each database is backed up or restored by its own run of the utility, so
+ * that every database gets its own connections, lock file, report, and error
+ * handling, exactly as if the utility had been run once per database.
+ */
+func RunUtilityWithArgs(args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
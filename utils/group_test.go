@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/pflag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/group tests", func() {
+	Describe("GetChangedFlagArgs", func() {
+		var flagSet *pflag.FlagSet
+		BeforeEach(func() {
+			flagSet = pflag.NewFlagSet("testFlags", pflag.ContinueOnError)
+			_ = flagSet.String("stringFlag", "", "This is a sample string flag.")
+			_ = flagSet.Bool("boolFlag", false, "This is a sample bool flag.")
+			_ = flagSet.Int("intFlag", 0, "This is a sample int flag.")
+			_ = flagSet.StringSlice("sliceFlag", []string{}, "This is a sample string slice flag.")
+			_ = flagSet.StringArray("arrayFlag", []string{}, "This is a sample string array flag.")
+		})
+		It("returns only the flags that were set", func() {
+			Expect(flagSet.Parse([]string{"--stringFlag", "foo", "--intFlag", "4"})).To(Succeed())
+			Expect(utils.GetChangedFlagArgs(flagSet)).To(Equal([]string{"--intFlag=4", "--stringFlag=foo"}))
+		})
+		It("passes each value of a slice flag separately", func() {
+			Expect(flagSet.Parse([]string{"--sliceFlag", "a,b", "--sliceFlag", "c", "--boolFlag"})).To(Succeed())
+			Expect(utils.GetChangedFlagArgs(flagSet)).To(Equal([]string{"--boolFlag=true", "--sliceFlag=a", "--sliceFlag=b", "--sliceFlag=c"}))
+		})
+		It("quotes slice flag values containing commas or quotes so that they are parsed back into the same values", func() {
+			Expect(flagSet.Parse([]string{"--sliceFlag", `"a,b",c`, "--sliceFlag", `"d""e"`})).To(Succeed())
+			args := utils.GetChangedFlagArgs(flagSet)
+			Expect(args).To(Equal([]string{`--sliceFlag="a,b"`, "--sliceFlag=c", `--sliceFlag="d""e"`}))
+
+			newFlagSet := pflag.NewFlagSet("newFlags", pflag.ContinueOnError)
+			_ = newFlagSet.StringSlice("sliceFlag", []string{}, "This is a sample string slice flag.")
+			Expect(newFlagSet.Parse(args)).To(Succeed())
+			Expect(newFlagSet.GetStringSlice("sliceFlag")).To(Equal([]string{"a,b", "c", `d"e`}))
+		})
+		It("passes each value of an array flag unchanged", func() {
+			Expect(flagSet.Parse([]string{"--arrayFlag", "a,b", "--arrayFlag", `c"d`})).To(Succeed())
+			Expect(utils.GetChangedFlagArgs(flagSet)).To(Equal([]string{"--arrayFlag=a,b", `--arrayFlag=c"d`}))
+		})
+		It("skips the specified flags", func() {
+			Expect(flagSet.Parse([]string{"--stringFlag", "foo", "--boolFlag", "--sliceFlag", "a"})).To(Succeed())
+			Expect(utils.GetChangedFlagArgs(flagSet, "sliceFlag", "boolFlag")).To(Equal([]string{"--stringFlag=foo"}))
+		})
+	})
+	Describe("WaitForNextTimestamp", func() {
+		It("returns once the current timestamp is later than the given timestamp", func() {
+			callCount := 0
+			operating.System.Now = func() time.Time {
+				callCount++
+				return time.Date(2017, time.January, 1, 1, 1, 1+callCount/3, 0, time.Local)
+			}
+			defer func() { operating.System = operating.InitializeSystemFunctions() }()
+
+			utils.WaitForNextTimestamp("20170101010101")
+
+			Expect(callCount).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,133 @@
+package utils
+
+/*
+ * This file contains functions for expanding the wildcard and regular
+ * expression patterns passed to the --*-pattern filter flags into lists of
+ * object names.
+ */
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	PATTERN_SYNTAX_GLOB  = "glob"
+	PATTERN_SYNTAX_REGEX = "regex"
+)
+
+func ValidatePatterns(patterns []string, syntax string) error {
+	if syntax != PATTERN_SYNTAX_GLOB && syntax != PATTERN_SYNTAX_REGEX {
+		return errors.Errorf("Pattern syntax %s is invalid.  Valid values are %s and %s.", syntax, PATTERN_SYNTAX_GLOB, PATTERN_SYNTAX_REGEX)
+	}
+	for _, pattern := range patterns {
+		if _, err := MatchesPattern("", pattern, syntax); err != nil {
+			return errors.Errorf("Pattern %s is not a valid %s pattern: %s", pattern, syntax, err.Error())
+		}
+	}
+	return nil
+}
+
+func ValidatePatternFlags(flags *pflag.FlagSet) error {
+	patterns := make([]string, 0)
+	for _, flagName := range []string{INCLUDE_SCHEMA_PATTERN, EXCLUDE_SCHEMA_PATTERN, INCLUDE_RELATION_PATTERN, EXCLUDE_RELATION_PATTERN} {
+		flagPatterns, err := flags.GetStringSlice(flagName)
+		if err != nil {
+			return err
+		}
+		patterns = append(patterns, flagPatterns...)
+	}
+	syntax, err := flags.GetString(PATTERN_SYNTAX)
+	if err != nil {
+		return err
+	}
+	return ValidatePatterns(patterns, syntax)
+}
+
+/*
+ * Regular expressions are anchored at both ends, so that a pattern must match
+ * the whole name as a glob does rather than any substring of it.
+ */
+func MatchesPattern(name string, pattern string, syntax string) (bool, error) {
+	if syntax == PATTERN_SYNTAX_REGEX {
+		regex, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+		if err != nil {
+			return false, err
+		}
+		return regex.MatchString(name), nil
+	}
+	return path.Match(pattern, name)
+}
+
+/*
+ * Returns the names matching any of the patterns, in the order in which they
+ * were passed in, and the patterns that did not match any name so that the
+ * caller can decide whether that is an error.
+ */
+func ExpandPatterns(names []string, patterns []string, syntax string) ([]string, []string, error) {
+	matchedNames := make([]string, 0)
+	unmatchedPatterns := make([]string, 0)
+	patternMatched := make([]bool, len(patterns))
+	for _, name := range names {
+		nameMatched := false
+		for i, pattern := range patterns {
+			matches, err := MatchesPattern(name, pattern, syntax)
+			if err != nil {
+				return nil, nil, err
+			}
+			if matches {
+				patternMatched[i] = true
+				nameMatched = true
+			}
+		}
+		if nameMatched {
+			matchedNames = append(matchedNames, name)
+		}
+	}
+	for i, pattern := range patterns {
+		if !patternMatched[i] {
+			unmatchedPatterns = append(unmatchedPatterns, pattern)
+		}
+	}
+	return matchedNames, unmatchedPatterns, nil
+}
+
+/*
+ * Sets filterFlag to the names matching the patterns passed to patternFlag,
+ * so that the rest of the filtering code only has to handle exact names.  An
+ * include pattern that matches nothing is an error, as the filter would
+ * otherwise be dropped and every object included, but an exclude pattern that
+ * matches nothing only warrants a warning, as with --exclude-table.
+ */
+func ExpandPatternFlag(flags *pflag.FlagSet, patternFlag string, filterFlag string, names []string, objectType string, isExclude bool) {
+	patterns, err := flags.GetStringSlice(patternFlag)
+	gplog.FatalOnError(err)
+	if len(patterns) == 0 {
+		return
+	}
+	syntax, err := flags.GetString(PATTERN_SYNTAX)
+	gplog.FatalOnError(err)
+	matchedNames, unmatchedPatterns, err := ExpandPatterns(names, patterns, syntax)
+	gplog.FatalOnError(err)
+	for _, pattern := range unmatchedPatterns {
+		if isExclude {
+			gplog.Warn("No %s match excluded pattern %s", objectType, pattern)
+		} else {
+			gplog.Fatal(errors.Errorf("No %s match pattern %s", objectType, pattern), "")
+		}
+	}
+	if len(matchedNames) == 0 {
+		return
+	}
+	gplog.Verbose("Expanded --%s to %d %s: %s", patternFlag, len(matchedNames), objectType, strings.Join(matchedNames, ", "))
+	for _, name := range matchedNames {
+		err = flags.Set(filterFlag, QuoteCSVField(name))
+		gplog.FatalOnError(err)
+	}
+}
@@ -0,0 +1,106 @@
+package utils_test
+
+import (
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/pflag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("utils/pattern tests", func() {
+	Describe("ValidatePatterns", func() {
+		It("accepts valid glob and regex patterns", func() {
+			Expect(utils.ValidatePatterns([]string{"public.staging_*", "tmp_[0-9]?"}, utils.PATTERN_SYNTAX_GLOB)).To(Succeed())
+			Expect(utils.ValidatePatterns([]string{`public\.staging_.*`, "tmp_(a|b)"}, utils.PATTERN_SYNTAX_REGEX)).To(Succeed())
+		})
+		It("rejects an invalid glob pattern", func() {
+			err := utils.ValidatePatterns([]string{"public.[foo"}, utils.PATTERN_SYNTAX_GLOB)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Pattern public.[foo is not a valid glob pattern"))
+		})
+		It("rejects an invalid regex pattern", func() {
+			err := utils.ValidatePatterns([]string{"tmp_(a"}, utils.PATTERN_SYNTAX_REGEX)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("Pattern tmp_(a is not a valid regex pattern"))
+		})
+		It("rejects an unknown pattern syntax", func() {
+			err := utils.ValidatePatterns([]string{}, "sql")
+			Expect(err).To(MatchError("Pattern syntax sql is invalid.  Valid values are glob and regex."))
+		})
+	})
+	Describe("MatchesPattern", func() {
+		It("matches glob patterns against the whole name", func() {
+			Expect(utils.MatchesPattern("public.staging_foo", "public.staging_*", utils.PATTERN_SYNTAX_GLOB)).To(BeTrue())
+			Expect(utils.MatchesPattern("public.foo_staging_foo", "staging_*", utils.PATTERN_SYNTAX_GLOB)).To(BeFalse())
+		})
+		It("anchors regex patterns so that they match the whole name", func() {
+			Expect(utils.MatchesPattern("tmp_1", "tmp_[0-9]+", utils.PATTERN_SYNTAX_REGEX)).To(BeTrue())
+			Expect(utils.MatchesPattern("old_tmp_1", "tmp_[0-9]+", utils.PATTERN_SYNTAX_REGEX)).To(BeFalse())
+			Expect(utils.MatchesPattern("tmp_1_old", "tmp_[0-9]+", utils.PATTERN_SYNTAX_REGEX)).To(BeFalse())
+			Expect(utils.MatchesPattern("b", "a|b", utils.PATTERN_SYNTAX_REGEX)).To(BeTrue())
+		})
+	})
+	Describe("ExpandPatterns", func() {
+		names := []string{"public.foo", "public.staging_a", "public.staging_b", "tmp_1.bar"}
+		It("returns the names matching any pattern in their original order", func() {
+			matched, unmatched, err := utils.ExpandPatterns(names, []string{"tmp_*.*", "public.staging_*"}, utils.PATTERN_SYNTAX_GLOB)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matched).To(Equal([]string{"public.staging_a", "public.staging_b", "tmp_1.bar"}))
+			Expect(unmatched).To(BeEmpty())
+		})
+		It("returns the patterns that match no names", func() {
+			matched, unmatched, err := utils.ExpandPatterns(names, []string{`public\.foo`, "nothing.*"}, utils.PATTERN_SYNTAX_REGEX)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matched).To(Equal([]string{"public.foo"}))
+			Expect(unmatched).To(Equal([]string{"nothing.*"}))
+		})
+	})
+	Describe("ExpandPatternFlag", func() {
+		var flagSet *pflag.FlagSet
+		names := []string{"public.foo", "public.staging_a", "public.staging_b"}
+		BeforeEach(func() {
+			flagSet = pflag.NewFlagSet("testFlags", pflag.ContinueOnError)
+			flagSet.StringSlice(utils.INCLUDE_RELATION, []string{}, "")
+			flagSet.StringSlice(utils.INCLUDE_RELATION_PATTERN, []string{}, "")
+			flagSet.StringSlice(utils.EXCLUDE_RELATION, []string{}, "")
+			flagSet.StringSlice(utils.EXCLUDE_RELATION_PATTERN, []string{}, "")
+			flagSet.String(utils.PATTERN_SYNTAX, utils.PATTERN_SYNTAX_GLOB, "")
+		})
+		It("sets the filter flag to the matching names", func() {
+			Expect(flagSet.Parse([]string{"--include-table-pattern", "public.staging_*"})).To(Succeed())
+
+			utils.ExpandPatternFlag(flagSet, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, names, "tables", false)
+
+			Expect(flagSet.GetStringSlice(utils.INCLUDE_RELATION)).To(Equal([]string{"public.staging_a", "public.staging_b"}))
+		})
+		It("sets the filter flag to matching names containing commas or double quotes", func() {
+			Expect(flagSet.Parse([]string{"--include-table-pattern", "public.*"})).To(Succeed())
+
+			utils.ExpandPatternFlag(flagSet, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, []string{`public."foo,bar"`, `public."foo""bar"`}, "tables", false)
+
+			Expect(flagSet.GetStringSlice(utils.INCLUDE_RELATION)).To(Equal([]string{`public."foo,bar"`, `public."foo""bar"`}))
+		})
+		It("does not set the filter flag if the pattern flag is not set", func() {
+			utils.ExpandPatternFlag(flagSet, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, names, "tables", false)
+
+			Expect(flagSet.Changed(utils.INCLUDE_RELATION)).To(BeFalse())
+		})
+		It("panics if an include pattern matches nothing", func() {
+			Expect(flagSet.Parse([]string{"--include-table-pattern", "public.staging_*", "--include-table-pattern", "public.missing_*"})).To(Succeed())
+
+			defer testhelper.ShouldPanicWithMessage("No tables match pattern public.missing_*")
+			utils.ExpandPatternFlag(flagSet, utils.INCLUDE_RELATION_PATTERN, utils.INCLUDE_RELATION, names, "tables", false)
+		})
+		It("logs a warning if an exclude pattern matches nothing", func() {
+			Expect(flagSet.Parse([]string{"--exclude-table-pattern", "public.missing_*"})).To(Succeed())
+
+			utils.ExpandPatternFlag(flagSet, utils.EXCLUDE_RELATION_PATTERN, utils.EXCLUDE_RELATION, names, "tables", true)
+
+			Expect(flagSet.Changed(utils.EXCLUDE_RELATION)).To(BeFalse())
+			Expect(logfile).To(gbytes.Say(`No tables match excluded pattern public.missing_\*`))
+		})
+	})
+})
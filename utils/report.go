@@ -1,12 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -18,6 +20,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_history"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
@@ -27,8 +30,13 @@ import (
  * file that we will want to read in for a restore.
  */
 type Report struct {
-	BackupParamsString string
-	DatabaseSize       string
+	BackupParamsString          string
+	DatabaseSize                string
+	DataConsistency             string
+	IncrementalMetadataDuration time.Duration
+	LockWaits                   []string
+	TableDataRetries            []string
+	TablePredicates             []string
 	backup_history.BackupConfig
 }
 
@@ -104,9 +112,13 @@ func (report *Report) constructIncrementalSection() string {
 	for _, restorePlanEntry := range report.RestorePlan {
 		backupTimestamps = append(backupTimestamps, restorePlanEntry.Timestamp)
 	}
-	return fmt.Sprintf(`Incremental: True
+	differentialStr := ""
+	if report.GetBackupType() == backup_history.DIFFERENTIAL_BACKUP {
+		differentialStr = "\nDifferential: True"
+	}
+	return fmt.Sprintf(`Incremental: True%s
 Incremental Backup Set:
-%s`, strings.Join(backupTimestamps, "\n"))
+%s`, differentialStr, strings.Join(backupTimestamps, "\n"))
 }
 
 func (report *Report) WriteBackupReportFile(reportFilename string, timestamp string, objectCounts map[string]int, errMsg string) {
@@ -123,11 +135,11 @@ gpbackup Version: %s
 
 Database Name: %s
 Command Line: %s
-%s
+%s%s
 
 Start Time: %s
 End Time: %s
-Duration: %s
+Duration: %s%s
 
 Backup Status: %s
 %s`
@@ -142,22 +154,42 @@ Backup Status: %s
 	if report.DatabaseSize != "" {
 		dbSizeStr = fmt.Sprintf("\nDatabase Size: %s", report.DatabaseSize)
 	}
+	dataConsistencyStr := ""
+	if report.DataConsistency != "" {
+		dataConsistencyStr = fmt.Sprintf("\nData Consistency: %s", report.DataConsistency)
+	}
+	incrementalMetadataStr := ""
+	if report.IncrementalMetadataDuration > 0 {
+		incrementalMetadataStr = fmt.Sprintf("\nIncremental Metadata Duration: %s", reformatDuration(report.IncrementalMetadataDuration))
+	}
 
 	_, err = fmt.Fprintf(reportFile, reportFileTemplate,
 		timestamp, report.DatabaseVersion, report.BackupVersion,
-		report.DatabaseName, gpbackupCommandLine, report.BackupParamsString,
-		start, end, duration,
+		report.DatabaseName, gpbackupCommandLine, report.BackupParamsString, dataConsistencyStr,
+		start, end, duration, incrementalMetadataStr,
 		backupStatus, dbSizeStr)
 	if err != nil {
 		gplog.Error("Unable to write backup report file %s", reportFilename)
 		return
 	}
 
+	if len(report.LockWaits) > 0 {
+		PrintLockWaits(reportFile, report.LockWaits)
+	}
+	if len(report.TableDataRetries) > 0 {
+		PrintTableDataRetries(reportFile, report.TableDataRetries)
+	}
+	if len(report.TablePredicates) > 0 {
+		PrintTablePredicates(reportFile, report.TablePredicates)
+	}
+	if len(report.MaskingRules) > 0 {
+		PrintMaskingRules(reportFile, report.MaskingRules)
+	}
 	PrintObjectCounts(reportFile, objectCounts)
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
-func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, errMsg string) {
+func WriteRestoreReportFile(reportFilename string, backupTimestamp string, startTimestamp string, connectionPool *dbconn.DBConn, restoreVersion string, errMsg string, restoreErrors *RestoreErrors, dataVerification *DataVerificationResult, tablePredicates []string) {
 	reportFile, err := iohelper.OpenFileForWriting(reportFilename)
 	if err != nil {
 		gplog.Error("Unable to open restore report file %s", reportFilename)
@@ -197,6 +229,16 @@ Restore Status: %s`
 		return
 	}
 
+	if restoreErrors != nil && len(restoreErrors.Records) > 0 {
+		PrintErrorCountsBySection(reportFile, restoreErrors.GetErrorCountsBySection())
+	}
+	if len(tablePredicates) > 0 {
+		MustPrintf(reportFile, "\n")
+		PrintTablePredicates(reportFile, tablePredicates)
+	}
+	if dataVerification != nil {
+		PrintDataVerificationResult(reportFile, dataVerification)
+	}
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
@@ -218,6 +260,38 @@ func reformatDuration(duration time.Duration) string {
 	return fmt.Sprintf("%d:%02d:%02d", hour, min, sec)
 }
 
+func PrintLockWaits(reportFile io.WriteCloser, lockWaits []string) {
+	lockWaitStr := "\n\nSessions Blocking Table Locks:\n"
+	for _, lockWait := range lockWaits {
+		lockWaitStr += fmt.Sprintf("%s\n", lockWait)
+	}
+	MustPrintf(reportFile, lockWaitStr)
+}
+
+func PrintTableDataRetries(reportFile io.WriteCloser, tableDataRetries []string) {
+	retryStr := "\nTables With Data Backup Retries:\n"
+	for _, tableDataRetry := range tableDataRetries {
+		retryStr += fmt.Sprintf("%s\n", tableDataRetry)
+	}
+	MustPrintf(reportFile, retryStr)
+}
+
+func PrintTablePredicates(reportFile io.WriteCloser, tablePredicates []string) {
+	predicateStr := "\nTables With Partial Data:\n"
+	for _, tablePredicate := range tablePredicates {
+		predicateStr += fmt.Sprintf("%s\n", tablePredicate)
+	}
+	MustPrintf(reportFile, predicateStr)
+}
+
+func PrintMaskingRules(reportFile io.WriteCloser, maskingRules []string) {
+	maskingStr := "\nMasked Columns:\n"
+	for _, maskingRule := range maskingRules {
+		maskingStr += fmt.Sprintf("%s\n", maskingRule)
+	}
+	MustPrintf(reportFile, maskingStr)
+}
+
 func PrintObjectCounts(reportFile io.WriteCloser, objectCounts map[string]int) {
 	objectStr := "\nCount of Database Objects in Backup:\n"
 	objectSlice := make([]string, 0)
@@ -232,6 +306,118 @@ func PrintObjectCounts(reportFile io.WriteCloser, objectCounts map[string]int) {
 	MustPrintf(reportFile, objectStr)
 }
 
+/*
+ * Sections are printed in the order in which they are restored, rather than
+ * alphabetically, so that the table reads in the same order as the log file.
+ */
+var restoreSections = []string{"global", "predata", "data", "postdata", "statistics"}
+
+func PrintErrorCountsBySection(reportFile io.WriteCloser, errorCounts map[string]int) {
+	errorStr := "\n\nCount of Errors by Section:\n"
+	for _, section := range restoreSections {
+		errorStr += fmt.Sprintf("%-29s%d\n", section, errorCounts[section])
+	}
+	MustPrintf(reportFile, errorStr)
+}
+
+/*
+ * A RestoreErrorRecord holds the details of a single statement or table load
+ * that failed during a restore with --on-error-continue.  For table loads, the
+ * Statement field holds the target of the COPY command.  Statements that were
+ * not executed because an object they depend on failed to restore are also
+ * recorded, with Skipped set, but are not counted as errors.
+ */
+type RestoreErrorRecord struct {
+	Section      string `json:"section"`
+	ObjectType   string `json:"object_type"`
+	Schema       string `json:"schema"`
+	Name         string `json:"name"`
+	Statement    string `json:"statement"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	Skipped      bool   `json:"skipped,omitempty"`
+}
+
+const SKIPPED_STATEMENT_MESSAGE = "skipped due to failed dependency"
+
+type RestoreErrors struct {
+	Records []RestoreErrorRecord
+	mutex   sync.Mutex
+}
+
+func NewRestoreErrors() *RestoreErrors {
+	return &RestoreErrors{Records: make([]RestoreErrorRecord, 0)}
+}
+
+func NewRestoreErrorRecord(section string, objectType string, schema string, name string, statement string, err error) RestoreErrorRecord {
+	record := RestoreErrorRecord{
+		Section:      section,
+		ObjectType:   objectType,
+		Schema:       schema,
+		Name:         name,
+		Statement:    strings.TrimSpace(statement),
+		ErrorMessage: err.Error(),
+	}
+	record.ErrorCode = GetSQLState(err)
+	if pqErr, ok := errors.Cause(err).(*pq.Error); ok {
+		record.ErrorMessage = pqErr.Message
+	}
+	return record
+}
+
+// This function is called concurrently by the goroutines restoring metadata and data
+func (restoreErrors *RestoreErrors) AddRecord(record RestoreErrorRecord) {
+	restoreErrors.mutex.Lock()
+	defer restoreErrors.mutex.Unlock()
+	restoreErrors.Records = append(restoreErrors.Records, record)
+}
+
+func (restoreErrors *RestoreErrors) AddStatementError(section string, statement StatementWithType, err error) {
+	restoreErrors.AddRecord(NewRestoreErrorRecord(section, statement.ObjectType, statement.Schema, statement.Name, statement.Statement, err))
+}
+
+func (restoreErrors *RestoreErrors) AddSkippedStatement(section string, statement StatementWithType) {
+	restoreErrors.AddRecord(RestoreErrorRecord{
+		Section:      section,
+		ObjectType:   statement.ObjectType,
+		Schema:       statement.Schema,
+		Name:         statement.Name,
+		Statement:    strings.TrimSpace(statement.Statement),
+		ErrorMessage: SKIPPED_STATEMENT_MESSAGE,
+		Skipped:      true,
+	})
+}
+
+func (restoreErrors *RestoreErrors) GetErrorCountsBySection() map[string]int {
+	errorCounts := make(map[string]int, 0)
+	for _, record := range restoreErrors.Records {
+		if !record.Skipped {
+			errorCounts[record.Section]++
+		}
+	}
+	return errorCounts
+}
+
+// The error file has one JSON object per line, so it can be processed incrementally
+func (restoreErrors *RestoreErrors) WriteErrorFile(errorFilename string) error {
+	errorFile, err := iohelper.OpenFileForWriting(errorFilename)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(errorFile)
+	for _, record := range restoreErrors.Records {
+		err = encoder.Encode(record)
+		if err != nil {
+			return err
+		}
+	}
+	err = errorFile.Close()
+	if err != nil {
+		return err
+	}
+	return operating.System.Chmod(errorFilename, 0444)
+}
+
 /*
  * This function will not error out if the user has gprestore X.Y.Z
  * and gpbackup X.Y.Z+dev, when technically the uncommitted code changes
@@ -253,11 +439,20 @@ func EnsureBackupVersionCompatibility(backupVersion string, restoreVersion strin
 	}
 }
 
-func EnsureDatabaseVersionCompatibility(backupGPDBVersion string, restoreGPDBVersion dbconn.GPDBVersion) {
+/*
+ * The database version recorded in a backup config includes the build
+ * details, so only the three-digit version in it is parsed.
+ */
+func ParseBackupDatabaseVersion(backupGPDBVersion string) dbconn.GPDBVersion {
 	pattern := regexp.MustCompile(`\d+\.\d+\.\d+`)
 	threeDigitVersion := pattern.FindStringSubmatch(backupGPDBVersion)[0]
 	backupGPDBSemVer, err := semver.Make(threeDigitVersion)
 	gplog.FatalOnError(err)
+	return dbconn.GPDBVersion{VersionString: backupGPDBVersion, SemVer: backupGPDBSemVer}
+}
+
+func EnsureDatabaseVersionCompatibility(backupGPDBVersion string, restoreGPDBVersion dbconn.GPDBVersion) {
+	backupGPDBSemVer := ParseBackupDatabaseVersion(backupGPDBVersion).SemVer
 	if backupGPDBSemVer.Major > restoreGPDBVersion.SemVer.Major {
 		gplog.Fatal(errors.Errorf("Cannot restore from GPDB version %s to %s due to catalog incompatibilities.", backupGPDBVersion, restoreGPDBVersion.VersionString), "")
 	}
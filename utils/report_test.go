@@ -19,6 +19,7 @@ import (
 	"github.com/greenplum-db/gpbackup/backup_history"
 	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/lib/pq"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
@@ -155,6 +156,65 @@ sequences                    1
 tables                       42
 types                        1000`))
 		})
+		It("writes a report with the duration of incremental metadata collection", func() {
+			backupReport.IncrementalMetadataDuration = 83 * time.Second
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Start Time: 2017-01-01 01:01:01
+End Time: 2017-01-01 05:04:03
+Duration: 4:03:02
+Incremental Metadata Duration: 0:01:23
+
+Backup Status: Success`))
+		})
+		It("writes a report with the data consistency level", func() {
+			backupReport.DataConsistency = "Synchronized snapshot across all connections"
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Data File Format: Single Data File Per Segment
+Data Consistency: Synchronized snapshot across all connections
+
+Start Time: 2017-01-01 01:01:01`))
+		})
+		It("writes a report with the sessions that blocked table locks", func() {
+			backupReport.LockWaits = []string{"AccessExclusiveLock on table public.foo held by pid 1234 (user testrole): TRUNCATE public.foo"}
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Database Size: 42 MB
+
+Sessions Blocking Table Locks:
+AccessExclusiveLock on table public.foo held by pid 1234 \(user testrole\): TRUNCATE public.foo
+
+Count of Database Objects in Backup:`))
+		})
+		It("writes a report with the tables whose data backup was retried", func() {
+			backupReport.LockWaits = []string{"AccessExclusiveLock on table public.foo held by pid 1234 (user testrole): TRUNCATE public.foo"}
+			backupReport.TableDataRetries = []string{"public.foo: 2 retries, succeeded", "public.bar: 3 retries, failed"}
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`AccessExclusiveLock on table public.foo held by pid 1234 \(user testrole\): TRUNCATE public.foo
+
+Tables With Data Backup Retries:
+public.foo: 2 retries, succeeded
+public.bar: 3 retries, failed
+
+Count of Database Objects in Backup:`))
+		})
+		It("writes a report with the tables whose data was backed up with a predicate", func() {
+			backupReport.TablePredicates = []string{"public.events: created_at > '2018-01-01'"}
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Database Size: 42 MB
+Tables With Partial Data:
+public.events: created_at > '2018-01-01'
+
+Count of Database Objects in Backup:`))
+		})
+		It("writes a report with the masking rules applied to the data", func() {
+			backupReport.MaskingRules = []string{"public.customers.email: hash", "public.customers.phone: keep-prefix:3"}
+			backupReport.WriteBackupReportFile("filename", timestamp, objectCounts, "")
+			Expect(buffer).To(gbytes.Say(`Database Size: 42 MB
+Masked Columns:
+public.customers.email: hash
+public.customers.phone: keep-prefix:3
+
+Count of Database Objects in Backup:`))
+		})
 	})
 	Describe("WriteRestoreReportFile", func() {
 		timestamp := "20170101010101"
@@ -184,7 +244,7 @@ types                        1000`))
 
 		It("writes a report for a failed restore", func() {
 			gplog.SetErrorCode(2)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "Cannot access /tmp/backups: Permission denied")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "Cannot access /tmp/backups: Permission denied", nil, nil, nil)
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -203,7 +263,7 @@ Restore Error: Cannot access /tmp/backups: Permission denied`))
 		})
 		It("writes a report for a successful restore", func() {
 			gplog.SetErrorCode(0)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "", nil, nil, nil)
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -221,7 +281,7 @@ Restore Status: Success`))
 		})
 		It("writes a report for a successful restore with errors", func() {
 			gplog.SetErrorCode(1)
-			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "")
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "", nil, nil, nil)
 			Expect(buffer).To(gbytes.Say(`Greenplum Database Restore Report
 
 Timestamp Key: 20170101010101
@@ -237,6 +297,136 @@ Duration: 4:03:01
 
 Restore Status: Success but non-fatal errors occurred. See log file .+ for details.`))
 		})
+		It("writes a count of errors for each section for a restore with errors", func() {
+			gplog.SetErrorCode(1)
+			restoreErrors := utils.NewRestoreErrors()
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata", ObjectType: "TABLE", Schema: "public", Name: "foo"})
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata", ObjectType: "VIEW", Schema: "public", Name: "bar"})
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "data", ObjectType: "TABLE", Schema: "public", Name: "baz"})
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "", restoreErrors, nil, nil)
+			Expect(buffer).To(gbytes.Say(`Restore Status: Success but non-fatal errors occurred. See log file .+ for details.
+
+Count of Errors by Section:
+global                       0
+predata                      2
+data                         1
+postdata                     0
+statistics                   0`))
+		})
+		It("writes the tables and segments that differ for a restore with data verification", func() {
+			gplog.SetErrorCode(1)
+			dataVerification := &utils.DataVerificationResult{
+				NumTablesVerified: 3,
+				Mismatches: []utils.FingerprintMismatch{
+					{Schema: "public", Name: "foo", ContentID: 0, Expected: utils.SegmentFingerprint{ContentID: 0, NumRows: 10, Checksum: "123"}, Actual: utils.SegmentFingerprint{ContentID: 0, NumRows: 9, Checksum: "456"}},
+					{Schema: "public", Name: "foo", ContentID: 2, Expected: utils.SegmentFingerprint{ContentID: 2, NumRows: 5, Checksum: "789"}, Actual: utils.SegmentFingerprint{ContentID: 2, NumRows: 5, Checksum: "788"}},
+				},
+			}
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "", nil, dataVerification, nil)
+			Expect(buffer).To(gbytes.Say(`Restore Status: Success but non-fatal errors occurred. See log file .+ for details.
+
+Data Verification: Failure
+Tables verified              3
+Tables with differences      1
+Tables not verified          0
+
+Differences by Table and Segment:
+public.foo segment 0: expected 10 rows \(checksum 123\), restored 9 rows \(checksum 456\)
+public.foo segment 2: expected 5 rows \(checksum 789\), restored 5 rows \(checksum 788\)`))
+		})
+		It("writes the tables that were restored from a backup of a subset of their rows", func() {
+			utils.WriteRestoreReportFile("filename", timestamp, restoreStartTime, connectionPool, restoreVersion, "", nil, nil, []string{"public.events: created_at > '2018-01-01'"})
+			Expect(buffer).To(gbytes.Say(`Restore Status: Success
+
+Tables With Partial Data:
+public.events: created_at > '2018-01-01'
+`))
+		})
+	})
+	Describe("RestoreErrors", func() {
+		It("stores the code and message of a database error", func() {
+			err := &pq.Error{Code: "42P07", Message: `relation "foo" already exists`}
+			statement := utils.StatementWithType{Schema: "public", Name: "foo", ObjectType: "TABLE", Statement: "\n\nCREATE TABLE public.foo (i int);\n"}
+			restoreErrors := utils.NewRestoreErrors()
+
+			restoreErrors.AddStatementError("predata", statement, err)
+
+			Expect(restoreErrors.Records).To(Equal([]utils.RestoreErrorRecord{{
+				Section:      "predata",
+				ObjectType:   "TABLE",
+				Schema:       "public",
+				Name:         "foo",
+				Statement:    "CREATE TABLE public.foo (i int);",
+				ErrorCode:    "42P07",
+				ErrorMessage: `relation "foo" already exists`,
+			}}))
+		})
+		It("stores the code and message of a wrapped database error", func() {
+			err := errors.Wrap(&pq.Error{Code: "22P04", Message: "missing data for column"}, "Error loading data into table public.foo")
+
+			record := utils.NewRestoreErrorRecord("data", "TABLE", "public", "foo", "public.foo(i)", err)
+
+			Expect(record.ErrorCode).To(Equal("22P04"))
+			Expect(record.ErrorMessage).To(Equal("missing data for column"))
+		})
+		It("stores the code of a database error returned by pgx", func() {
+			err := errors.New(`ERROR: relation "foo" already exists (SQLSTATE 42P07)`)
+
+			record := utils.NewRestoreErrorRecord("predata", "TABLE", "public", "foo", "CREATE TABLE public.foo (i int);", err)
+
+			Expect(record.ErrorCode).To(Equal("42P07"))
+			Expect(record.ErrorMessage).To(Equal(`ERROR: relation "foo" already exists (SQLSTATE 42P07)`))
+		})
+		It("stores a statement skipped due to a failed dependency without counting it as an error", func() {
+			statement := utils.StatementWithType{Schema: "public", Name: "bar", ObjectType: "VIEW", Statement: "\n\nCREATE VIEW public.bar AS SELECT 1;\n"}
+			restoreErrors := utils.NewRestoreErrors()
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata"})
+
+			restoreErrors.AddSkippedStatement("predata", statement)
+
+			Expect(restoreErrors.Records[1]).To(Equal(utils.RestoreErrorRecord{
+				Section:      "predata",
+				ObjectType:   "VIEW",
+				Schema:       "public",
+				Name:         "bar",
+				Statement:    "CREATE VIEW public.bar AS SELECT 1;",
+				ErrorMessage: "skipped due to failed dependency",
+				Skipped:      true,
+			}))
+			Expect(restoreErrors.GetErrorCountsBySection()).To(Equal(map[string]int{"predata": 1}))
+		})
+		It("stores the message of a non-database error without a code", func() {
+			record := utils.NewRestoreErrorRecord("data", "TABLE", "public", "foo", "public.foo", errors.New("Expected to restore 2 rows"))
+
+			Expect(record.ErrorCode).To(Equal(""))
+			Expect(record.ErrorMessage).To(Equal("Expected to restore 2 rows"))
+		})
+		It("counts errors by section", func() {
+			restoreErrors := utils.NewRestoreErrors()
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata"})
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "postdata"})
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata"})
+
+			Expect(restoreErrors.GetErrorCountsBySection()).To(Equal(map[string]int{"predata": 2, "postdata": 1}))
+		})
+		It("writes one JSON record per line to the error file", func() {
+			operating.System.OpenFileWrite = func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				return buffer, nil
+			}
+			operating.System.Chmod = func(name string, mode os.FileMode) error {
+				return nil
+			}
+			restoreErrors := utils.NewRestoreErrors()
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "predata", ObjectType: "TABLE", Schema: "public", Name: "foo", Statement: "CREATE TABLE public.foo (i int);", ErrorCode: "42P07", ErrorMessage: "already exists"})
+			restoreErrors.AddRecord(utils.RestoreErrorRecord{Section: "data", ObjectType: "TABLE", Schema: "public", Name: "bar", Statement: "public.bar(i)", ErrorMessage: "Expected to restore 2 rows"})
+
+			err := restoreErrors.WriteErrorFile("filename")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(buffer.Contents())).To(Equal(`{"section":"predata","object_type":"TABLE","schema":"public","name":"foo","statement":"CREATE TABLE public.foo (i int);","error_code":"42P07","error_message":"already exists"}
+{"section":"data","object_type":"TABLE","schema":"public","name":"bar","statement":"public.bar(i)","error_code":"","error_message":"Expected to restore 2 rows"}
+`))
+		})
 	})
 	Describe("SetBackupParamFromFlags", func() {
 		AfterEach(func() {
@@ -251,6 +441,7 @@ Restore Status: Success but non-fatal errors occurred. See log file .+ for detai
 				"5.0.0 build test", "0.1.0",
 				"/tmp/plugin.sh", "timestamp1")
 			structmatcher.ExpectStructsToMatch(backup_history.BackupConfig{
+				BackupType:       "full",
 				BackupVersion:    "0.1.0",
 				Compressed:       true,
 				DatabaseName:     "testdb",
@@ -259,6 +450,7 @@ Restore Status: Success but non-fatal errors occurred. See log file .+ for detai
 				IncludeRelations: []string{},
 				ExcludeSchemas:   []string{},
 				ExcludeRelations: []string{},
+				MetadataFormat:   "file",
 				Plugin:           "/tmp/plugin.sh",
 				Timestamp:        "timestamp1",
 			}, backupConfig)
@@ -328,6 +520,14 @@ Restore Status: Success but non-fatal errors occurred. See log file .+ for detai
 			utils.EnsureBackupVersionCompatibility("0.1.0", "0.1.0")
 		})
 	})
+	Describe("ParseBackupDatabaseVersion", func() {
+		It("parses the three-digit version from a version string with build details", func() {
+			version := utils.ParseBackupDatabaseVersion("5.0.6-beta.9+dev.129.g4bd4e41 build dev")
+			Expect(version.VersionString).To(Equal("5.0.6-beta.9+dev.129.g4bd4e41 build dev"))
+			Expect(version.SemVer.String()).To(Equal("5.0.6"))
+			Expect(version.Before("6")).To(BeTrue())
+		})
+	})
 	Describe("EnsureDatabaseVersionCompatibility", func() {
 		var restoreVersion dbconn.GPDBVersion
 		BeforeEach(func() {
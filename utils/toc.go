@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"crypto/md5"
 	"fmt"
 	"io"
+	"path"
 	"regexp"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/iohelper"
@@ -11,6 +14,11 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	METADATA_FORMAT_DIRECTORY = "directory"
+	METADATA_FORMAT_FILE      = "file"
+)
+
 type TOC struct {
 	metadataEntryMap    map[string]*[]MetadataEntry
 	GlobalEntries       []MetadataEntry
@@ -19,6 +27,9 @@ type TOC struct {
 	StatisticsEntries   []MetadataEntry
 	DataEntries         []MasterDataEntry
 	IncrementalMetadata IncrementalEntries
+	PredataDependencies map[string][]string
+	FingerprintVersion  int      `yaml:",omitempty"`
+	UnpopulatedMatviews []string `yaml:",omitempty"`
 }
 
 type SegmentTOC struct {
@@ -32,6 +43,7 @@ type MetadataEntry struct {
 	ReferenceObject string
 	StartByte       uint64
 	EndByte         uint64
+	Path            string `yaml:",omitempty"`
 }
 
 type MasterDataEntry struct {
@@ -41,6 +53,10 @@ type MasterDataEntry struct {
 	AttributeString string
 	RowsCopied      int64
 	PartitionRoot   string
+	Fingerprints    []SegmentFingerprint `yaml:",omitempty"`
+	SizeEstimate    int64                `yaml:",omitempty"`
+	Predicate       string               `yaml:",omitempty"`
+	MaskedColumns   []string             `yaml:",omitempty"`
 }
 
 type SegmentDataEntry struct {
@@ -49,7 +65,8 @@ type SegmentDataEntry struct {
 }
 
 type IncrementalEntries struct {
-	AO map[string]AOEntry
+	AO   map[string]AOEntry
+	Heap map[string]HeapEntry `yaml:",omitempty"`
 }
 
 type AOEntry struct {
@@ -57,6 +74,11 @@ type AOEntry struct {
 	LastDDLTimestamp string
 }
 
+type HeapEntry struct {
+	LastDDLTimestamp string
+	Fingerprints     []SegmentFingerprint
+}
+
 func NewTOC(filename string) *TOC {
 	toc := &TOC{}
 	contents, err := operating.System.ReadFile(filename)
@@ -132,51 +154,144 @@ func GetIncludedPartitionRoots(tocDataEntries []MasterDataEntry, includeRelation
 	return rootPartitions
 }
 
-func (toc *TOC) GetSQLStatementForObjectTypes(section string, metadataFile io.ReaderAt, includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) []StatementWithType {
+/*
+ * Entries written in the directory metadata format reference their statement
+ * by a path relative to backupDir, rather than by a byte range in the
+ * metadata file, so metadataFile may be nil if every entry has a path.
+ */
+func (toc *TOC) GetSQLStatementForObjectTypes(section string, metadataFile io.ReaderAt, backupDir string, includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) []StatementWithType {
 	entries := *toc.metadataEntryMap[section]
 
-	objectSet, schemaSet, relationSet := constructFilterSets(includeObjectTypes, excludeObjectTypes, includeSchemas, excludeSchemas, includeRelations, excludeRelations)
+	objectSet := constructObjectTypeFilterSet(includeObjectTypes, excludeObjectTypes)
+	filter := NewFilter(includeSchemas, excludeSchemas, includeRelations, excludeRelations)
 	statements := make([]StatementWithType, 0)
 	for _, entry := range entries {
-		if shouldIncludeStatement(entry, objectSet, schemaSet, relationSet) {
-			contents := make([]byte, entry.EndByte-entry.StartByte)
-			_, err := metadataFile.ReadAt(contents, int64(entry.StartByte))
-			gplog.FatalOnError(err)
+		if shouldIncludeStatement(entry, objectSet, filter) {
+			contents := readMetadataEntry(entry, metadataFile, backupDir)
 			statements = append(statements, StatementWithType{Schema: entry.Schema, Name: entry.Name, ObjectType: entry.ObjectType, ReferenceObject: entry.ReferenceObject, Statement: string(contents)})
 		}
 	}
 	return statements
 }
 
-func constructFilterSets(includeObjectTypes []string, excludeObjectTypes []string, includeSchemas []string, excludeSchemas []string, includeRelations []string, excludeRelations []string) (*FilterSet, *FilterSet, *FilterSet) {
-	var objectSet, schemaSet, relationSet *FilterSet
-	if len(includeObjectTypes) > 0 {
-		objectSet = NewIncludeSet(includeObjectTypes)
-	} else {
-		objectSet = NewExcludeSet(excludeObjectTypes)
+func readMetadataEntry(entry MetadataEntry, metadataFile io.ReaderAt, backupDir string) []byte {
+	if entry.Path != "" {
+		contents, err := operating.System.ReadFile(path.Join(backupDir, entry.Path))
+		gplog.FatalOnError(err)
+		return contents
+	}
+	contents := make([]byte, entry.EndByte-entry.StartByte)
+	_, err := metadataFile.ReadAt(contents, int64(entry.StartByte))
+	gplog.FatalOnError(err)
+	return contents
+}
+
+func (toc *TOC) HasMetadataEntryPaths(section string) bool {
+	for _, entry := range *toc.metadataEntryMap[section] {
+		if entry.Path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ * In the directory metadata format, the statement for each entry in the
+ * metadata file is written to its own file under backupDir, at
+ * metadata/<section>/<schema>/<type>/<name>.sql, so that the metadata can be
+ * diffed and reviewed per object.  Objects without a schema omit that level.
+ */
+func (toc *TOC) WriteMetadataDirectory(metadataFile io.ReaderAt, backupDir string) {
+	usedPaths := make(map[string]bool, 0)
+	for _, section := range []string{"global", "predata", "postdata"} {
+		entries := *toc.metadataEntryMap[section]
+		for i, entry := range entries {
+			contents := readMetadataEntry(entry, metadataFile, backupDir)
+			entryPath := getMetadataEntryPath(section, entry, usedPaths)
+			err := operating.System.MkdirAll(path.Join(backupDir, path.Dir(entryPath)), 0755)
+			gplog.FatalOnError(err)
+			entryFile := NewFileWithByteCountFromFile(path.Join(backupDir, entryPath))
+			entryFile.MustPrintf("%s", contents)
+			entryFile.Close()
+			entries[i].Path = entryPath
+			entries[i].StartByte = 0
+			entries[i].EndByte = 0
+		}
+	}
+}
+
+/*
+ * Escaping is one-to-one and truncated names carry a hash of the full name, so
+ * a path is only reused by a later entry for the same object, such as the
+ * statements setting its owner and privileges.  Those entries get a numeric
+ * suffix that counts only the entries for that object, so each path depends on
+ * the object alone and not on which other objects are in the backup.  Escaped
+ * names never contain a "+", so a suffix cannot collide with another name.
+ */
+func getMetadataEntryPath(section string, entry MetadataEntry, usedPaths map[string]bool) string {
+	objectType := escapeMetadataPathComponent(strings.Replace(entry.ObjectType, " ", "_", -1))
+	entryDir := path.Join("metadata", section, objectType)
+	if entry.Schema != "" {
+		entryDir = path.Join("metadata", section, escapeMetadataPathComponent(entry.Schema), objectType)
 	}
-	if len(includeSchemas) > 0 {
-		schemaSet = NewIncludeSet(includeSchemas)
-	} else {
-		schemaSet = NewExcludeSet(excludeSchemas)
+	name := objectType
+	if entry.Name != "" {
+		name = escapeMetadataPathComponent(entry.Name)
 	}
-	if len(includeRelations) > 0 {
-		relationSet = NewIncludeSet(includeRelations)
-	} else {
-		relationSet = NewExcludeSet(excludeRelations)
+	entryPath := path.Join(entryDir, name+".sql")
+	for suffix := 2; usedPaths[entryPath]; suffix++ {
+		entryPath = path.Join(entryDir, fmt.Sprintf("%s+%d.sql", name, suffix))
 	}
-	return objectSet, schemaSet, relationSet
+	usedPaths[entryPath] = true
+	return entryPath
 }
 
-func shouldIncludeStatement(entry MetadataEntry, objectSet *FilterSet, schemaSet *FilterSet, relationSet *FilterSet) bool {
-	shouldIncludeObject := objectSet.MatchesFilter(entry.ObjectType)
-	shouldIncludeSchema := schemaSet.MatchesFilter(entry.Schema)
-	relationFQN := MakeFQN(entry.Schema, entry.Name)
-	shouldIncludeRelation := (relationSet.IsExclude && entry.ObjectType != "TABLE" && entry.ObjectType != "VIEW" && entry.ObjectType != "SEQUENCE" && entry.ReferenceObject == "") ||
-		((entry.ObjectType == "TABLE" || entry.ObjectType == "VIEW" || entry.ObjectType == "SEQUENCE") && relationSet.MatchesFilter(relationFQN) && entry.ReferenceObject == "") || // Relations should match the filter
-		(entry.ReferenceObject != "" && relationSet.MatchesFilter(entry.ReferenceObject)) // Include relations that filtered tables depend on
+const maxMetadataPathComponentLength = 200
+
+/*
+ * Identifiers may contain any character, so anything that is not safe to use
+ * in a file name is percent-encoded, as is a leading period.  Long names are
+ * truncated before the first character whose escaped form would not fit
+ * within file name length limits, so that an escape sequence is never split,
+ * and are given a suffix derived from the full name, so that names sharing a
+ * long prefix still map to different files.
+ */
+func escapeMetadataPathComponent(name string) string {
+	escaped := ""
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		isSafe := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte("_-.,()", c) != -1
+		escapedChar := string(c)
+		if !isSafe || (i == 0 && c == '.') {
+			escapedChar = fmt.Sprintf("%%%02X", c)
+		}
+		if len(escaped)+len(escapedChar) > maxMetadataPathComponentLength {
+			nameHash := fmt.Sprintf("%x", md5.Sum([]byte(name)))
+			return escaped + "+" + nameHash[:8]
+		}
+		escaped += escapedChar
+	}
+	return escaped
+}
 
-	return shouldIncludeObject && shouldIncludeSchema && shouldIncludeRelation
+func constructObjectTypeFilterSet(includeObjectTypes []string, excludeObjectTypes []string) *FilterSet {
+	if len(includeObjectTypes) > 0 {
+		return NewIncludeSet(includeObjectTypes)
+	}
+	return NewExcludeSet(excludeObjectTypes)
+}
+
+func shouldIncludeStatement(entry MetadataEntry, objectSet *FilterSet, filter Filter) bool {
+	if !objectSet.MatchesFilter(entry.ObjectType) {
+		return false
+	}
+	if entry.ReferenceObject != "" { // Include objects that belong to filtered relations
+		return filter.MatchesRelation(entry.Schema, entry.ReferenceObject)
+	}
+	if entry.ObjectType == "TABLE" || entry.ObjectType == "VIEW" || entry.ObjectType == "MATERIALIZED VIEW" || entry.ObjectType == "SEQUENCE" {
+		return filter.MatchesRelation(entry.Schema, MakeFQN(entry.Schema, entry.Name))
+	}
+	return filter.MatchesNonRelation(entry.Schema)
 }
 
 func getLeafPartitions(tableFQNs []string, tocDataEntries []MasterDataEntry) (leafPartitions []string) {
@@ -199,21 +314,14 @@ func getLeafPartitions(tableFQNs []string, tocDataEntries []MasterDataEntry) (le
 func (toc *TOC) GetDataEntriesMatching(includeSchemas []string, excludeSchemas []string,
 	includeTableFQNs []string, excludeTableFQNs []string, restorePlanTableFQNs []string) []MasterDataEntry {
 
-	schemaSet := NewIncludeSet([]string{})
-	if len(includeSchemas) > 0 {
-		schemaSet = NewIncludeSet(includeSchemas)
-	} else if len(excludeSchemas) > 0 {
-		schemaSet = NewExcludeSet(excludeSchemas)
-	}
-
-	tableSet := NewIncludeSet([]string{})
+	// Filtering on a partition table filters on all of its leaf partitions
 	if len(includeTableFQNs) > 0 {
 		includeTableFQNs = append(includeTableFQNs, getLeafPartitions(includeTableFQNs, toc.DataEntries)...)
-		tableSet = NewIncludeSet(includeTableFQNs)
-	} else if len(excludeTableFQNs) > 0 {
+	}
+	if len(excludeTableFQNs) > 0 {
 		excludeTableFQNs = append(excludeTableFQNs, getLeafPartitions(excludeTableFQNs, toc.DataEntries)...)
-		tableSet = NewExcludeSet(excludeTableFQNs)
 	}
+	filter := NewFilter(includeSchemas, excludeSchemas, includeTableFQNs, excludeTableFQNs)
 
 	restorePlanTableSet := NewSet(restorePlanTableFQNs)
 
@@ -221,10 +329,8 @@ func (toc *TOC) GetDataEntriesMatching(includeSchemas []string, excludeSchemas [
 	for _, entry := range toc.DataEntries {
 		tableFQN := MakeFQN(entry.Schema, entry.Name)
 
-		validSchema := schemaSet.MatchesFilter(entry.Schema)
 		validRestorePlan := restorePlanTableSet.MatchesFilter(tableFQN)
-		validTable := tableSet.MatchesFilter(tableFQN)
-		if validRestorePlan && validSchema && validTable {
+		if validRestorePlan && filter.MatchesRelation(entry.Schema, tableFQN) {
 			matchingEntries = append(matchingEntries, entry)
 		}
 	}
@@ -242,6 +348,110 @@ func SubstituteRedirectDatabaseInStatements(statements []StatementWithType, oldQ
 	return statements
 }
 
+/*
+ * Tablespace names are printed as quoted identifiers, so we match either a
+ * double-quoted identifier (which may contain escaped double quotes) or an
+ * unquoted one.  Tablespace clauses are matched along with string literals and
+ * quoted identifiers, which are left as they are, so that text in a comment, a
+ * column default, or an identifier that looks like a tablespace clause is not
+ * mistaken for one.
+ */
+var (
+	tablespaceIdentPattern = `("(?:[^"]|"")+"|[A-Za-z_][A-Za-z0-9_$]*)`
+	tablespaceClause       = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"| TABLESPACE ` + tablespaceIdentPattern)
+	createTablespaceClause = regexp.MustCompile(`(?s)(CREATE TABLESPACE ` + tablespaceIdentPattern + `) LOCATION [^;]*;`)
+	alterIndexTablespace   = regexp.MustCompile(`^ALTER INDEX .* SET TABLESPACE ` + tablespaceIdentPattern + `;$`)
+
+	// These are the object types whose statements may reference a tablespace
+	tablespaceObjectTypes = map[string]bool{"DATABASE": true, "INDEX": true, "MATERIALIZED VIEW": true, "TABLE": true}
+)
+
+/*
+ * Calls replaceFunc with the unquoted tablespace name of each tablespace
+ * clause in the statement, and replaces the clause with the result.
+ */
+func replaceTablespaceClauses(statement string, replaceFunc func(clause string, name string) string) string {
+	return tablespaceClause.ReplaceAllStringFunc(statement, func(match string) string {
+		quotedName := tablespaceClause.FindStringSubmatch(match)[1]
+		if quotedName == "" {
+			return match
+		}
+		return replaceFunc(match, unquoteTablespaceName(quotedName))
+	})
+}
+
+func unquoteTablespaceName(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		return strings.Replace(name[1:len(name)-1], `""`, `"`, -1)
+	}
+	return name
+}
+
+/*
+ * The keys of tablespaceMap are tablespace names as they are stored in the
+ * catalog, and its values are the new names already quoted as identifiers.
+ */
+func SubstituteTablespacesInStatements(statements []StatementWithType, tablespaceMap map[string]string) []StatementWithType {
+	if len(tablespaceMap) == 0 {
+		return statements
+	}
+	for i := range statements {
+		if !tablespaceObjectTypes[statements[i].ObjectType] {
+			continue
+		}
+		statements[i].Statement = replaceTablespaceClauses(statements[i].Statement, func(clause string, name string) string {
+			if newName, ok := tablespaceMap[name]; ok {
+				return fmt.Sprintf(" TABLESPACE %s", newName)
+			}
+			return clause
+		})
+	}
+	return statements
+}
+
+func RemoveTablespacesFromStatements(statements []StatementWithType) []StatementWithType {
+	newStatements := make([]StatementWithType, 0)
+	for _, statement := range statements {
+		if statement.ObjectType == "TABLESPACE" {
+			continue
+		}
+		if tablespaceObjectTypes[statement.ObjectType] {
+			if alterIndexTablespace.MatchString(strings.TrimSpace(statement.Statement)) {
+				continue
+			}
+			statement.Statement = replaceTablespaceClauses(statement.Statement, func(string, string) string { return "" })
+		}
+		newStatements = append(newStatements, statement)
+	}
+	return newStatements
+}
+
+/*
+ * Only GPDB 6 and later tablespaces have a LOCATION clause; GPDB 4 and 5
+ * tablespaces reference a filespace, so those statements are left as-is.
+ * Any per-segment locations are dropped along with the original location,
+ * as the new location applies to the master and all segments.  As with
+ * tablespaceMap, the keys of locationMap are tablespace names as they are
+ * stored in the catalog.
+ */
+func SubstituteTablespaceLocationsInStatements(statements []StatementWithType, locationMap map[string]string) []StatementWithType {
+	if len(locationMap) == 0 {
+		return statements
+	}
+	for i := range statements {
+		if statements[i].ObjectType != "TABLESPACE" {
+			continue
+		}
+		location, ok := locationMap[unquoteTablespaceName(statements[i].Name)]
+		if !ok {
+			continue
+		}
+		replacement := fmt.Sprintf("$1 LOCATION '%s';", strings.Replace(EscapeSingleQuotes(location), "$", "$$", -1))
+		statements[i].Statement = createTablespaceClause.ReplaceAllString(statements[i].Statement, replacement)
+	}
+	return statements
+}
+
 func RemoveActiveRole(activeUser string, statements []StatementWithType) []StatementWithType {
 	newStatements := make([]StatementWithType, 0)
 	for _, statement := range statements {
@@ -276,8 +486,34 @@ func (toc *TOC) AddMetadataEntry(section string, entry MetadataEntry, start, end
 	*toc.metadataEntryMap[section] = append(*toc.metadataEntryMap[section], entry)
 }
 
-func (toc *TOC) AddMasterDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string) {
-	toc.DataEntries = append(toc.DataEntries, MasterDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot})
+/*
+ * Objects in the dependency graph are identified by their type and name, as
+ * those are the only identifying information stored in each metadata entry.
+ * Entries for objects that can share a schema and name, such as overloaded
+ * functions and operators, include the identifying argument list or index
+ * method in their name so that each object gets a distinct key.
+ */
+func GetDependencyKey(objectType string, schema string, name string) string {
+	if schema == "" {
+		return fmt.Sprintf("%s %s", objectType, name)
+	}
+	return fmt.Sprintf("%s %s", objectType, MakeFQN(schema, name))
+}
+
+/*
+ * Every object whose creation order was determined by dependency sorting gets
+ * an entry here, even if it has no dependencies, so that restore can tell which
+ * statements may be executed in parallel and which must be executed in order.
+ */
+func (toc *TOC) AddPredataDependencies(key string, dependencies []string) {
+	if toc.PredataDependencies == nil {
+		toc.PredataDependencies = make(map[string][]string, 0)
+	}
+	toc.PredataDependencies[key] = dependencies
+}
+
+func (toc *TOC) AddMasterDataEntry(entry MasterDataEntry) {
+	toc.DataEntries = append(toc.DataEntries, entry)
 }
 
 func (toc *SegmentTOC) AddSegmentDataEntry(oid uint, startByte uint64, endByte uint64) {
@@ -2,11 +2,17 @@ package utils_test
 
 import (
 	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
 
 	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/greenplum-db/gpbackup/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("utils/toc tests", func() {
@@ -39,192 +45,284 @@ var _ = Describe("utils/toc tests", func() {
 		var noInObj, noExObj, noInSchema, noExSchema, noInRelation, noExRelation []string
 		It("returns statement for a single object type", func() {
 			backupfile.ByteCount = commentLen + createLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0}, commentLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0, ""}, commentLen, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(comment.Statement + create.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, []string{"DATABASE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", []string{"DATABASE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{create}))
 		})
 		It("returns statement for multiple object types", func() {
 			backupfile.ByteCount = commentLen + createLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0}, commentLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0, ""}, commentLen, backupfile.ByteCount)
 			backupfile.ByteCount += role1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole1", "ROLE", "", 0, 0}, commentLen+createLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole1", "ROLE", "", 0, 0, ""}, commentLen+createLen, backupfile.ByteCount)
 			backupfile.ByteCount += role2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole2", "ROLE", "", 0, 0}, commentLen+createLen+role1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole2", "ROLE", "", 0, 0, ""}, commentLen+createLen+role1Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(comment.Statement + create.Statement + role1.Statement + role2.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, []string{"DATABASE", "ROLE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", []string{"DATABASE", "ROLE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{create, role1, role2}))
 		})
 		It("does not return a statement type listed in the exclude list", func() {
 			backupfile.ByteCount = commentLen + createLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0}, commentLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0, ""}, commentLen, backupfile.ByteCount)
 			backupfile.ByteCount += role1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole1", "ROLE", "", 0, 0}, commentLen+createLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole1", "ROLE", "", 0, 0, ""}, commentLen+createLen, backupfile.ByteCount)
 			backupfile.ByteCount += role2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole2", "ROLE", "", 0, 0}, commentLen+createLen+role1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole2", "ROLE", "", 0, 0, ""}, commentLen+createLen+role1Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(comment.Statement + create.Statement + role1.Statement + role2.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, []string{"DATABASE"}, noInSchema, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, []string{"DATABASE"}, noInSchema, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{role1, role2}))
 		})
 		It("returns empty statement when no object types are found", func() {
 			backupfile.ByteCount = commentLen + createLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0}, commentLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0, ""}, commentLen, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(comment.Statement + create.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, []string{"TABLE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", []string{"TABLE"}, noExObj, noInSchema, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{}))
 		})
 		It("returns statement for a single object type with matching schema", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, []string{"TABLE"}, noExObj, []string{"schema"}, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", []string{"TABLE"}, noExObj, []string{"schema"}, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table1}))
 		})
 		It("returns statement for any object type in the include schema", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, []string{"schema"}, noExSchema, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, []string{"schema"}, noExSchema, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table1, sequence}))
 		})
 		It("returns statement for any object type not in the exclude schema", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, []string{"schema2"}, noInRelation, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, []string{"schema2"}, noInRelation, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table1, sequence}))
 		})
 		It("returns statement for a table matching an included table", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, []string{"schema.table1"}, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, []string{"schema.table1"}, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table1}))
 		})
 		It("returns statement for a view matching an included view", func() {
 			backupfile.ByteCount = view1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "view1", "VIEW", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "view1", "VIEW", "", 0, 0, ""}, 0, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(view1.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, []string{"schema.view1"}, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, []string{"schema.view1"}, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{view1}))
 		})
 		It("returns statement for a sequence matching an included sequence", func() {
 			backupfile.ByteCount = sequence1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "sequence1", "SEQUENCE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "sequence1", "SEQUENCE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(sequence1.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, []string{"schema.sequence1"}, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, []string{"schema.sequence1"}, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{sequence1}))
 		})
 		It("returns statement for any object type or reference object not matching an excluded table", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "schema.table2", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "schema.table2", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.table1"})
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.table1"})
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table2, referenceSequence}))
 		})
 		It("returns no statements for any object type with reference object matching an excluded table", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "schema.table1", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "schema.table1", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 			backupfile.ByteCount += indexLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "schema.table1", 0, 0}, table1Len+table2Len+sequenceLen, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "schema.table1", 0, 0, ""}, table1Len+table2Len+sequenceLen, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement + index.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.table1"})
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.table1"})
 
 			Expect(statements).To(Equal([]utils.StatementWithType{table2}))
 		})
 		It("returns no statements for an excluded view or sequence", func() {
 			backupfile.ByteCount = view1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "view1", "VIEW", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "view1", "VIEW", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += sequence1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "sequence1", "SEQUENCE", "", 0, 0}, view1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "sequence1", "SEQUENCE", "", 0, 0, ""}, view1Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(view1.Statement + sequence1.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.view1", "schema.sequence1"})
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, noInRelation, []string{"schema.view1", "schema.sequence1"})
 
 			Expect(statements).To(Equal([]utils.StatementWithType{}))
 		})
 		It("returns statement for any object type with matching reference object", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "INDEX", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "INDEX", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += indexLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "schema.table", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "schema.table", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + index.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, []string{"schema.table"}, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, []string{"schema.table"}, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{index}))
 
 		})
+		It("returns statements in an included schema except for an excluded table and the objects that reference it", func() {
+			backupfile.ByteCount = table1Len
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			backupfile.ByteCount += table2Len
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
+			backupfile.ByteCount += sequenceLen
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "somesequence", "SEQUENCE", "schema.table2", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
+			backupfile.ByteCount += indexLen
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "schema.table1", 0, 0, ""}, table1Len+table2Len+sequenceLen, backupfile.ByteCount)
+			backupfile.ByteCount += view1Len
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "view1", "VIEW", "", 0, 0, ""}, table1Len+table2Len+sequenceLen+indexLen, backupfile.ByteCount)
+
+			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + sequence.Statement + index.Statement + view1.Statement))
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, []string{"schema"}, noExSchema, noInRelation, []string{"schema.table1"})
+
+			Expect(statements).To(Equal([]utils.StatementWithType{referenceSequence, view1}))
+		})
+		It("returns no statements for an included table in an excluded schema", func() {
+			backupfile.ByteCount = table1Len
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
+			backupfile.ByteCount += table2Len
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
+
+			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement))
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, []string{"schema"}, []string{"schema.table1", "schema2.table2"}, noExRelation)
+
+			Expect(statements).To(Equal([]utils.StatementWithType{table2}))
+		})
 		It("returns no statements for a non-relation object with matching name from relation list", func() {
 			backupfile.ByteCount = table1Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0}, 0, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, 0, backupfile.ByteCount)
 			backupfile.ByteCount += table2Len
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0}, table1Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema2", "table2", "TABLE", "", 0, 0, ""}, table1Len, backupfile.ByteCount)
 			backupfile.ByteCount += sequenceLen
-			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "", 0, 0}, table1Len+table2Len, backupfile.ByteCount)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"schema", "someindex", "INDEX", "", 0, 0, ""}, table1Len+table2Len, backupfile.ByteCount)
 
 			metadataFile := bytes.NewReader([]byte(table1.Statement + table2.Statement + index.Statement))
-			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, noInObj, noExObj, noInSchema, noExSchema, []string{"schema.someindex"}, noExRelation)
+			statements := toc.GetSQLStatementForObjectTypes("global", metadataFile, "", noInObj, noExObj, noInSchema, noExSchema, []string{"schema.someindex"}, noExRelation)
 
 			Expect(statements).To(Equal([]utils.StatementWithType{}))
 		})
 	})
+	Describe("WriteMetadataDirectory", func() {
+		var files map[string]*gbytes.Buffer
+		BeforeEach(func() {
+			files = make(map[string]*gbytes.Buffer, 0)
+			operating.System.MkdirAll = func(path string, perm os.FileMode) error { return nil }
+			operating.System.OpenFileWrite = func(name string, flag int, perm os.FileMode) (io.WriteCloser, error) {
+				files[name] = gbytes.NewBuffer()
+				return files[name], nil
+			}
+			operating.System.Chmod = func(name string, mode os.FileMode) error { return nil }
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return files[filename].Contents(), nil }
+		})
+		AfterEach(func() {
+			operating.System = operating.InitializeSystemFunctions()
+		})
+		It("writes each statement to its own file and references it by path", func() {
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somedatabase", "DATABASE", "", 0, 0, ""}, 0, createLen)
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "somerole1", "ROLE", "", 0, 0, ""}, createLen, createLen+role1Len)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "table1", "TABLE", "", 0, 0, ""}, createLen+role1Len, createLen+role1Len+table1Len)
+			metadataFile := bytes.NewReader([]byte(create.Statement + role1.Statement + table1.Statement))
+
+			toc.WriteMetadataDirectory(metadataFile, "/backups")
+
+			Expect(toc.GlobalEntries).To(Equal([]utils.MetadataEntry{
+				{"", "somedatabase", "DATABASE", "", 0, 0, "metadata/global/DATABASE/somedatabase.sql"},
+				{"", "somerole1", "ROLE", "", 0, 0, "metadata/global/ROLE/somerole1.sql"},
+			}))
+			Expect(toc.PredataEntries).To(Equal([]utils.MetadataEntry{{"schema", "table1", "TABLE", "", 0, 0, "metadata/predata/schema/TABLE/table1.sql"}}))
+			Expect(string(files["/backups/metadata/predata/schema/TABLE/table1.sql"].Contents())).To(Equal(table1.Statement))
+
+			statements := toc.GetSQLStatementForObjectTypes("global", nil, "/backups", []string{"ROLE"}, []string{}, []string{}, []string{}, []string{}, []string{})
+			Expect(statements).To(Equal([]utils.StatementWithType{role1}))
+		})
+		It("escapes names that are not safe to use as file names and gives repeated entries unique paths", func() {
+			toc.AddMetadataEntry("global", utils.MetadataEntry{"", "", "SESSION GUCS", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{`"My Schema"`, "func(integer, text)", "FUNCTION", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("postdata", utils.MetadataEntry{"schema", "../index", "INDEX", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("postdata", utils.MetadataEntry{"schema", "../index", "INDEX", "", 0, 0, ""}, 0, 1)
+
+			toc.WriteMetadataDirectory(bytes.NewReader([]byte(";")), "/backups")
+
+			Expect(toc.GlobalEntries[0].Path).To(Equal("metadata/global/SESSION_GUCS/SESSION_GUCS.sql"))
+			Expect(toc.PredataEntries[0].Path).To(Equal("metadata/predata/%22My%20Schema%22/FUNCTION/func(integer,%20text).sql"))
+			Expect(toc.PostdataEntries[0].Path).To(Equal("metadata/postdata/schema/INDEX/%2E.%2Findex.sql"))
+			Expect(toc.PostdataEntries[1].Path).To(Equal("metadata/postdata/schema/INDEX/%2E.%2Findex+2.sql"))
+		})
+		It("truncates long names without splitting an escape sequence and gives them paths that do not depend on other objects", func() {
+			longPrefix := strings.Repeat("a", 199)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", longPrefix + " one", "FUNCTION", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", longPrefix + " two", "FUNCTION", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "func_2", "FUNCTION", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "func", "FUNCTION", "", 0, 0, ""}, 0, 1)
+			toc.AddMetadataEntry("predata", utils.MetadataEntry{"schema", "func", "FUNCTION", "", 0, 0, ""}, 0, 1)
+
+			toc.WriteMetadataDirectory(bytes.NewReader([]byte(";")), "/backups")
+
+			Expect(toc.PredataEntries[0].Path).To(MatchRegexp(`^metadata/predata/schema/FUNCTION/a{199}\+[0-9a-f]{8}\.sql$`))
+			Expect(toc.PredataEntries[1].Path).To(MatchRegexp(`^metadata/predata/schema/FUNCTION/a{199}\+[0-9a-f]{8}\.sql$`))
+			Expect(toc.PredataEntries[0].Path).ToNot(Equal(toc.PredataEntries[1].Path))
+			Expect(toc.PredataEntries[2].Path).To(Equal("metadata/predata/schema/FUNCTION/func_2.sql"))
+			Expect(toc.PredataEntries[3].Path).To(Equal("metadata/predata/schema/FUNCTION/func.sql"))
+			Expect(toc.PredataEntries[4].Path).To(Equal("metadata/predata/schema/FUNCTION/func+2.sql"))
+		})
+	})
 	Describe("GetDataEntriesMatching", func() {
 		BeforeEach(func() {
-			toc.AddMasterDataEntry("schema1", "table1", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema2", "table2", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema3", "table3", 1, "(i)", 0, "")
-			toc.AddMasterDataEntry("schema3", "table3_partition1", 1, "(i)", 0, "table3")
-			toc.AddMasterDataEntry("schema3", "table3_partition2", 1, "(i)", 0, "table3")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "table1", Oid: 1, AttributeString: "(i)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "table2", Oid: 1, AttributeString: "(i)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "table3", Oid: 1, AttributeString: "(i)"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "table3_partition1", Oid: 1, AttributeString: "(i)", PartitionRoot: "table3"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "table3_partition2", Oid: 1, AttributeString: "(i)", PartitionRoot: "table3"})
 		})
 		Context("Non-empty restore plan", func() {
 			restorePlanTableFQNs := []string{"schema1.table1", "schema2.table2", "schema3.table3", "schema3.table3_partition1", "schema3.table3_partition2"}
@@ -353,6 +451,38 @@ var _ = Describe("utils/toc tests", func() {
 					},
 				))
 			})
+			It("returns matching entries on include schema and exclude leaf partition table", func() {
+				matchingEntries := toc.GetDataEntriesMatching([]string{"schema3"}, []string{},
+					[]string{}, []string{"schema3.table3_partition2"}, restorePlanTableFQNs)
+
+				Expect(matchingEntries).To(Equal(
+					[]utils.MasterDataEntry{
+						{Schema: "schema3", Name: "table3", Oid: 1, AttributeString: "(i)", PartitionRoot: ""},
+						{Schema: "schema3", Name: "table3_partition1", Oid: 1, AttributeString: "(i)", PartitionRoot: "table3"},
+					},
+				))
+			})
+			It("returns matching entries on exclude schema and exclude table", func() {
+				matchingEntries := toc.GetDataEntriesMatching([]string{}, []string{"schema3"},
+					[]string{}, []string{"schema2.table2"}, restorePlanTableFQNs)
+
+				Expect(matchingEntries).To(Equal(
+					[]utils.MasterDataEntry{
+						{Schema: "schema1", Name: "table1", Oid: 1, AttributeString: "(i)", PartitionRoot: ""},
+					},
+				))
+			})
+			It("does not return leaf partitions of an included table that are excluded", func() {
+				matchingEntries := toc.GetDataEntriesMatching([]string{}, []string{},
+					[]string{"schema3.table3"}, []string{"schema3.table3_partition1"}, restorePlanTableFQNs)
+
+				Expect(matchingEntries).To(Equal(
+					[]utils.MasterDataEntry{
+						{Schema: "schema3", Name: "table3", Oid: 1, AttributeString: "(i)", PartitionRoot: ""},
+						{Schema: "schema3", Name: "table3_partition2", Oid: 1, AttributeString: "(i)", PartitionRoot: "table3"},
+					},
+				))
+			})
 		})
 
 		Context("Empty restore plan", func() {
@@ -422,24 +552,114 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(resultStatements).To(Equal([]utils.StatementWithType{user1, user2}))
 		})
 	})
+	Describe("GetDependencyKey", func() {
+		It("returns the object type and fully-qualified name for a schema object", func() {
+			Expect(utils.GetDependencyKey("TABLE", "public", "foo")).To(Equal("TABLE public.foo"))
+		})
+		It("returns the object type and name for an object without a schema", func() {
+			Expect(utils.GetDependencyKey("CAST", "", "(integer AS text)")).To(Equal("CAST (integer AS text)"))
+		})
+	})
+	Describe("SubstituteTablespacesInStatements", func() {
+		table := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE test_tablespace DISTRIBUTED BY (i);\n"}
+		quotedTable := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE \"test tablespace\" DISTRIBUTED BY (i);\n"}
+		index := utils.StatementWithType{ObjectType: "INDEX", Statement: "\nALTER INDEX public.foo_idx SET TABLESPACE test_tablespace;"}
+		database := utils.StatementWithType{ObjectType: "DATABASE", Statement: "CREATE DATABASE somedatabase TEMPLATE template0 TABLESPACE test_tablespace;\n"}
+		tablespace := utils.StatementWithType{Name: "test_tablespace", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE test_tablespace LOCATION '/data/dir';"}
+		tablespaceMap := map[string]string{"test_tablespace": "new_tablespace", "test tablespace": `"new tablespace"`, `test"tablespace`: `"new""tablespace"`}
+		It("substitutes the tablespace of a table", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{table}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE new_tablespace DISTRIBUTED BY (i);\n"))
+		})
+		It("substitutes a quoted tablespace of a table", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{quotedTable}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE \"new tablespace\" DISTRIBUTED BY (i);\n"))
+		})
+		It("substitutes a quoted tablespace containing a double quote", func() {
+			escapedTable := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE \"test\"\"tablespace\" DISTRIBUTED BY (i);\n"}
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{escapedTable}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE \"new\"\"tablespace\" DISTRIBUTED BY (i);\n"))
+		})
+		It("substitutes the tablespace of an index", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{index}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("\nALTER INDEX public.foo_idx SET TABLESPACE new_tablespace;"))
+		})
+		It("substitutes the tablespace of a database", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{database}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("CREATE DATABASE somedatabase TEMPLATE template0 TABLESPACE new_tablespace;\n"))
+		})
+		It("does not modify a tablespace that is not in the map", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{table}, map[string]string{"other_tablespace": "new_tablespace"})
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE public.foo (\n\ti integer\n) TABLESPACE test_tablespace DISTRIBUTED BY (i);\n"))
+		})
+		It("does not modify tablespace clauses in string literals or quoted identifiers", func() {
+			commentedTable := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (\n\t\"a TABLESPACE test_tablespace\" text DEFAULT 'it''s in TABLESPACE test_tablespace'::text\n) TABLESPACE test_tablespace DISTRIBUTED BY (i);\n"}
+			tableComment := utils.StatementWithType{ObjectType: "TABLE", Statement: "\n\nCOMMENT ON TABLE public.foo IS 'moved from TABLESPACE test_tablespace';"}
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{commentedTable, tableComment}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("CREATE TABLE public.foo (\n\t\"a TABLESPACE test_tablespace\" text DEFAULT 'it''s in TABLESPACE test_tablespace'::text\n) TABLESPACE new_tablespace DISTRIBUTED BY (i);\n"))
+			Expect(statements[1].Statement).To(Equal("\n\nCOMMENT ON TABLE public.foo IS 'moved from TABLESPACE test_tablespace';"))
+		})
+		It("does not modify a CREATE TABLESPACE statement", func() {
+			statements := utils.SubstituteTablespacesInStatements([]utils.StatementWithType{tablespace}, tablespaceMap)
+			Expect(statements[0].Statement).To(Equal("\n\nCREATE TABLESPACE test_tablespace LOCATION '/data/dir';"))
+		})
+	})
+	Describe("RemoveTablespacesFromStatements", func() {
+		It("removes tablespace clauses, ALTER INDEX SET TABLESPACE statements, and tablespaces", func() {
+			table := utils.StatementWithType{ObjectType: "TABLE", Statement: "CREATE TABLE public.foo (\n\ti integer\n) WITH (appendonly=true) TABLESPACE test_tablespace DISTRIBUTED BY (i);\n"}
+			index := utils.StatementWithType{ObjectType: "INDEX", Statement: "\nALTER INDEX public.foo_idx SET TABLESPACE test_tablespace;"}
+			createIndex := utils.StatementWithType{ObjectType: "INDEX", Statement: "\n\nCREATE INDEX foo_idx ON public.foo USING btree (i);"}
+			database := utils.StatementWithType{ObjectType: "DATABASE", Statement: "CREATE DATABASE somedatabase TEMPLATE template0 TABLESPACE test_tablespace ENCODING 'UTF8';\n"}
+			tablespace := utils.StatementWithType{Name: "test_tablespace", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE test_tablespace LOCATION '/data/dir';"}
+
+			statements := utils.RemoveTablespacesFromStatements([]utils.StatementWithType{tablespace, database, table, createIndex, index})
+
+			Expect(statements).To(HaveLen(3))
+			Expect(statements[0].Statement).To(Equal("CREATE DATABASE somedatabase TEMPLATE template0 ENCODING 'UTF8';\n"))
+			Expect(statements[1].Statement).To(Equal("CREATE TABLE public.foo (\n\ti integer\n) WITH (appendonly=true) DISTRIBUTED BY (i);\n"))
+			Expect(statements[2]).To(Equal(createIndex))
+		})
+	})
+	Describe("SubstituteTablespaceLocationsInStatements", func() {
+		It("substitutes the location of a tablespace", func() {
+			tablespace := utils.StatementWithType{Name: "test_tablespace", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE test_tablespace LOCATION '/data/dir';"}
+			statements := utils.SubstituteTablespaceLocationsInStatements([]utils.StatementWithType{tablespace}, map[string]string{"test_tablespace": "/new/dir"})
+			Expect(statements[0].Statement).To(Equal("\n\nCREATE TABLESPACE test_tablespace LOCATION '/new/dir';"))
+		})
+		It("substitutes the location of a tablespace with segment locations", func() {
+			tablespace := utils.StatementWithType{Name: "test_tablespace", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE test_tablespace LOCATION '/data/dir'\n\tWITH (content0='/data/seg0');"}
+			statements := utils.SubstituteTablespaceLocationsInStatements([]utils.StatementWithType{tablespace}, map[string]string{"test_tablespace": "/new/dir"})
+			Expect(statements[0].Statement).To(Equal("\n\nCREATE TABLESPACE test_tablespace LOCATION '/new/dir';"))
+		})
+		It("substitutes the location of a quoted tablespace using its unquoted name", func() {
+			tablespace := utils.StatementWithType{Name: `"Test Tablespace"`, ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE \"Test Tablespace\" LOCATION '/data/dir';"}
+			statements := utils.SubstituteTablespaceLocationsInStatements([]utils.StatementWithType{tablespace}, map[string]string{"Test Tablespace": "/new/dir"})
+			Expect(statements[0].Statement).To(Equal("\n\nCREATE TABLESPACE \"Test Tablespace\" LOCATION '/new/dir';"))
+		})
+		It("does not modify a tablespace that uses a filespace", func() {
+			tablespace := utils.StatementWithType{Name: "test_tablespace", ObjectType: "TABLESPACE", Statement: "\n\nCREATE TABLESPACE test_tablespace FILESPACE test_filespace;"}
+			statements := utils.SubstituteTablespaceLocationsInStatements([]utils.StatementWithType{tablespace}, map[string]string{"test_tablespace": "/new/dir"})
+			Expect(statements[0].Statement).To(Equal("\n\nCREATE TABLESPACE test_tablespace FILESPACE test_filespace;"))
+		})
+	})
 	Describe("GetIncludedPartitionRoots", func() {
 		It("does not return anything if relations are not leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema0", Name: "name0", AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "name1", Oid: 1, AttributeString: "attribute0", RowsCopied: 1})
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns root parition of leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 2, "attribute0", 1, "root0")
-			toc.AddMasterDataEntry("schema1", "name1", 3, "attribute0", 1, "root1")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema0", Name: "name0", Oid: 2, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root0"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "name1", Oid: 3, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root1"})
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema0.name0", "schema1.name1"})
 			Expect(roots).To(ConsistOf("schema0.root0", "schema1.root1"))
 		})
 		It("only returns root partitions of leaf partitions", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema0", Name: "name0", AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "name1", Oid: 1, AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "name2", Oid: 2, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root2"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "name3", Oid: 3, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root3"})
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema2.name2", "schema3.name3"})
 			Expect(roots).To(ConsistOf("schema2.root2", "schema3.root3"))
 		})
@@ -448,18 +668,18 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns nothing if relation is not part of TOC data entries", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema0", Name: "name0", AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "name1", Oid: 1, AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "name2", Oid: 2, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root2"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "name3", Oid: 3, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root3"})
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{"schema4.name4", "schema5.name5"})
 			Expect(roots).To(BeEmpty())
 		})
 		It("returns empty if no relations are passed in", func() {
-			toc.AddMasterDataEntry("schema0", "name0", 0, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema1", "name1", 1, "attribute0", 1, "")
-			toc.AddMasterDataEntry("schema2", "name2", 2, "attribute0", 1, "root2")
-			toc.AddMasterDataEntry("schema3", "name3", 3, "attribute0", 1, "root3")
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema0", Name: "name0", AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema1", Name: "name1", Oid: 1, AttributeString: "attribute0", RowsCopied: 1})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema2", Name: "name2", Oid: 2, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root2"})
+			toc.AddMasterDataEntry(utils.MasterDataEntry{Schema: "schema3", Name: "name3", Oid: 3, AttributeString: "attribute0", RowsCopied: 1, PartitionRoot: "root3"})
 			roots := utils.GetIncludedPartitionRoots(toc.DataEntries, []string{})
 			Expect(roots).To(BeEmpty())
 		})
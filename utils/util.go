@@ -17,6 +17,7 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/backup_filepath"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 )
 
@@ -50,6 +51,30 @@ func MakeFQN(schema string, object string) string {
 	return fmt.Sprintf("%s.%s", schema, object)
 }
 
+/*
+ * Returns the schema of an FQN in the format accepted by ValidateFQNs, which
+ * may be a quoted identifier containing periods or escaped quotes.
+ */
+func GetSchemaFromFQN(fqn string) string {
+	if strings.HasPrefix(fqn, `"`) {
+		for i := 1; i < len(fqn); i++ {
+			if fqn[i] != '"' {
+				continue
+			}
+			if i+1 < len(fqn) && fqn[i+1] == '"' {
+				i++
+				continue
+			}
+			return fqn[:i+1]
+		}
+		return fqn
+	}
+	if period := strings.Index(fqn, "."); period >= 0 {
+		return fqn[:period]
+	}
+	return fqn
+}
+
 func ValidateFQNs(fqns []string) {
 	unquotedIdentString := "[a-z_][a-z0-9_]*"
 	validIdentString := fmt.Sprintf("(?:\"(.*)\"|(%s))", unquotedIdentString)
@@ -96,6 +121,28 @@ AND procpid <> pg_backend_pid()`, appName, copyFileName)
 	_, _ = connectionPool.Exec(query)
 }
 
+var sqlStateSuffix = regexp.MustCompile(`\(SQLSTATE ([0-9A-Z]{5})\)$`)
+
+/*
+ * The type of a database error depends on the driver behind the connection
+ * pool, so the SQLSTATE is taken from a pq error, from any error exposing a
+ * SQLState method, or from the "(SQLSTATE xxxxx)" suffix that pgx appends to
+ * its error messages.  An empty string is returned for non-database errors.
+ */
+func GetSQLState(err error) string {
+	cause := errors.Cause(err)
+	switch dbErr := cause.(type) {
+	case *pq.Error:
+		return string(dbErr.Code)
+	case interface{ SQLState() string }:
+		return dbErr.SQLState()
+	}
+	if match := sqlStateSuffix.FindStringSubmatch(cause.Error()); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
 func ValidateGPDBVersionCompatibility(connectionPool *dbconn.DBConn) {
 	if connectionPool.Version.Before(MINIMUM_GPDB4_VERSION) {
 		gplog.Fatal(errors.Errorf(`GPDB version %s is not supported. Please upgrade to GPDB %s.0 or later.`, connectionPool.Version.VersionString, MINIMUM_GPDB4_VERSION), "")
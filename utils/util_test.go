@@ -6,6 +6,8 @@ import (
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -46,6 +48,14 @@ var _ = Describe("utils/util tests", func() {
 			Expect(actual).To(Equal(expected))
 		})
 	})
+	Describe("GetSchemaFromFQN", func() {
+		It("returns the schema of an unquoted FQN", func() {
+			Expect(utils.GetSchemaFromFQN("public.foo")).To(Equal("public"))
+		})
+		It("returns the schema of a quoted FQN containing periods and quotes", func() {
+			Expect(utils.GetSchemaFromFQN(`"my.""schema"""."foo.bar"`)).To(Equal(`"my.""schema"""`))
+		})
+	})
 	Describe("ValidateFQNs", func() {
 		It("validates an unquoted string", func() {
 			testStrings := []string{`schemaname.tablename`}
@@ -100,6 +110,22 @@ var _ = Describe("utils/util tests", func() {
 
 		})
 	})
+	Describe("GetSQLState", func() {
+		It("returns the code from a pq error", func() {
+			err := errors.Wrap(&pq.Error{Code: "55P03"}, "could not lock table")
+			Expect(utils.GetSQLState(err)).To(Equal("55P03"))
+		})
+		It("returns the code from an error with a SQLState method", func() {
+			Expect(utils.GetSQLState(sqlStateError{code: "42P07"})).To(Equal("42P07"))
+		})
+		It("returns the code from the message of a pgx error", func() {
+			err := errors.New("ERROR: canceling statement due to statement timeout (SQLSTATE 57014)")
+			Expect(utils.GetSQLState(err)).To(Equal("57014"))
+		})
+		It("returns an empty string for other errors", func() {
+			Expect(utils.GetSQLState(errors.New("connection refused"))).To(Equal(""))
+		})
+	})
 	Describe("ValidateGPDBVersionCompatibility", func() {
 		It("panics if GPDB version is less than 4.3.17", func() {
 			testhelper.SetDBVersion(connectionPool, "4.3.14")
@@ -125,3 +151,15 @@ var _ = Describe("utils/util tests", func() {
 		})
 	})
 })
+
+type sqlStateError struct {
+	code string
+}
+
+func (err sqlStateError) Error() string {
+	return "database error"
+}
+
+func (err sqlStateError) SQLState() string {
+	return err.code
+}